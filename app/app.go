@@ -36,6 +36,8 @@ type App struct {
 	configPath string
 	interval   int
 	watcher    *fsnotify.Watcher
+	// subDirWatcher 本地订阅目录监听，未开启 sub-dirs-watch 时为 nil
+	subDirWatcher *fsnotify.Watcher
 	// watcherCancel 用于停止轮询配置监听 goroutine（inotify 不可用时的降级方案）。
 	// 若 inotify 正常工作则此字段为 nil。
 	watcherCancel context.CancelFunc
@@ -127,6 +129,7 @@ func (app *App) Initialize() error {
 	if err := app.initConfigWatcher(); err != nil {
 		return fmt.Errorf("初始化配置文件监听失败: %w", err)
 	}
+	app.initSubDirWatcher()
 
 	app.interval = func() int {
 		if config.GlobalConfig.CheckInterval <= 0 {
@@ -409,7 +412,13 @@ func (app *App) checkProxies() error {
 	save.SaveConfig(results)
 
 	check.CurrentStepName.Store("发送通知")
-	utils.SendNotifyCheckResult(len(results), check.CheckTrafficTotal)
+	utils.SendNotifyCheckResult(len(results), check.CheckTrafficTotal, check.BudgetNote())
+	if config.GlobalConfig.SubQuota.Notify {
+		utils.SendNotifySubQuota(proxyutils.QuotaNotices())
+	}
+	if config.GlobalConfig.SourceHealth.Enable && config.GlobalConfig.SourceHealth.Notify {
+		utils.SendNotifySubHealth(proxyutils.SourceHealthAlerts())
+	}
 
 	check.CurrentStepName.Store("更新订阅")
 	utils.UpdateSubs()
@@ -520,6 +529,9 @@ func (app *App) Shutdown() error {
 	if app.watcher != nil {
 		lastErr = app.watcher.Close()
 	}
+	if app.subDirWatcher != nil {
+		_ = app.subDirWatcher.Close()
+	}
 
 	// 优雅关闭 HTTP 服务
 	if app.httpServer != nil {
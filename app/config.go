@@ -6,6 +6,7 @@ import (
 	"log/slog"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 	"time"
 
@@ -239,6 +240,7 @@ func (app *App) onConfigChange() {
 	oldCronCheckUpdateExpr := config.GlobalConfig.CronCheckUpdate
 	oldSubStorePath := config.GlobalConfig.SubStorePath
 	oldSubStorePort := config.GlobalConfig.SubStorePort
+	oldSubDirs, oldSubDirsWatch := config.GlobalConfig.SubDirs, config.GlobalConfig.SubDirsWatch
 
 	if err := app.loadConfig(); err != nil {
 		slog.Error("重新加载配置文件失败", "error", err)
@@ -364,4 +366,8 @@ func (app *App) onConfigChange() {
 		slog.Warn("版本更新设置发生变化，重新设置定时更新任务")
 		app.SetupUpdateTasks()
 	}
+
+	if oldSubDirsWatch != config.GlobalConfig.SubDirsWatch || !slices.Equal(oldSubDirs, config.GlobalConfig.SubDirs) {
+		app.initSubDirWatcher()
+	}
 }
@@ -0,0 +1,97 @@
+// Package app: convert.go
+package app
+
+import (
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	proxyutils "github.com/sinspired/subs-check-pro/v2/proxy"
+	"github.com/sinspired/subs-check-pro/v2/save"
+)
+
+// convertMaxBody 直接提交订阅内容的大小上限
+const convertMaxBody = 10 << 20
+
+// convertRequest 订阅转换参数，GET 读取查询参数，POST 支持 JSON 或直接提交订阅内容
+type convertRequest struct {
+	Target  string   `form:"target" json:"target"`
+	URL     string   `form:"url" json:"url"` // 多个链接以 | 分隔，与 subconverter 一致
+	URLs    []string `form:"-" json:"urls"`  // JSON 中也可用数组提交
+	Raw     string   `form:"-" json:"raw"`   // 订阅内容
+	Include string   `form:"include" json:"include"`
+	Exclude string   `form:"exclude" json:"exclude"`
+	Rename  string   `form:"rename" json:"rename"` // 名称模板，语法同 name-template
+	Dedup   *bool    `form:"dedup" json:"dedup"`   // 默认去重
+	Emoji   bool     `form:"emoji" json:"emoji"`
+	List    bool     `form:"list" json:"list"` // clash 仅输出 proxies
+}
+
+// convertHandler 订阅转换接口 /api/convert，只拉取解析，不执行检测
+func (app *App) convertHandler(c *gin.Context) {
+	var req convertRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if c.Request.Method == http.MethodPost {
+		if strings.HasPrefix(c.ContentType(), "application/json") {
+			if err := c.ShouldBindJSON(&req); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+		} else {
+			body, err := io.ReadAll(io.LimitReader(c.Request.Body, convertMaxBody))
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			req.Raw = string(body)
+		}
+	}
+
+	target, ok := targetAliases[strings.ToLower(strings.TrimSpace(req.Target))]
+	if req.Target == "" {
+		target, ok = targetClash, true
+	}
+	if !ok || target == targetSingBox {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "不支持的转换格式: " + req.Target})
+		return
+	}
+
+	urls := req.URLs
+	for u := range strings.SplitSeq(req.URL, "|") {
+		if u = strings.TrimSpace(u); u != "" {
+			urls = append(urls, u)
+		}
+	}
+	proxies, stats, err := proxyutils.Convert(proxyutils.ConvertOptions{
+		URLs:    urls,
+		Raw:     []byte(req.Raw),
+		Include: req.Include,
+		Exclude: req.Exclude,
+		Rename:  req.Rename,
+		Dedup:   req.Dedup == nil || *req.Dedup,
+		Emoji:   req.Emoji,
+	})
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "stats": stats})
+		return
+	}
+
+	data, err := save.Render(target, proxies, req.List)
+	if err != nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error(), "stats": stats})
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.Header("X-Convert-Parsed", strconv.Itoa(stats.Parsed))
+	c.Header("X-Convert-Output", strconv.Itoa(stats.Output))
+	if len(stats.Failed) > 0 {
+		c.Header("X-Convert-Failed", strconv.Itoa(len(stats.Failed)))
+	}
+	c.Data(http.StatusOK, "text/plain; charset=utf-8", data)
+}
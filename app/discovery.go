@@ -0,0 +1,120 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sinspired/subs-check-pro/v2/config"
+	proxyutils "github.com/sinspired/subs-check-pro/v2/proxy"
+)
+
+// getDiscovered 返回订阅发现记录，?all=true 时包含已忽略的订阅
+func (app *App) getDiscovered(c *gin.Context) {
+	all, _ := strconv.ParseBool(c.Query("all"))
+	c.JSON(http.StatusOK, proxyutils.DiscoveredSources(all))
+}
+
+// promoteDiscovered 将发现的订阅写入配置文件的 sub-urls，配置监听会自动重新加载
+func (app *App) promoteDiscovered(c *gin.Context) {
+	var req struct {
+		URLs []string `json:"urls"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil || len(req.URLs) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请提供 urls"})
+		return
+	}
+
+	known := make(map[string]struct{})
+	for _, s := range proxyutils.DiscoveredSources(true) {
+		known[s.URL] = struct{}{}
+	}
+	configured := make(map[string]struct{}, len(config.GlobalConfig.SubUrls))
+	for _, s := range config.GlobalConfig.SubUrls {
+		configured[strings.TrimSpace(s.URL)] = struct{}{}
+	}
+	var add []string
+	for _, u := range req.URLs {
+		u = strings.TrimSpace(u)
+		if _, ok := known[u]; !ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "不在发现记录中: " + u})
+			return
+		}
+		if _, ok := configured[u]; !ok {
+			configured[u] = struct{}{}
+			add = append(add, u)
+		}
+	}
+
+	if len(add) > 0 {
+		raw, err := os.ReadFile(app.configPath)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "读取配置文件失败: " + err.Error()})
+			return
+		}
+		updated, err := appendSubURLsInYAMLContent(string(raw), add)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if err := os.WriteFile(app.configPath, []byte(updated), 0o644); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "保存配置文件失败: " + err.Error()})
+			return
+		}
+	}
+	if _, err := proxyutils.UpdateDiscovered(req.URLs, false); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "更新发现记录失败: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": fmt.Sprintf("已加入 %d 个订阅", len(add)), "added": add})
+}
+
+// dismissDiscovered 忽略发现的订阅，之后不再加入检测
+func (app *App) dismissDiscovered(c *gin.Context) {
+	var req struct {
+		URLs []string `json:"urls"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil || len(req.URLs) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请提供 urls"})
+		return
+	}
+	n, err := proxyutils.UpdateDiscovered(req.URLs, true)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "更新发现记录失败: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": fmt.Sprintf("已忽略 %d 个订阅", n)})
+}
+
+// appendSubURLsInYAMLContent 在 YAML 文本的 sub-urls 列表开头追加订阅，保留注释和格式。
+// 配置中没有 sub-urls 时在末尾新建；行内写法（sub-urls: [a, b]）无法安全追加，返回错误
+func appendSubURLsInYAMLContent(content string, urls []string) (string, error) {
+	entries := make([]string, 0, len(urls))
+	for _, u := range urls {
+		entries = append(entries, "  - "+strconv.Quote(u)+" # 订阅发现")
+	}
+
+	lines := strings.Split(content, "\n")
+	for i, line := range lines {
+		rest, ok := strings.CutPrefix(strings.TrimRight(line, "\r"), "sub-urls:")
+		if !ok {
+			continue
+		}
+		value, _, _ := strings.Cut(rest, "#")
+		switch strings.TrimSpace(value) {
+		case "", "[]":
+			lines[i] = "sub-urls:"
+		default:
+			return "", errors.New("配置中的 sub-urls 为行内写法，请手动添加")
+		}
+		lines = append(lines[:i+1], append(entries, lines[i+1:]...)...)
+		return strings.Join(lines, "\n"), nil
+	}
+
+	content = strings.TrimRight(content, "\n") + "\nsub-urls:\n" + strings.Join(entries, "\n") + "\n"
+	return content, nil
+}
@@ -18,6 +18,7 @@ import (
 // StatusData 包含当前检测的所有状态信息
 type StatusData struct {
 	IsChecking    bool
+	IsPaused      bool
 	StepName      string
 	ProxyCount    int64
 	Processed     int64
@@ -48,10 +49,14 @@ func (app *App) GetCurrentState() StatusData {
 	case etaSec > 0:
 		etaSuffix = " ETA: " + check.FormatEta(etaSec)
 	}
+	if check.Paused.Load() {
+		etaSuffix = " [已暂停]"
+	}
 
 	// 2. 将 uint32 强转为 int64
 	data := StatusData{
 		IsChecking: app.checking.Load(),
+		IsPaused:   check.Paused.Load(),
 		StepName:   stepName,
 		ProxyCount: int64(check.ProxyCount.Load()),
 		Processed:  int64(check.Processed.Load()),
@@ -24,6 +24,7 @@ import (
 	"github.com/sinspired/subs-check-pro/v2/assets"
 	"github.com/sinspired/subs-check-pro/v2/check"
 	"github.com/sinspired/subs-check-pro/v2/config"
+	proxyutils "github.com/sinspired/subs-check-pro/v2/proxy"
 	"github.com/sinspired/subs-check-pro/v2/save/method"
 	"github.com/sinspired/subs-check-pro/v2/utils"
 )
@@ -38,6 +39,7 @@ const (
 	AdminPath       = "/admin"
 	SubPath         = "/sub"
 	SharePath       = "/share"
+	SubscribePath   = "/subscribe"
 	PublicPath      = "/more"
 	FilesPath       = "/files"
 	AnalysisPath    = "/analysis"
@@ -208,6 +210,11 @@ func (app *App) registerStaticRoutes(router *gin.Engine, outputPath string) {
 		"/history.yaml": "history.yaml", // 历史节点
 		"/base64.yaml":  "base64.yaml",  // Base64 格式
 		"/mihomo.yaml":  "mihomo.yaml",  // Mihomo 格式
+		// 客户端配置导出，需在 exporters 中启用
+		"/surge.conf":       "surge.conf",
+		"/loon.conf":        "loon.conf",
+		"/quanx.list":       "quanx.list",
+		"/shadowrocket.txt": "shadowrocket.txt",
 	}
 	for routePath, fileName := range protectedFiles {
 		// 映射到 outputPath/sub 下的文件
@@ -230,6 +237,8 @@ func (app *App) registerShareRoutes(router *gin.Engine, outputPath string) error
 	router.GET(SubPath+"/", app.handleEncryptedShare(encryptedShareDir))
 	router.GET(SharePath, app.handleEncryptedShare(encryptedShareDir))
 	router.GET(SharePath+"/", app.handleEncryptedShare(encryptedShareDir))
+	// 统一订阅入口，按 User-Agent 或 target 参数返回对应客户端格式
+	router.GET(SubscribePath, app.handleSubscribe(encryptedShareDir))
 
 	// 2. 公开分享路由 (/more/...)
 	moreDirPath := filepath.Join(publicShareDir, ShareDirName)
@@ -294,14 +303,25 @@ func (app *App) registerAPIRoutes(router *gin.Engine) {
 		api.GET("/config", app.getConfig)
 		api.POST("/config", app.updateConfig)
 		api.GET("/status", app.getStatus)
+		api.GET("/scores", app.getScores)
+		api.GET("/sources/health", app.getSourceHealth)
+		api.GET("/sources/:id/diagnostics", app.getSourceDiagnostics)
+		api.POST("/sources/:id/enable", app.enableSource)
+		api.GET("/discovered", app.getDiscovered)
+		api.POST("/discovered/promote", app.promoteDiscovered)
+		api.POST("/discovered/dismiss", app.dismissDiscovered)
 		api.POST("/trigger-check", app.triggerCheckHandler)
 		api.POST("/force-close", app.forceCloseHandler)
+		api.POST("/pause", app.pauseHandler)
+		api.POST("/resume", app.resumeHandler)
 		api.GET("/version", app.getVersion)
 		api.GET("/singbox-versions", app.getSingboxVersions)
 		api.GET("/logs", app.getLogs)
 		api.GET("/analysis-report", app.getAnalysisReport)
 		api.POST("/proxy/check", app.proxyCheckHandler)
 		api.POST("/notify/test", app.notifyTestHandler)
+		api.GET("/convert", app.convertHandler)
+		api.POST("/convert", app.convertHandler)
 	}
 }
 
@@ -433,6 +453,48 @@ func (app *App) updateConfig(c *gin.Context) {
 	})
 }
 
+// getSourceHealth 获取各订阅源的健康记录，已停用的在前
+func (app *App) getSourceHealth(c *gin.Context) {
+	c.JSON(http.StatusOK, proxyutils.SourceHealthList())
+}
+
+// enableSource 手动恢复自动停用的订阅源，id 为分析报告中的订阅 id 或订阅 URL
+func (app *App) enableSource(c *gin.Context) {
+	h, ok, err := proxyutils.EnableSource(c.Param("id"))
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "未找到该订阅源的健康记录"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "保存订阅健康记录失败: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, h)
+}
+
+// getSourceDiagnostics 获取单个订阅源最近一轮的解析诊断，id 为分析报告中的订阅 id
+func (app *App) getSourceDiagnostics(c *gin.Context) {
+	d, ok := proxyutils.DiagnosticsOf(c.Param("id"))
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "未找到该订阅源的诊断记录"})
+		return
+	}
+	c.JSON(http.StatusOK, d)
+}
+
+// getScores 获取最近一轮检测的节点评分（按评分降序）
+func (app *App) getScores(c *gin.Context) {
+	scores := check.LastScores()
+	if scores == nil {
+		scores = []check.NodeScore{}
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"enabled": config.GlobalConfig.Score.Enable,
+		"total":   len(scores),
+		"nodes":   scores,
+	})
+}
+
 // getStatus 获取检测状态
 func (app *App) getStatus(c *gin.Context) {
 	lastCheckTime := ""
@@ -459,12 +521,15 @@ func (app *App) getStatus(c *gin.Context) {
 		"available":         check.Available.Load(),
 		"progress":          check.Progress.Load(),
 		"forceClose":        check.ForceClose.Load(),
+		"paused":            check.Paused.Load(),
+		"budgetExceeded":    check.BudgetExceeded.Load(),
 		"successlimited":    check.Successlimited.Load(),
 		"processResults":    check.ProcessResults.Load(),
 		"lastCheck":         lastCheck,
 		"isSubStoreRunning": assets.IsSubStoreRunning.Load(),
 		"subStoreSyncing":   subStoreSyncing.Load(),  // 将后台更新状态暴露给前端
 		"eta":               check.ETASeconds.Load(), // -1=计算中, 0=完成, >0=剩余秒
+		"subQuotas":         proxyutils.Quotas(),     // 上游订阅流量与到期信息
 
 		"subStorePort":  config.GlobalConfig.SubStorePort,
 		"subStorePath":  config.GlobalConfig.SubStorePath,
@@ -483,6 +548,24 @@ func (app *App) forceCloseHandler(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "已强制关闭"})
 }
 
+// pauseHandler 暂停当前检测，保留检测状态以便恢复
+func (app *App) pauseHandler(c *gin.Context) {
+	if !check.Pause() {
+		c.JSON(http.StatusConflict, gin.H{"error": "当前没有可暂停的检测"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "已暂停检测"})
+}
+
+// resumeHandler 恢复已暂停的检测
+func (app *App) resumeHandler(c *gin.Context) {
+	if !check.Resume() {
+		c.JSON(http.StatusConflict, gin.H{"error": "检测未处于暂停状态"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "已恢复检测"})
+}
+
 // getLogs 获取日志
 func (app *App) getLogs(c *gin.Context) {
 	logPath := TempLog()
@@ -12,6 +12,7 @@ import (
 	"github.com/goccy/go-yaml"
 	"github.com/sinspired/subs-check-pro/v2/check"
 	"github.com/sinspired/subs-check-pro/v2/config"
+	proxyutils "github.com/sinspired/subs-check-pro/v2/proxy"
 )
 
 // reportFallback 从分析报告中提取的兜底数据
@@ -56,6 +57,8 @@ func (app *App) handleSubscriptionInfo(c *gin.Context) {
 //   - upload / download  来自 check.UP / check.DOWN（原子计数器，单位 bytes）
 //   - total              固定 1024 TiB，1 PB
 //   - expire             固定 2077-06-01 UTC Unix 时间戳
+//     开启 sub-quota.aggregate 时，upload/download/total 改为上游限量订阅的汇总，
+//     expire 取最早的未过期时间，没有上游数据时仍使用上述占位值
 //   - reset_hour         距下次重置不足 1 天时显示，值为重置时刻的小时数
 //   - reset_day          距下次重置超过 1 天时显示，值为剩余整天数
 //   - next_update        下次重置的格式化时间（始终显示）
@@ -103,12 +106,23 @@ func buildSubscriptionInfo() string {
 		resetField = "reset_day=" + strconv.Itoa(resetDays)
 	}
 
+	total := uint64(totalBytes)
+	expire := expireUnix
+	if config.GlobalConfig.SubQuota.Aggregate {
+		if up, down, t, exp, ok := proxyutils.AggregateQuota(now); ok {
+			upload, download, total = up, down, t
+			if exp > 0 {
+				expire = exp
+			}
+		}
+	}
+
 	var b strings.Builder
 
 	writeKV(&b, "upload", strconv.FormatUint(upload, 10))
 	writeKV(&b, "download", strconv.FormatUint(download, 10))
-	writeKV(&b, "total", strconv.FormatInt(totalBytes, 10))
-	writeKV(&b, "expire", strconv.FormatInt(expireUnix, 10))
+	writeKV(&b, "total", strconv.FormatUint(total, 10))
+	writeKV(&b, "expire", strconv.FormatInt(expire, 10))
 
 	b.WriteString(resetField)
 	b.WriteString("; ")
@@ -0,0 +1,114 @@
+package app
+
+import (
+	"log/slog"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/sinspired/subs-check-pro/v2/config"
+	proxyutils "github.com/sinspired/subs-check-pro/v2/proxy"
+)
+
+// subDirDebounce 新文件写入完成的等待时间，期间的多个文件合并为一次检测
+const subDirDebounce = 3 * time.Second
+
+// initSubDirWatcher 按 sub-dirs-watch 监听本地订阅目录，重复调用时先关闭旧的监听
+func (app *App) initSubDirWatcher() {
+	if app.subDirWatcher != nil {
+		_ = app.subDirWatcher.Close()
+		app.subDirWatcher = nil
+	}
+	if !config.GlobalConfig.SubDirsWatch || len(config.GlobalConfig.SubDirs) == 0 {
+		return
+	}
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		slog.Warn("本地订阅目录监听启动失败，新文件将在下次检测时处理", "error", err)
+		return
+	}
+	patterns := make(map[string][]string) // 目录 → 文件名 glob
+	for _, entry := range config.GlobalConfig.SubDirs {
+		dir, pattern := proxyutils.SubDirPattern(entry)
+		if _, ok := patterns[dir]; !ok {
+			if err := watcher.Add(dir); err != nil {
+				slog.Warn("监听本地订阅目录失败", "目录", dir, "error", err)
+				continue
+			}
+		}
+		patterns[dir] = append(patterns[dir], pattern)
+	}
+	if len(patterns) == 0 {
+		_ = watcher.Close()
+		return
+	}
+
+	app.subDirWatcher = watcher
+	go app.watchSubDirs(watcher, patterns)
+	slog.Info("本地订阅目录监听启动", "目录数", len(patterns))
+}
+
+// watchSubDirs 收集新增或修改的订阅文件，防抖后触发检测。
+// 开启 keep-success-proxies 时只检测新文件与上次成功的节点，否则执行完整检测
+func (app *App) watchSubDirs(watcher *fsnotify.Watcher, patterns map[string][]string) {
+	var (
+		mu      sync.Mutex
+		pending = make(map[string]struct{})
+		timer   *time.Timer
+	)
+	flush := func() {
+		mu.Lock()
+		files := slices.Sorted(maps.Keys(pending))
+		clear(pending)
+		mu.Unlock()
+
+		if app.checking.Load() {
+			slog.Info("检测进行中，新增的订阅文件将在下次检测时处理", "数量", len(files))
+			return
+		}
+		if config.GlobalConfig.KeepSuccessProxies {
+			proxyutils.QueueLocalFiles(files)
+		}
+		slog.Info("本地订阅目录有新文件，触发检测", "数量", len(files))
+		app.TriggerCheck()
+	}
+
+	for {
+		select {
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return
+			}
+			if event.Op&(fsnotify.Create|fsnotify.Write) == 0 {
+				continue
+			}
+			name := filepath.Base(event.Name)
+			if !slices.ContainsFunc(patterns[filepath.Dir(event.Name)], func(p string) bool {
+				return proxyutils.MatchSubFile(name, p)
+			}) {
+				continue
+			}
+			if info, err := os.Stat(event.Name); err != nil || info.IsDir() {
+				continue
+			}
+
+			mu.Lock()
+			pending[event.Name] = struct{}{}
+			if timer != nil {
+				timer.Stop()
+			}
+			timer = time.AfterFunc(subDirDebounce, flush)
+			mu.Unlock()
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return
+			}
+			slog.Error("本地订阅目录监听错误", "error", err)
+		}
+	}
+}
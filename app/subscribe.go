@@ -0,0 +1,170 @@
+// Package app: subscribe.go
+package app
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/goccy/go-yaml"
+	"github.com/sinspired/subs-check-pro/v2/assets"
+	"github.com/sinspired/subs-check-pro/v2/config"
+	"github.com/sinspired/subs-check-pro/v2/save"
+)
+
+// 统一订阅入口：同一个链接按 target 参数或客户端 User-Agent 返回对应格式，
+// 访问需携带 api-key（请求头或 key 参数）或分享码（code 参数）。
+
+// 订阅格式
+const (
+	targetClash        = "clash"
+	targetSingBox      = "sing-box"
+	targetV2Ray        = "v2ray"
+	targetShadowrocket = "shadowrocket"
+	targetSurge        = "surge"
+	targetLoon         = "loon"
+	targetQuanX        = "quanx"
+)
+
+// targetAliases target 参数的可选写法
+var targetAliases = map[string]string{
+	"clash": targetClash, "mihomo": targetClash, "meta": targetClash, "clash.meta": targetClash, "stash": targetClash,
+	"sing-box": targetSingBox, "singbox": targetSingBox,
+	"v2ray": targetV2Ray, "v2rayn": targetV2Ray, "v2rayng": targetV2Ray, "base64": targetV2Ray,
+	"shadowrocket": targetShadowrocket, "loon": targetLoon,
+	"surge": targetSurge, "surfboard": targetSurge,
+	"quanx": targetQuanX, "qx": targetQuanX, "quantumult": targetQuanX, "quantumultx": targetQuanX,
+}
+
+// uaTargets 按 User-Agent 关键字识别客户端，按顺序匹配，专用客户端排在通用内核关键字之前
+var uaTargets = []struct {
+	keywords []string
+	target   string
+}{
+	{[]string{"shadowrocket"}, targetShadowrocket},
+	{[]string{"quantumult"}, targetQuanX},
+	{[]string{"surge", "surfboard"}, targetSurge},
+	{[]string{"loon"}, targetLoon},
+	{[]string{"sing-box", "singbox", "sfa/", "sfi/", "sfm/", "sft/"}, targetSingBox},
+	{[]string{"v2rayn", "v2rayng", "v2rayu", "v2box"}, targetV2Ray},
+	{[]string{"clash", "mihomo", "stash"}, targetClash},
+}
+
+// resolveSubscribeTarget 确定订阅格式：target 参数优先，其次 User-Agent，均无法识别时返回 clash
+func resolveSubscribeTarget(target, userAgent string) string {
+	if t, ok := targetAliases[strings.ToLower(strings.TrimSpace(target))]; ok {
+		return t
+	}
+	ua := strings.ToLower(userAgent)
+	for _, u := range uaTargets {
+		for _, kw := range u.keywords {
+			if strings.Contains(ua, kw) {
+				return u.target
+			}
+		}
+	}
+	return targetClash
+}
+
+// subscribeAuthorized 校验 api-key 或分享码
+func subscribeAuthorized(c *gin.Context) bool {
+	if key := cmp.Or(c.GetHeader(APIAuthHeader), c.Query("key")); key != "" &&
+		equalConstantTime(key, config.GlobalConfig.APIKey) {
+		return true
+	}
+	// 未配置分享码时分享功能关闭，只接受 api-key
+	if pw := config.GlobalConfig.SharePassword; pw != "" {
+		if code := c.Query("code"); code != "" && equalConstantTime(code, pw) {
+			return true
+		}
+	}
+	return false
+}
+
+// handleSubscribe 处理统一订阅入口 /subscribe
+func (app *App) handleSubscribe(subDir string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !subscribeAuthorized(c) {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "无效的API密钥或分享码"})
+			return
+		}
+
+		target := resolveSubscribeTarget(c.Query("target"), c.GetHeader("User-Agent"))
+		data, err := subscribeContent(subDir, target)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error(), "target": target})
+			return
+		}
+
+		c.Header("Cache-Control", "no-store")
+		c.Header("subscription-userinfo", buildSubscriptionInfo())
+		c.Data(http.StatusOK, "text/plain; charset=utf-8", data)
+	}
+}
+
+// subscribeContent 读取目标格式的订阅内容。
+// 导出文件不存在时（未在 exporters 中启用）由 all.yaml 即时转换
+func subscribeContent(subDir, target string) ([]byte, error) {
+	switch target {
+	case targetClash:
+		return readFirstFile(subDir, "mihomo.yaml", "all.yaml")
+	case targetSingBox:
+		// sing-box 配置由 sub-store 生成
+		if config.GlobalConfig.SubStorePort == "" || !assets.IsSubStoreRunning.Load() {
+			return nil, errors.New("sing-box 格式需要启用 sub-store")
+		}
+		data, err := save.DownloadSubStore("sing-box")
+		if err != nil {
+			return nil, fmt.Errorf("获取 sing-box 订阅失败: %w", err)
+		}
+		return data, nil
+	case targetV2Ray:
+		if data, err := readFirstFile(subDir, "base64.txt"); err == nil {
+			return data, nil
+		}
+		// 未运行 sub-store 时没有 base64.txt，Shadowrocket 的 base64 链接列表与 v2rayN 兼容
+		return exportFromAll(subDir, targetShadowrocket)
+	default:
+		if file, ok := save.ExportFile(target); ok {
+			if data, err := readFirstFile(subDir, file); err == nil {
+				return data, nil
+			}
+		}
+		return exportFromAll(subDir, target)
+	}
+}
+
+// readFirstFile 按顺序返回第一个存在且非空的文件内容
+func readFirstFile(dir string, names ...string) ([]byte, error) {
+	for _, name := range names {
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		if err == nil && len(data) > 0 {
+			return data, nil
+		}
+	}
+	return nil, fmt.Errorf("订阅文件不存在: %s", strings.Join(names, ", "))
+}
+
+// exportFromAll 读取 all.yaml 并即时转换为指定客户端格式
+func exportFromAll(subDir, target string) ([]byte, error) {
+	data, err := readFirstFile(subDir, "all.yaml")
+	if err != nil {
+		return nil, err
+	}
+	var parsed struct {
+		Proxies []map[string]any `yaml:"proxies"`
+	}
+	if err := yaml.Unmarshal(data, &parsed); err != nil {
+		return nil, fmt.Errorf("解析 all.yaml 失败: %w", err)
+	}
+	out := save.Export(target, parsed.Proxies)
+	if len(out) == 0 {
+		return nil, fmt.Errorf("没有可导出为 %s 格式的节点", target)
+	}
+	return out, nil
+}
@@ -0,0 +1,76 @@
+package app
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sinspired/subs-check-pro/v2/config"
+)
+
+func TestResolveSubscribeTarget(t *testing.T) {
+	cases := []struct {
+		name, target, ua, want string
+	}{
+		{"shadowrocket", "", "Shadowrocket/2070 CFNetwork/1410.0.3 Darwin/22.4.0", targetShadowrocket},
+		{"stash", "", "Stash/2.4.6 Clash/1.9.0", targetClash},
+		{"sfa", "", "SFA/1.9.0 (Android 14; sing-box 1.9.0)", targetSingBox},
+		{"sfi", "", "SFI/1.9.0 (iOS 17.4)", targetSingBox},
+		{"v2rayn", "", "v2rayN/6.42", targetV2Ray},
+		{"v2rayng", "", "v2rayNG/1.8.19", targetV2Ray},
+		{"quantumult", "", "Quantumult%20X/1.4.1 (iPhone14,2; iOS 17.4)", targetQuanX},
+		{"surge", "", "Surge iOS/2920", targetSurge},
+		{"loon", "", "Loon/3.2.1 CFNetwork/1494 Darwin/23.4.0", targetLoon},
+		{"clash verge", "", "clash-verge/v1.6.0 mihomo/1.18", targetClash},
+		{"unknown ua", "", "curl/8.5.0", targetClash},
+		{"empty", "", "", targetClash},
+		// target 参数优先于 User-Agent
+		{"target over shadowrocket", "singbox", "Shadowrocket/2070", targetSingBox},
+		{"target over v2rayn", "QX", "v2rayN/6.42", targetQuanX},
+		{"target trimmed", " Surfboard ", "", targetSurge},
+		{"unknown target falls back to ua", "foo", "v2rayN/6.42", targetV2Ray},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := resolveSubscribeTarget(c.target, c.ua); got != c.want {
+				t.Errorf("resolveSubscribeTarget(%q, %q) = %q, want %q", c.target, c.ua, got, c.want)
+			}
+		})
+	}
+}
+
+func TestSubscribeAuthorized(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	oldCfg := config.GlobalConfig
+	defer func() { config.GlobalConfig = oldCfg }()
+
+	cases := []struct {
+		name, apiKey, sharePassword string
+		header, query               string
+		want                        bool
+	}{
+		{"key in header", "secret", "", "secret", "", true},
+		{"key in query", "secret", "", "", "key=secret", true},
+		{"wrong key", "secret", "", "nope", "key=nope", false},
+		{"header wins over query", "secret", "", "nope", "key=secret", false},
+		{"share code", "secret", "share", "", "code=share", true},
+		{"wrong share code", "secret", "share", "", "code=nope", false},
+		{"key is not a share code", "secret", "share", "", "code=secret", false},
+		{"empty share password disables code", "secret", "", "", "code=", false},
+		{"empty api key rejects empty key", "", "", "", "key=", false},
+		{"no credentials", "secret", "share", "", "", false},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			config.GlobalConfig = &config.Config{APIKey: c.apiKey, SharePassword: c.sharePassword}
+			ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+			ctx.Request = httptest.NewRequest("GET", "/subscribe?"+c.query, nil)
+			if c.header != "" {
+				ctx.Request.Header.Set(APIAuthHeader, c.header)
+			}
+			if got := subscribeAuthorized(ctx); got != c.want {
+				t.Errorf("got %v, want %v", got, c.want)
+			}
+		})
+	}
+}
@@ -29,19 +29,38 @@ type githubRelease struct {
 	} `json:"assets"`
 }
 
-// OpenMaxMindDB 使用指定路径或默认路径打开 MaxMind 数据库
+// MaxMind 数据库版本名，与 release 中的文件名一致
+const (
+	GeoLite2Country = "GeoLite2-Country"
+	GeoLite2City    = "GeoLite2-City"
+	GeoLite2ASN     = "GeoLite2-ASN"
+)
+
+// OpenMaxMindDB 使用指定路径或默认路径打开 MaxMind 国家数据库
 func OpenMaxMindDB(dbPath string) (*maxminddb.Reader, error) {
+	return OpenMaxMindEdition(GeoLite2Country, dbPath)
+}
+
+// OpenMaxMindEdition 打开指定版本的 MaxMind 数据库（Country/City/ASN 或兼容的 mmdb）。
+// dbPath 为空时使用默认路径：国家库从内置文件解压，城市库与 ASN 库首次使用时自动下载。
+func OpenMaxMindEdition(edition, dbPath string) (*maxminddb.Reader, error) {
 	if dbPath != "" {
 		return openDBWithArch(dbPath)
 	}
-	mmdbPath, err := resolveDBPath()
+	mmdbPath, err := resolveDBPath(edition)
 	if err != nil {
 		return nil, err
 	}
 
-	// 如果数据库不存在，先解压生成
+	// 如果数据库不存在，先解压或下载
 	if _, err := os.Stat(mmdbPath); os.IsNotExist(err) {
-		if err := decompressEmbeddedMMDB(mmdbPath); err != nil {
+		if edition == GeoLite2Country {
+			err = decompressEmbeddedMMDB(mmdbPath)
+		} else {
+			slog.Info(edition + ".mmdb 不存在，开始下载")
+			_, err = updateEditions([]string{edition})
+		}
+		if err != nil {
 			return nil, err
 		}
 	}
@@ -91,7 +110,7 @@ func decompressEmbeddedMMDB(targetPath string) error {
 }
 
 // 解析数据库存放路径
-func resolveDBPath() (string, error) {
+func resolveDBPath(edition string) (string, error) {
 	saver, err := method.NewLocalSaver()
 	if err != nil {
 		return "", err
@@ -117,7 +136,7 @@ func resolveDBPath() (string, error) {
 	if err := os.MkdirAll(maxminddbDir, 0o755); err != nil {
 		return "", fmt.Errorf("无法创建 MaxMind 输出目录: %w", err)
 	}
-	return filepath.Join(maxminddbDir, "GeoLite2-Country.mmdb"), nil
+	return filepath.Join(maxminddbDir, edition+".mmdb"), nil
 }
 
 // 32 位程序使用从内存读取的方式
@@ -136,34 +155,85 @@ func openFromBytes(path string) (*maxminddb.Reader, error) {
 	return reader, nil
 }
 
-// UpdateGeoLite2DB 检查并更新 GeoLite2 数据库
+// UpdateGeoLite2DB 检查并更新 GeoLite2 数据库。
+// 国家库始终更新；城市库与 ASN 库在启用或本地已存在时更新。
 func UpdateGeoLite2DB() error {
-	dbPath, err := resolveDBPath()
+	editions := []string{GeoLite2Country}
+	for _, e := range []struct {
+		edition string
+		enabled bool
+	}{
+		{GeoLite2City, config.GlobalConfig.GeoCity},
+		{GeoLite2ASN, config.GlobalConfig.GeoASN},
+	} {
+		if path, err := resolveDBPath(e.edition); e.enabled || (err == nil && fileExists(path)) {
+			editions = append(editions, e.edition)
+		}
+	}
+
+	version, err := updateEditions(editions)
+	if version != "" {
+		utils.SendNotifyGeoDBUpdate(version)
+	}
+	return err
+}
+
+// updateEditions 从最新 release 下载指定版本的数据库，返回 release 版本号。
+// 单个数据库失败不影响其他数据库，错误合并返回。
+func updateEditions(editions []string) (string, error) {
+	rel, err := fetchGeoLite2Release()
 	if err != nil {
-		return fmt.Errorf("解析数据库路径失败: %w", err)
+		return "", err
 	}
 
+	var errs []error
+	updated := false
+	for _, edition := range editions {
+		if err := updateEdition(rel, edition); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", edition, err))
+			continue
+		}
+		updated = true
+	}
+	if !updated {
+		return "", errors.Join(errs...)
+	}
+	return rel.TagName, errors.Join(errs...)
+}
+
+// fetchGeoLite2Release 获取 GeoLite2 数据库最新 release 信息
+func fetchGeoLite2Release() (*githubRelease, error) {
 	apiURL := "https://api.github.com/repos/mojolabs-id/GeoLite2-Database/releases/latest"
 
 	resp, err := http.Get(apiURL)
 	if err != nil {
-		return fmt.Errorf("获取 release 信息失败: %w", err)
+		return nil, fmt.Errorf("获取 release 信息失败: %w", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("GitHub API 状态码: %d", resp.StatusCode)
+		return nil, fmt.Errorf("GitHub API 状态码: %d", resp.StatusCode)
 	}
 
 	var rel githubRelease
 	if err := json.NewDecoder(resp.Body).Decode(&rel); err != nil {
-		return fmt.Errorf("解析 release JSON 失败: %w", err)
+		return nil, fmt.Errorf("解析 release JSON 失败: %w", err)
 	}
+	return &rel, nil
+}
 
+// updateEdition 下载单个数据库，失败时回退原文件
+func updateEdition(rel *githubRelease, edition string) error {
+	dbPath, err := resolveDBPath(edition)
+	if err != nil {
+		return fmt.Errorf("解析数据库路径失败: %w", err)
+	}
+
+	fileName := edition + ".mmdb"
 	var downloadURL string
 	isGhProxy := utils.GetGhProxy()
 	for _, asset := range rel.Assets {
-		if asset.Name == "GeoLite2-Country.mmdb" {
+		if asset.Name == fileName {
 			downloadURL = asset.BrowserDownloadURL
 			if isGhProxy {
 				downloadURL = config.GlobalConfig.GithubProxy + asset.BrowserDownloadURL
@@ -172,7 +242,7 @@ func UpdateGeoLite2DB() error {
 		}
 	}
 	if downloadURL == "" {
-		return errors.New("未找到 GeoLite2-Country.mmdb 下载地址")
+		return errors.New("未找到 " + fileName + " 下载地址")
 	}
 
 	// 备份原文件
@@ -205,12 +275,16 @@ func UpdateGeoLite2DB() error {
 
 	// 成功则删除备份
 	_ = os.Remove(bakPath)
-	slog.Info("GeoLite2-Country.mmdb 更新完成")
-	version := rel.TagName
-	utils.SendNotifyGeoDBUpdate(version)
+	slog.Info(fileName + " 更新完成")
 	return nil
 }
 
+// fileExists 判断文件是否存在
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
 func downloadFile(url, path string) error {
 	resp, err := http.Get(url)
 	if err != nil {
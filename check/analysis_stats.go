@@ -10,9 +10,11 @@ import (
 	"sync/atomic"
 	"time"
 
+	"github.com/sinspired/subs-check-pro/v2/check/platform"
 	"github.com/sinspired/subs-check-pro/v2/config"
 	proxyutils "github.com/sinspired/subs-check-pro/v2/proxy"
 	"github.com/sinspired/subs-check-pro/v2/save/method"
+	"github.com/sinspired/subs-check-pro/v2/utils"
 )
 
 var (
@@ -49,6 +51,8 @@ type AnalysisStats struct {
 	Total     int
 	Types     map[string]int
 	Countries map[string]int
+	Cities    map[string]int // 需开启 geo-city
+	ASNs      map[string]int // 需开启 geo-asn，键如 "AS906 Cogent"
 	CFIncon   map[string]int // ⁰ (不一致)
 	CFCon     map[string]int // ¹⁺ (一致)
 	CFBlock   map[string]int // ⁻¹ (proxyIP 异常)
@@ -60,6 +64,8 @@ func newAnalysisStats() *AnalysisStats {
 	return &AnalysisStats{
 		Types:     make(map[string]int),
 		Countries: make(map[string]int),
+		Cities:    make(map[string]int),
+		ASNs:      make(map[string]int),
 		CFIncon:   make(map[string]int),
 		CFCon:     make(map[string]int),
 		CFBlock:   make(map[string]int),
@@ -71,15 +77,29 @@ func newAnalysisStats() *AnalysisStats {
 // GenerateAnalysisReport 生成节点质量分析报告
 func (pc *ProxyChecker) GenerateAnalysisReport() {
 	// 统计可用节点数量
+	alive := make(map[string]int) // 按来源订阅统计，多个订阅提供的节点计入每一个来源
 	for _, result := range pc.results {
 		if result.Proxy != nil {
 			if subURL, ok := result.Proxy["sub_url"].(string); ok {
 				stats := proxyutils.SubStats[subURL]
 				stats.Success++
 				proxyutils.SubStats[subURL] = stats
+				if providers, ok := result.Proxy["sub_providers"].([]string); ok {
+					for _, p := range providers {
+						alive[p]++
+					}
+				} else {
+					alive[subURL]++
+				}
 			}
 		}
 	}
+	// 提前结束的检测没有测完全部节点，不计入订阅健康
+	if ForceClose.Load() || Successlimited.Load() || BudgetExceeded.Load() {
+		slog.Info("检测未完整完成，本轮不更新订阅健康记录")
+	} else {
+		proxyutils.UpdateSourceHealth(alive)
+	}
 
 	globalAnalysis := newAnalysisStats()
 	subAnalysis := make(map[string]*AnalysisStats)
@@ -133,6 +153,14 @@ func (pc *ProxyChecker) GenerateAnalysisReport() {
 				}
 			}
 
+			// 城市与 ASN
+			if result.City != "" {
+				s.Cities[result.City]++
+			}
+			if result.ASN > 0 {
+				s.ASNs[strings.TrimSpace("AS"+strconv.FormatUint(uint64(result.ASN), 10)+" "+result.ASOrg)]++
+			}
+
 			// AI解锁
 			if reMediaGPT.MatchString(name) {
 				if strings.Contains(name, "GPT⁺") {
@@ -192,7 +220,7 @@ func (pc *ProxyChecker) GenerateAnalysisReport() {
 	// 并保存订阅成功率统计并打印成功率过低日志
 	checkSubsSuccessRate(subAnalysis, sortedURLs)
 	// 保存深度分析报告
-	saveDetailedAnalysis(globalAnalysis, subAnalysis, sortedURLs)
+	saveDetailedAnalysis(globalAnalysis, subAnalysis, sortedURLs, pc.results)
 
 	// 终端输出总结
 	logSummary(globalAnalysis)
@@ -218,7 +246,7 @@ func (pc *ProxyChecker) GenerateAnalysisReport() {
 }
 
 // saveDetailedAnalysis 输出包含总结和可视化数据的报告
-func saveDetailedAnalysis(global *AnalysisStats, subs map[string]*AnalysisStats, sortedURLs []string) {
+func saveDetailedAnalysis(global *AnalysisStats, subs map[string]*AnalysisStats, sortedURLs []string, results []Result) {
 	var sb strings.Builder
 	sb.WriteString("# 检测结果分析报告\n")
 	sb.WriteString("# 生成时间: ");sb.WriteString(time.Now().Format(time.DateTime));sb.WriteString("\n\n")
@@ -254,6 +282,21 @@ func saveDetailedAnalysis(global *AnalysisStats, subs map[string]*AnalysisStats,
 	}
 	sb.WriteString("  check_min_speed: ");sb.WriteString(speedText);sb.WriteString("\n")
 	sb.WriteString("  check_success_limit: ");sb.WriteString(strconv.FormatInt(int64(config.GlobalConfig.SuccessLimit), 10));sb.WriteString("\n")
+	if config.GlobalConfig.PreDial.Enable {
+		sb.WriteString("  check_predial_pruned: ");sb.WriteString(strconv.FormatUint(uint64(PreDialPruned.Load()), 10));sb.WriteString("\n")
+	}
+	if config.GlobalConfig.PlatformRateLimit.Enable {
+		sb.WriteString("  check_probes_throttled: ");sb.WriteString(strconv.FormatUint(platform.ThrottledProbes.Load(), 10));sb.WriteString("\n")
+		sb.WriteString("  check_probes_rate_limited: ");sb.WriteString(strconv.FormatUint(platform.RateLimitedProbes.Load(), 10));sb.WriteString("\n")
+	}
+	if budgetEnabled() {
+		sb.WriteString("  check_traffic_budget_run_raw: ");sb.WriteString(strconv.FormatUint(uint64(config.GlobalConfig.MaxTrafficPerRun)*1024*1024, 10));sb.WriteString("\n")
+		sb.WriteString("  check_traffic_budget_day_raw: ");sb.WriteString(strconv.FormatUint(uint64(config.GlobalConfig.MaxTrafficPerDay)*1024*1024, 10));sb.WriteString("\n")
+		if config.GlobalConfig.MaxTrafficPerDay > 0 {
+			sb.WriteString("  check_traffic_daily_raw: ");sb.WriteString(strconv.FormatUint(dailyUsedBefore+TotalBytes.Load(), 10));sb.WriteString("\n")
+		}
+		sb.WriteString("  check_traffic_budget_exceeded: ");sb.WriteString(strconv.FormatBool(BudgetExceeded.Load()));sb.WriteString("\n")
+	}
 	sb.WriteString("\n")
 
 	// 2. 全局统计 (可视化友好结构)
@@ -261,6 +304,22 @@ func saveDetailedAnalysis(global *AnalysisStats, subs map[string]*AnalysisStats,
 	sb.WriteString("  alive_count: ");sb.WriteString(strconv.Itoa(global.Total));sb.WriteString("\n")
 	sb.WriteString("  geography_distribution:");sb.WriteString(formatMap(global.Countries, "    "));sb.WriteString("\n")
 	sb.WriteString("  protocol_distribution:");sb.WriteString(formatMap(global.Types, "    "));sb.WriteString("\n")
+	if len(global.Cities) > 0 {
+		sb.WriteString("  city_distribution:");sb.WriteString(formatMap(global.Cities, "    "));sb.WriteString("\n")
+	}
+	if len(global.ASNs) > 0 {
+		sb.WriteString("  asn_distribution:");sb.WriteString(formatMap(global.ASNs, "    "));sb.WriteString("\n")
+	}
+	if rejected, repaired := validationSummary(); len(rejected) > 0 || repaired > 0 {
+		sb.WriteString("  validation_repaired: ");sb.WriteString(strconv.Itoa(repaired));sb.WriteString("\n")
+		sb.WriteString("  validation_rejected:");sb.WriteString(formatMap(rejected, "    "));sb.WriteString("\n")
+	}
+	if filters := proxyutils.ActivePreFilters(); len(filters) > 0 {
+		sb.WriteString("  prefilter_rejected:\n")
+		for _, f := range filters {
+			sb.WriteString("    - { expr: ");sb.WriteString(strconv.Quote(f.String()));sb.WriteString(", count: ");sb.WriteString(strconv.FormatInt(f.Rejected(), 10));sb.WriteString(" }\n")
+		}
+	}
 
 	sb.WriteString("  quality_metrics:\n")
 	ratio := float64(getSum(global.CFCon)) / float64(max(1, global.Total)) * 100
@@ -272,6 +331,9 @@ func saveDetailedAnalysis(global *AnalysisStats, subs map[string]*AnalysisStats,
 	sb.WriteString("      blocked_⁻¹:");sb.WriteString(formatMap(global.CFBlock, "        "));sb.WriteString("\n")
 	sb.WriteString("    vps_details_²:");sb.WriteString(formatMap(global.NonCF, "      "));sb.WriteString("\n")
 
+	// 稳定性测试排行
+	writeStabilityRanking(&sb, results)
+
 	// 3. 订阅排行与明细
 	sb.WriteString("\nsubs_ranking:\n")
 
@@ -284,16 +346,150 @@ func saveDetailedAnalysis(global *AnalysisStats, subs map[string]*AnalysisStats,
 
 		if st != nil {
 			sb.WriteString("  - url: ");sb.WriteString(u);sb.WriteString("\n")
+			writeSourceLabel(&sb, u)
 			sb.WriteString("    stats: { rate: ");sb.WriteString(strconv.FormatFloat(rate*100, 'f', 4, 64));sb.WriteString("%, success: ");sb.WriteString(strconv.Itoa(pStat.Success));sb.WriteString(", total: ");sb.WriteString(strconv.Itoa(pStat.Total));sb.WriteString(" }\n")
 			sb.WriteString("    protocols: { ");sb.WriteString(formatMapToInline(st.Types));sb.WriteString(" }\n")
 			sb.WriteString("    top_locations: [");sb.WriteString(getTopKeys(st.Countries, 3));sb.WriteString("]\n")
+			if len(st.ASNs) > 0 {
+				sb.WriteString("    top_asns: [");sb.WriteString(getTopKeys(st.ASNs, 3));sb.WriteString("]\n")
+			}
+			if d, ok := proxyutils.DiagnosticsOf(u); ok && len(d.Rejected) > 0 {
+				sb.WriteString("    rejected: { ");sb.WriteString(formatMapToInline(d.Rejected));sb.WriteString(" }\n")
+			}
 		} else {
 			sbBad.WriteString("  - url: ");sbBad.WriteString(u);sbBad.WriteString("\n")
+			writeSourceLabel(&sbBad, u)
 			sbBad.WriteString("    stats: { rate: ");sbBad.WriteString(strconv.FormatFloat(rate*100, 'f', 4, 64));sbBad.WriteString("%, success: ");sbBad.WriteString(strconv.Itoa(pStat.Success));sbBad.WriteString(", total: ");sbBad.WriteString(strconv.Itoa(pStat.Total));sbBad.WriteString(" }\n")
+			writeDiagnostics(&sbBad, u)
+		}
+	}
+
+	// 未产出任何节点的订阅（拉取失败或解析为空），附解析诊断
+	var sbEmpty strings.Builder
+	for _, d := range proxyutils.Diagnostics() {
+		if _, ok := proxyutils.SubStats[d.URL]; ok {
+			continue
+		}
+		if sbEmpty.Len() == 0 {
+			sbEmpty.WriteString("\nsubs_no_nodes:\n")
+		}
+		sbEmpty.WriteString("  - url: ");sbEmpty.WriteString(d.URL);sbEmpty.WriteString("\n")
+		writeSourceLabel(&sbEmpty, d.URL)
+		writeDiagnostics(&sbEmpty, d.URL)
+	}
+
+	// 自动停用的订阅
+	var sbDisabled strings.Builder
+	for _, h := range proxyutils.SourceHealthList() {
+		if !h.Disabled {
+			continue
+		}
+		if sbDisabled.Len() == 0 {
+			sbDisabled.WriteString("\nsubs_disabled:\n")
+		}
+		sbDisabled.WriteString("  - url: ");sbDisabled.WriteString(h.URL);sbDisabled.WriteString("\n")
+		writeSourceLabel(&sbDisabled, h.URL)
+		sbDisabled.WriteString("    reason: ");sbDisabled.WriteString(strconv.Quote(h.LastReason));sbDisabled.WriteString("\n")
+		sbDisabled.WriteString("    disabled_at: ");sbDisabled.WriteString(h.DisabledAt.Format(time.DateTime));sbDisabled.WriteString("\n")
+	}
+
+	_ = method.SaveToStats([]byte(sb.String()+sbBad.String()+sbEmpty.String()+sbDisabled.String()), "subs-analysis.yaml", "分析结果")
+}
+
+// writeSourceLabel 输出订阅源的显示名称、标签、健康评分与上游流量信息
+func writeSourceLabel(sb *strings.Builder, u string) {
+	name, tags := proxyutils.SourceLabel(u)
+	sb.WriteString("    id: ");sb.WriteString(proxyutils.SourceID(u));sb.WriteString("\n")
+	if name != "" {
+		sb.WriteString("    name: ");sb.WriteString(strconv.Quote(name));sb.WriteString("\n")
+	}
+	if len(tags) > 0 {
+		sb.WriteString("    tags: [");sb.WriteString(strings.Join(tags, ", "));sb.WriteString("]\n")
+	}
+	if h, ok := proxyutils.SourceHealthOf(u); ok {
+		sb.WriteString("    health: { score: ");sb.WriteString(strconv.FormatFloat(h.Score, 'f', 1, 64))
+		sb.WriteString(", alive_ratio: ");sb.WriteString(strconv.FormatFloat(h.AliveRatio()*100, 'f', 2, 64));sb.WriteString("%")
+		sb.WriteString(", unique: ");sb.WriteString(strconv.Itoa(h.Unique))
+		sb.WriteString(", fetch_fails: ");sb.WriteString(strconv.Itoa(h.FetchFails));sb.WriteString("/");sb.WriteString(strconv.Itoa(h.Runs))
+		sb.WriteString(", bad_streak: ");sb.WriteString(strconv.Itoa(h.BadStreak))
+		if h.Disabled {
+			sb.WriteString(", recheck_in: ");sb.WriteString(strconv.Itoa(h.SkipLeft))
+		}
+		sb.WriteString(" }\n")
+	}
+	if q, ok := proxyutils.QuotaOf(u); ok {
+		sb.WriteString("    quota: { used: ");sb.WriteString(utils.FormatTraffic(q.Used()))
+		if q.Total > 0 {
+			sb.WriteString(", total: ");sb.WriteString(utils.FormatTraffic(q.Total))
+		}
+		if q.Expire > 0 {
+			sb.WriteString(", expire: ");sb.WriteString(time.Unix(q.Expire, 0).Format(time.DateOnly))
+		}
+		sb.WriteString(" }\n")
+	}
+}
+
+// writeDiagnostics 输出订阅的解析诊断摘要，完整内容见 sub-diagnostics.yaml
+func writeDiagnostics(sb *strings.Builder, u string) {
+	d, ok := proxyutils.DiagnosticsOf(u)
+	if !ok {
+		return
+	}
+	sb.WriteString("    diagnostics: { format: ");sb.WriteString(d.Format)
+	sb.WriteString(", entries: ");sb.WriteString(strconv.Itoa(d.Entries))
+	sb.WriteString(", converted: ");sb.WriteString(strconv.Itoa(d.Converted))
+	sb.WriteString(", accepted: ");sb.WriteString(strconv.Itoa(d.Accepted))
+	if len(d.Reasons) > 0 {
+		sb.WriteString(", reasons: { ");sb.WriteString(formatMapToInline(d.Reasons));sb.WriteString(" }")
+	}
+	if len(d.Rejected) > 0 {
+		sb.WriteString(", rejected: { ");sb.WriteString(formatMapToInline(d.Rejected));sb.WriteString(" }")
+	}
+	if len(d.Unsupported) > 0 {
+		sb.WriteString(", unsupported: { ");sb.WriteString(formatMapToInline(d.Unsupported));sb.WriteString(" }")
+	}
+	if d.Error != "" {
+		sb.WriteString(", error: ");sb.WriteString(strconv.Quote(d.Error))
+	}
+	sb.WriteString(" }\n")
+}
+
+// validationSummary 汇总各订阅节点校验的拒绝原因与修复数
+func validationSummary() (map[string]int, int) {
+	rejected := make(map[string]int)
+	repaired := 0
+	for _, d := range proxyutils.Diagnostics() {
+		for reason, n := range d.Rejected {
+			rejected[reason] += n
 		}
+		repaired += d.Repaired
 	}
+	return rejected, repaired
+}
 
-	_ = method.SaveToStats([]byte(sb.String()+sbBad.String()), "subs-analysis.yaml", "分析结果")
+// writeStabilityRanking 输出稳定性测试结果，结果已按评分排序
+func writeStabilityRanking(sb *strings.Builder, results []Result) {
+	var tested []Result
+	for _, r := range results {
+		if r.Stability != nil {
+			tested = append(tested, r)
+		}
+	}
+	if len(tested) == 0 {
+		return
+	}
+	sb.WriteString("\nstability_ranking:\n")
+	for _, r := range tested {
+		st := r.Stability
+		name, _ := r.Proxy["name"].(string)
+		sb.WriteString("  - name: ");sb.WriteString(strconv.Quote(name));sb.WriteString("\n")
+		sb.WriteString("    score: ");sb.WriteString(strconv.FormatFloat(st.Score, 'f', 1, 64));sb.WriteString("\n")
+		sb.WriteString("    speed: { avg_kbps: ");sb.WriteString(strconv.Itoa(st.AvgSpeed));sb.WriteString(", cv: ");sb.WriteString(strconv.FormatFloat(st.SpeedCV, 'f', 3, 64));sb.WriteString(" }\n")
+		sb.WriteString("    latency: { avg_ms: ");sb.WriteString(strconv.Itoa(st.AvgLatency));sb.WriteString(", jitter_ms: ");sb.WriteString(strconv.Itoa(st.Jitter));sb.WriteString(", loss: ");sb.WriteString(strconv.Itoa(st.LatencyLoss));sb.WriteString(" }\n")
+		sb.WriteString("    stalls: ");sb.WriteString(strconv.Itoa(st.Stalls));sb.WriteString("\n")
+		sb.WriteString("    disconnects: ");sb.WriteString(strconv.Itoa(st.Disconnects));sb.WriteString("\n")
+		sb.WriteString("    duration: ");sb.WriteString(prettyDuration(st.Duration));sb.WriteString("\n")
+	}
 }
 
 // generateSummary 生成单段落详细摘要
@@ -322,9 +518,12 @@ func generateSummary(s *AnalysisStats) string {
 	topAI := getTopFiltered(s.Media, []string{"GPT", "GPT+", "Gemini", "Copilot"}, 4)
 
 	var speedText string
-	if speedON {
+	switch {
+	case speedON && BudgetExceeded.Load():
+		speedText = "，" + BudgetNote()
+	case speedON:
 		speedText = "，设置速度下限 " + strconv.Itoa(config.GlobalConfig.MinSpeed) + " KB/s"
-	} else {
+	default:
 		speedText = "，未开启下载测速"
 	}
 
@@ -606,7 +805,22 @@ func (pc *ProxyChecker) CleanupMetadata() {
 	for _, result := range pc.results {
 		if result.Proxy != nil {
 			delete(result.Proxy, "sub_url")
+			delete(result.Proxy, "sub_providers")
 			delete(result.Proxy, "sub_tag")
+			delete(result.Proxy, "sub_name")
+			delete(result.Proxy, "sub_tags")
+			delete(result.Proxy, proxyutils.NameOriginKey)
+		}
+	}
+}
+
+// saveNameOrigins 保存检测结果的原始名称
+func (pc *ProxyChecker) saveNameOrigins() {
+	nodes := make([]map[string]any, 0, len(pc.results))
+	for _, result := range pc.results {
+		if result.Proxy != nil {
+			nodes = append(nodes, result.Proxy)
 		}
 	}
+	proxyutils.SaveNameOrigins(nodes)
 }
@@ -0,0 +1,172 @@
+package check
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"time"
+
+	"github.com/goccy/go-yaml"
+	"github.com/sinspired/subs-check-pro/v2/config"
+	"github.com/sinspired/subs-check-pro/v2/save/method"
+	"github.com/sinspired/subs-check-pro/v2/utils"
+)
+
+const dailyTrafficFile = "traffic-daily.yaml"
+
+// BudgetExceeded 本轮检测是否已触达流量预算（触达后跳过测速）
+var BudgetExceeded atomic.Bool
+
+var (
+	// liveBytes 本轮检测实时流量，由 countingConn 在连接层累加
+	liveBytes atomic.Uint64
+	// budgetReason 触达的预算类型："run" 单次 / "day" 每日
+	budgetReason atomic.Value
+	// budgetLimit 本轮可用的流量预算（字节），0 为不限
+	budgetLimit uint64
+	// dailyUsedBefore 本轮开始前当日已消耗流量（字节）
+	dailyUsedBefore uint64
+	// budgetCtx 触达预算时取消，用于中断进行中的测速下载
+	budgetCtx, budgetCancel = context.WithCancel(context.Background())
+)
+
+// dailyTraffic 每日流量累计记录
+type dailyTraffic struct {
+	Date  string `yaml:"date"`
+	Bytes uint64 `yaml:"bytes"`
+}
+
+// initBudget 在检测开始前计算本轮流量预算
+func initBudget() {
+	liveBytes.Store(0)
+	BudgetExceeded.Store(false)
+	budgetReason.Store("")
+	budgetLimit = 0
+	dailyUsedBefore = 0
+	budgetCancel()
+	budgetCtx, budgetCancel = context.WithCancel(context.Background())
+
+	const mb = 1024 * 1024
+	if n := config.GlobalConfig.MaxTrafficPerRun; n > 0 {
+		budgetLimit = uint64(n) * mb
+		budgetReason.Store("run")
+	}
+
+	if n := config.GlobalConfig.MaxTrafficPerDay; n > 0 {
+		dailyUsedBefore = loadDailyTraffic().Bytes
+		dayLimit := uint64(n) * mb
+		remain := uint64(0)
+		if dayLimit > dailyUsedBefore {
+			remain = dayLimit - dailyUsedBefore
+		}
+		if budgetLimit == 0 || remain < budgetLimit {
+			budgetLimit = remain
+			budgetReason.Store("day")
+		}
+		// 当日预算已用尽：直接跳过测速
+		if remain == 0 {
+			markBudgetExceeded()
+		}
+	}
+}
+
+// budgetEnabled 是否配置了任意流量预算
+func budgetEnabled() bool {
+	return config.GlobalConfig.MaxTrafficPerRun > 0 || config.GlobalConfig.MaxTrafficPerDay > 0
+}
+
+// watchBudget 周期性检查实时流量，触达预算后标记并停止测速
+func watchBudget(ctx context.Context) {
+	if !budgetEnabled() || BudgetExceeded.Load() {
+		return
+	}
+	ticker := time.NewTicker(200 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if liveBytes.Load() >= budgetLimit {
+				markBudgetExceeded()
+				return
+			}
+		}
+	}
+}
+
+// addLiveBytes 累加实时流量，超出预算时立即标记，不必等待 watchBudget 下一次轮询
+func addLiveBytes(n uint64) {
+	if liveBytes.Add(n) >= budgetLimit && budgetLimit > 0 {
+		markBudgetExceeded()
+	}
+}
+
+// markBudgetExceeded 标记触达流量预算并中断进行中的测速，仅首次输出日志
+func markBudgetExceeded() {
+	if !BudgetExceeded.CompareAndSwap(false, true) {
+		return
+	}
+	budgetCancel()
+	slog.Warn("已达到流量预算, 停止测速, 剩余节点仅进行测活和媒体检测",
+		"预算", budgetName(), "已用", utils.FormatTraffic(liveBytes.Load()))
+}
+
+// budgetName 返回触达预算类型的可读名称
+func budgetName() string {
+	if reason, _ := budgetReason.Load().(string); reason == "day" {
+		return "每日 " + utils.FormatTraffic(uint64(config.GlobalConfig.MaxTrafficPerDay)*1024*1024)
+	}
+	return "单次 " + utils.FormatTraffic(uint64(config.GlobalConfig.MaxTrafficPerRun)*1024*1024)
+}
+
+// BudgetNote 返回预算触达说明，供通知使用；未触达时返回空字符串
+func BudgetNote() string {
+	if !BudgetExceeded.Load() {
+		return ""
+	}
+	return "已达流量预算(" + budgetName() + ")，已停止测速"
+}
+
+// dailyTrafficPath 返回每日流量记录文件路径
+func dailyTrafficPath() string {
+	saver, err := method.NewStatsSaver()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(saver.StatsPath, dailyTrafficFile)
+}
+
+// loadDailyTraffic 读取当日流量记录，日期不符时视为 0
+func loadDailyTraffic() dailyTraffic {
+	today := dailyTraffic{Date: time.Now().Format(time.DateOnly)}
+	path := dailyTrafficPath()
+	if path == "" {
+		return today
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return today
+	}
+	var rec dailyTraffic
+	if err := yaml.Unmarshal(data, &rec); err != nil || rec.Date != today.Date {
+		return today
+	}
+	return rec
+}
+
+// saveDailyTraffic 将本轮消耗累加到当日流量记录
+func saveDailyTraffic(used uint64) {
+	if config.GlobalConfig.MaxTrafficPerDay <= 0 {
+		return
+	}
+	rec := loadDailyTraffic()
+	rec.Bytes += used
+	data, err := yaml.Marshal(rec)
+	if err != nil {
+		return
+	}
+	_ = method.SaveToStats(data, dailyTrafficFile, "每日流量统计")
+}
@@ -0,0 +1,30 @@
+package check
+
+import (
+	"testing"
+
+	"github.com/sinspired/subs-check-pro/v2/config"
+)
+
+func TestBudgetCancelsSpeedTests(t *testing.T) {
+	oldCfg := config.GlobalConfig
+	config.GlobalConfig = &config.Config{MaxTrafficPerRun: 1}
+	defer func() { config.GlobalConfig = oldCfg }()
+
+	initBudget()
+	addLiveBytes(512 * 1024)
+	if BudgetExceeded.Load() || budgetCtx.Err() != nil {
+		t.Fatal("budget should not be exceeded yet")
+	}
+	// 进行中的测速在触达预算的同一次读取时即被中断
+	addLiveBytes(512 * 1024)
+	if !BudgetExceeded.Load() || budgetCtx.Err() == nil {
+		t.Fatal("reaching the budget should cancel in-flight speed tests")
+	}
+
+	// 新一轮检测重新开始计算
+	initBudget()
+	if BudgetExceeded.Load() || budgetCtx.Err() != nil {
+		t.Error("budget context should be reset for a new run")
+	}
+}
@@ -9,6 +9,7 @@ import (
 	"log/slog"
 	"net"
 	"net/http"
+	"net/url"
 	"regexp"
 	"runtime/debug"
 	"strconv"
@@ -84,6 +85,13 @@ type Result struct {
 	Country        string
 	CountryCodeTag string
 	ISPTag         string
+	City           string           // 城市，需开启 geo-city
+	ASN            uint             // 自治系统号，需开启 geo-asn
+	ASOrg          string           // 自治系统所属组织
+	Latency        int              // 测活延迟 ms
+	Speed          int              // 测速结果 KB/s，未测速为 0
+	Stability      *StabilityResult // 稳定性测试结果，未测试为 nil
+	Score          float64          // 综合评分 0-100，未启用评分为 0
 }
 
 // ProxyChecker 处理代理检测的主要结构体
@@ -103,6 +111,9 @@ type ProxyChecker struct {
 	mediaChan chan *ProxyJob
 
 	pt *ProgressTracker
+
+	cityDB *maxminddb.Reader // 城市库，未启用为 nil
+	asnDB  *maxminddb.Reader // ASN 库，未启用为 nil
 }
 
 // ProxyJob 在测活-测速-流媒体检测任务间传输信息
@@ -223,9 +234,15 @@ func NewProxyChecker(proxyCount int) *ProxyChecker {
 // Check 执行代理检测的主函数
 func Check() ([]Result, error) {
 	proxyutils.ResetRenameCounter()
+	if tpl := config.GlobalConfig.NameTemplate; tpl != "" {
+		if _, err := proxyutils.CompiledNameTemplate(tpl); err != nil {
+			slog.Warn("名称模板无效，使用默认命名", "name-template", tpl, "error", err)
+		}
+	}
 	ForceClose.Store(false)
 	Successlimited.Store(false)
 	ProcessResults.Store(false)
+	Paused.Store(false)
 
 	ProxyCount.Store(0)
 	Available.Store(0)
@@ -236,6 +253,15 @@ func Check() ([]Result, error) {
 	// 重置预计剩余时间计算
 	ResetETA()
 
+	// 计算本轮流量预算
+	initBudget()
+
+	// 加载节点历史可用率
+	initUptime()
+
+	// 重置媒体检测按主机限流状态
+	platform.ResetHostLimiter()
+
 	// 初始化测速和流媒体检测开关
 	speedON = config.GlobalConfig.SpeedTestURL != ""
 	mediaON = config.GlobalConfig.MediaCheck
@@ -287,11 +313,41 @@ func Check() ([]Result, error) {
 			ScanLimit:  config.GlobalConfig.Concurrent * 2,     // 冲突向前扫描的最大距离
 		}
 
+		// 域名解析与 ASN 间距约束（可选）
+		shuffleCfg := config.GlobalConfig.SmartShuffle
+		cfg.ResolveDomain = shuffleCfg.ResolveDomain
+		cfg.ResolveConcurrent = shuffleCfg.ResolveConcurrent
+		if shuffleCfg.ASN {
+			asnDB, err := assets.OpenMaxMindEdition(assets.GeoLite2ASN, config.GlobalConfig.MaxMindASNDB)
+			if err != nil {
+				slog.Warn("打开 ASN 数据库失败，跳过 ASN 间距约束", "error", err)
+			} else {
+				cfg.ASNDB = asnDB
+				cfg.ASNMinSpacing = shuffleCfg.ASNMinSpacing
+				if cfg.ASNMinSpacing <= 0 {
+					cfg.ASNMinSpacing = config.GlobalConfig.Concurrent
+				}
+			}
+		}
+
 		tail := proxies[headSize:]
-		proxyutils.SmartShuffleByServer(tail, cfg)
+		stats := proxyutils.SmartShuffleByServer(tail, cfg)
+		if cfg.ASNDB != nil {
+			_ = cfg.ASNDB.Close()
+		}
 
 		cidr := proxyutils.ThresholdToCIDR(cfg.Threshold)
 		slog.Info(fmt.Sprintf("节点乱序, 相同 CIDR%s 最小间距: %d", cidr, cfg.MinSpacing))
+		if cfg.ResolveDomain {
+			slog.Info("乱序域名解析", "域名", stats.Domains, "成功", stats.Resolved)
+		}
+		args := []any{"CIDR" + cidr + " 实际最小间距", stats.Min24Gap, "未达标", stats.Spacing24Misses}
+		if cfg.ASNDB != nil {
+			args = append(args,
+				"ASN 目标间距", cfg.ASNMinSpacing, "实际最小间距", stats.MinASNGap, "未达标", stats.SpacingASNMisses,
+				"ASN 数量", stats.ASNCount, "最大 ASN 节点数", stats.TopASNSize, "已识别节点", stats.WithASN)
+		}
+		slog.Info("乱序间距统计", args...)
 	}
 
 	CurrentStepName.Store("获取订阅完成")
@@ -304,6 +360,14 @@ func Check() ([]Result, error) {
 	Fetching.Store(false)
 	Checking.Store(true)
 
+	// 直连预检，剔除端口不可达的节点
+	proxies = preDialFilter(proxies)
+	if len(proxies) == 0 {
+		slog.Info("预检后没有需要检测的节点")
+		Checking.Store(false)
+		return nil, nil
+	}
+
 	checker := NewProxyChecker(len(proxies))
 
 	results, err := checker.run(proxies)
@@ -343,6 +407,22 @@ func (pc *ProxyChecker) run(proxies []map[string]any) ([]Result, error) {
 		}()
 	}
 
+	// 城市库与 ASN 库（可选），打开失败不影响检测
+	if config.GlobalConfig.GeoCity {
+		if pc.cityDB, err = assets.OpenMaxMindEdition(assets.GeoLite2City, config.GlobalConfig.MaxMindCityDB); err != nil {
+			slog.Warn("打开城市数据库失败，跳过城市查询", "error", err)
+		} else {
+			defer pc.cityDB.Close()
+		}
+	}
+	if config.GlobalConfig.GeoASN {
+		if pc.asnDB, err = assets.OpenMaxMindEdition(assets.GeoLite2ASN, config.GlobalConfig.MaxMindASNDB); err != nil {
+			slog.Warn("打开 ASN 数据库失败，跳过 ASN 查询", "error", err)
+		} else {
+			defer pc.asnDB.Close()
+		}
+	}
+
 	slog.Info("开始检测节点")
 
 	// 记录开始检测时间
@@ -385,6 +465,12 @@ func (pc *ProxyChecker) run(proxies []map[string]any) ([]Result, error) {
 	if config.GlobalConfig.TotalSpeedLimit > 0 && speedON {
 		args = append(args, "total-speed-limit", config.GlobalConfig.TotalSpeedLimit)
 	}
+	if config.GlobalConfig.MaxTrafficPerRun > 0 {
+		args = append(args, "max-traffic-per-run", config.GlobalConfig.MaxTrafficPerRun)
+	}
+	if config.GlobalConfig.MaxTrafficPerDay > 0 {
+		args = append(args, "max-traffic-per-day", config.GlobalConfig.MaxTrafficPerDay)
+	}
 
 	// 再追加剩余参数
 	args = append(args,
@@ -433,6 +519,7 @@ func (pc *ProxyChecker) run(proxies []map[string]any) ([]Result, error) {
 			case <-ticker.C:
 				if ForceClose.Load() {
 					slog.Warn("用户手动结束检测,等待收集结果")
+					Paused.Store(false)
 					cancel()
 					return
 				}
@@ -440,6 +527,9 @@ func (pc *ProxyChecker) run(proxies []map[string]any) ([]Result, error) {
 		}
 	}()
 
+	// 监测流量预算
+	go watchBudget(ctx)
+
 	// // 进度显示 —— 使用关闭信号并等待 showProgress 完成
 	// doneCh := make(chan struct{})
 	// finishedCh := make(chan struct{})
@@ -461,6 +551,10 @@ func (pc *ProxyChecker) run(proxies []map[string]any) ([]Result, error) {
 				UpdateETA()
 				return
 			case <-ticker.C:
+				// 暂停期间冻结 ETA，避免速率被拉低
+				if Paused.Load() {
+					continue
+				}
 				SnapshotRate()
 				UpdateETA()
 			}
@@ -477,8 +571,14 @@ func (pc *ProxyChecker) run(proxies []map[string]any) ([]Result, error) {
 	go pc.runAliveStage(ctx, geoDB)
 	go pc.runSpeedStage(ctx, cancel)
 	pc.runMediaStageAndCollect(geoDB, ctx, cancel)
+
+	// 头部节点长时稳定性测试（可选）
+	pc.runStabilityStage()
 	CurrentStepName.Store("处理结果")
 
+	// 综合评分并按评分排序输出
+	pc.scoreResults()
+
 	// 确保进度显示到 100%
 	pc.pt.Finalize()
 
@@ -495,6 +595,7 @@ func (pc *ProxyChecker) run(proxies []map[string]any) ([]Result, error) {
 	slog.Info(fmt.Sprintf("可用节点数量: %d", len(pc.results)))
 	CheckTrafficTotal = utils.FormatTraffic(TotalBytes.Load())
 	slog.Info(fmt.Sprintf("检测消耗流量: %s", CheckTrafficTotal))
+	saveDailyTraffic(TotalBytes.Load())
 	slog.Debug("流量", "UP", UP.Load(), "DOWN", DOWN.Load())
 
 	// 计算检测用时
@@ -507,7 +608,12 @@ func (pc *ProxyChecker) run(proxies []map[string]any) ([]Result, error) {
 	// 1. 深度分析 (利用上一步的成功率进行排序，生成 analysis yaml)
 	pc.GenerateAnalysisReport()
 
-	// 2. 清理元数据 (删除 sub_url 等字段，防止污染最终配置)
+	// 2. 记录名称模板渲染前的原始名称，下轮读回保留的节点时还原
+	if config.GlobalConfig.NameTemplate != "" {
+		pc.saveNameOrigins()
+	}
+
+	// 3. 清理元数据 (删除 sub_url 等字段，防止污染最终配置)
 	pc.CleanupMetadata()
 
 	// 手动解除引用
@@ -524,6 +630,7 @@ func (pc *ProxyChecker) run(proxies []map[string]any) ([]Result, error) {
 
 	// 标记结束
 	Checking.Store(false)
+	Paused.Store(false)
 
 	return pc.results, nil
 }
@@ -549,6 +656,9 @@ func (pc *ProxyChecker) distributeJobs(proxies []map[string]any, ctx context.Con
 	for range concurrency {
 		wg.Go(func() {
 			for {
+				// 暂停时停止分发新任务
+				waitIfPaused(ctx)
+
 				// 原子地获取下一个代理索引
 				index := proxyIndex.Add(1)
 				if index >= int64(len(proxies)) {
@@ -575,6 +685,8 @@ func (pc *ProxyChecker) distributeJobs(proxies []map[string]any, ctx context.Con
 					}(index)
 				}
 
+				markUptimeSeen(mapping)
+
 				cli := CreateClient(mapping)
 				if cli == nil {
 					// 创建失败：视为 alive 完成（失败），不进入 speed/media
@@ -623,6 +735,7 @@ func (pc *ProxyChecker) runAliveStage(ctx context.Context, db *maxminddb.Reader)
 	for range concurrency {
 		wg.Go(func() {
 			for job := range pc.aliveChan {
+				waitIfPaused(ctx)
 				if checkCtxDone(ctx) {
 					if job.aliveMarked.CompareAndSwap(false, true) {
 						pc.pt.CountAlive(false)
@@ -710,6 +823,7 @@ func (pc *ProxyChecker) runSpeedStage(ctx context.Context, cancel context.Cancel
 	for range concurrency {
 		wg.Go(func() {
 			for job := range pc.speedChan {
+				waitIfPaused(ctx)
 				if checkCtxDone(ctx) {
 					if job.speedMarked.CompareAndSwap(false, true) {
 						pc.pt.CountSpeed(false)
@@ -717,9 +831,18 @@ func (pc *ProxyChecker) runSpeedStage(ctx context.Context, cancel context.Cancel
 					job.Close()
 					continue
 				}
-				getBytes := func() uint64 { return job.Client.BytesRead.Load() }
-				speed, _, err := platform.CheckSpeed(job.Client.Client, Bucket, getBytes)
-				success := err == nil && speed >= config.GlobalConfig.MinSpeed
+				// 触达流量预算后跳过测速，节点直接流转至媒体检测
+				speed, success := 0, true
+				if !BudgetExceeded.Load() {
+					getBytes := func() uint64 { return job.Client.BytesRead.Load() }
+					var err error
+					speed, _, err = platform.CheckSpeed(budgetCtx, job.Client.Client, Bucket, getBytes)
+					success = err == nil && speed >= config.GlobalConfig.MinSpeed
+					// 测速途中触达预算被中断：结果不完整，按跳过测速处理
+					if budgetCtx.Err() != nil {
+						speed, success = 0, true
+					}
+				}
 				if job.speedMarked.CompareAndSwap(false, true) {
 					pc.pt.CountSpeed(success)
 					// 仅在测速成功时计入可用数量
@@ -798,6 +921,7 @@ func (pc *ProxyChecker) runMediaStageAndCollect(db *maxminddb.Reader, ctx contex
 	for range concurrency {
 		wg.Go(func() {
 			for job := range pc.mediaChan {
+				waitIfPaused(ctx)
 				if !speedON {
 					// 只在没开启测速时接受媒体检测停止信号
 					// 丢弃结果
@@ -836,6 +960,7 @@ func (pc *ProxyChecker) runMediaStageAndCollect(db *maxminddb.Reader, ctx contex
 				pc.updateProxyName(&job.Result, job.Client, job.Speed, db, job.CfLoc, job.CfIP, ctx)
 
 				// 将结果发送到 collector
+				job.Result.Speed = job.Speed
 				pc.resultChan <- job.Result
 
 				if job.mediaMarked.CompareAndSwap(false, true) {
@@ -866,8 +991,10 @@ func (pc *ProxyChecker) collectResults() {
 
 // checkAlive 使用谷歌服务执行基本的存活检测。
 func checkAlive(job *ProxyJob, ctx context.Context) bool {
+	start := time.Now()
 	gstatic, err := platform.CheckGstatic(job.Client.Client, ctx)
 	if err == nil && gstatic {
+		job.Result.Latency = int(time.Since(start).Milliseconds())
 		return true
 	}
 	slog.Debug("测活出错", "Name", job.Client.mProxy.Name(), "error", err)
@@ -892,7 +1019,7 @@ func mediaCheck(job *ProxyJob, db *maxminddb.Reader, ctx context.Context) {
 	}
 
 	mediaClient := &http.Client{
-		Transport: job.Client.Client.Transport,
+		Transport: platform.WrapHostLimit(job.Client.Client.Transport),
 		Timeout:   time.Duration(mediaTimeout) * time.Second,
 	}
 
@@ -1082,13 +1209,25 @@ func checkOnePlatform(job *ProxyJob, plat string, mediaClient *http.Client, db *
 
 // updateProxyName 更新代理名称
 func (pc *ProxyChecker) updateProxyName(res *Result, httpClient *ProxyClient, speed int, db *maxminddb.Reader, cfLoc string, cfIP string, jctx context.Context) {
+	// 城市与 ASN 依赖出口 IP
+	if pc.cityDB != nil || pc.asnDB != nil {
+		if res.IP == "" {
+			fillLocation(res, httpClient.Client, db, jctx, cfLoc, cfIP)
+		}
+		pc.fillGeoDetail(res)
+	}
+
+	if src := config.GlobalConfig.NameTemplate; src != "" {
+		if tpl, err := proxyutils.CompiledNameTemplate(src); err == nil {
+			renderProxyName(tpl, res, httpClient, speed, db, cfLoc, cfIP, jctx)
+			return
+		}
+	}
+
 	// 以节点IP查询位置重命名（如果开启）
 	if config.GlobalConfig.RenameNode {
 		if res.Country == "" {
-			country, _, countryCodeTag, ispTag, _ := proxyutils.GetProxyCountry(httpClient.Client, db, jctx, cfLoc, cfIP)
-			res.Country = country
-			res.CountryCodeTag = countryCodeTag
-			res.ISPTag = ispTag
+			fillLocation(res, httpClient.Client, db, jctx, cfLoc, cfIP)
 		}
 		if res.Country != "" {
 			res.Proxy["name"] = config.GlobalConfig.NodePrefix + proxyutils.Rename(res.Country, res.CountryCodeTag)
@@ -1107,14 +1246,7 @@ func (pc *ProxyChecker) updateProxyName(res *Result, httpClient *ProxyClient, sp
 	// 速度标签
 	if config.GlobalConfig.SpeedTestURL != "" && speed > 0 {
 		name = regexp.MustCompile(`\s*\|(?:\s*[\d.]+[KM]B/s)`).ReplaceAllString(name, "")
-		var speedStr string
-		if speed < 100 {
-			speedStr = strconv.Itoa(speed) + "KB/s"
-		} else {
-			speedStr = strconv.FormatFloat(float64(speed)/1024, 'f', 1, 64) + "MB/s"
-		}
-
-		tags = append(tags, speedStr)
+		tags = append(tags, formatSpeed(speed))
 	}
 
 	if config.GlobalConfig.MediaCheck {
@@ -1125,6 +1257,104 @@ func (pc *ProxyChecker) updateProxyName(res *Result, httpClient *ProxyClient, sp
 	}
 
 	// 平台标签（按用户配置顺序）
+	tags = append(tags, platformTags(res, name)...)
+
+	if tag, ok := res.Proxy["sub_tag"].(string); ok && tag != "" {
+		tags = append(tags, tag)
+	}
+
+	// 运营商标签
+	if config.GlobalConfig.ISPCheck {
+		if res.ISPTag != "" {
+			tags = append(tags, res.ISPTag)
+		}
+	}
+
+	// 将所有标记添加到名称中
+	if len(tags) > 0 {
+		name += "|" + strings.Join(tags, "|")
+	}
+
+	res.Proxy["name"] = name
+}
+
+// renderProxyName 按 name-template 渲染节点名称。
+// {name} 取自渲染前的原始名称，重复检测不会叠加标签。
+func renderProxyName(tpl *proxyutils.NameTemplate, res *Result, httpClient *ProxyClient, speed int, db *maxminddb.Reader, cfLoc string, cfIP string, jctx context.Context) {
+	if res.Country == "" && tpl.Uses("flag", "country", "isp") {
+		fillLocation(res, httpClient.Client, db, jctx, cfLoc, cfIP)
+	}
+
+	origin := proxyutils.SourceName(res.Proxy)
+
+	label := proxyutils.CountryLabel(res.Country, res.CountryCodeTag)
+	vars := map[string]string{
+		"country": label,
+		"city":    res.City,
+		"isp":     res.ISPTag,
+		"tags":    strings.Join(platformTags(res, label), "|"),
+		"name":    origin,
+	}
+	if res.Country != "" {
+		vars["flag"] = proxyutils.CountryCodeToFlag(res.Country)
+	}
+	if speed > 0 {
+		vars["speed"] = formatSpeed(speed)
+	}
+	if res.Latency > 0 {
+		vars["latency"] = strconv.Itoa(res.Latency) + "ms"
+	}
+	if res.ASN > 0 {
+		vars["asn"] = "AS" + strconv.FormatUint(uint64(res.ASN), 10)
+		vars["asorg"] = res.ASOrg
+	}
+	if tags, ok := res.Proxy["sub_tags"].(string); ok {
+		vars["subtags"] = tags
+	}
+	if name, ok := res.Proxy["sub_name"].(string); ok && name != "" {
+		vars["sub"] = name
+	} else if tag, ok := res.Proxy["sub_tag"].(string); ok && tag != "" {
+		vars["sub"] = tag
+	} else if su, ok := res.Proxy["sub_url"].(string); ok {
+		if u, err := url.Parse(su); err == nil {
+			vars["sub"] = u.Hostname()
+		}
+	}
+
+	res.Proxy["name"] = tpl.Render(vars)
+}
+
+// fillLocation 查询节点出口位置并写入结果
+func fillLocation(res *Result, client *http.Client, db *maxminddb.Reader, ctx context.Context, cfLoc string, cfIP string) {
+	country, ip, countryCodeTag, ispTag, _ := proxyutils.GetProxyCountry(client, db, ctx, cfLoc, cfIP)
+	res.Country = country
+	res.CountryCodeTag = countryCodeTag
+	res.ISPTag = ispTag
+	if res.IP == "" {
+		res.IP = ip
+	}
+}
+
+// fillGeoDetail 根据出口 IP 补充城市与 ASN
+func (pc *ProxyChecker) fillGeoDetail(res *Result) {
+	if res.IP == "" {
+		return
+	}
+	d := proxyutils.LookupGeoDetail(pc.cityDB, pc.asnDB, res.IP)
+	res.City, res.ASN, res.ASOrg = d.City, d.ASN, d.ASOrg
+}
+
+// formatSpeed 格式化测速结果
+func formatSpeed(speed int) string {
+	if speed < 100 {
+		return strconv.Itoa(speed) + "KB/s"
+	}
+	return strconv.FormatFloat(float64(speed)/1024, 'f', 1, 64) + "MB/s"
+}
+
+// platformTags 按用户配置的平台顺序生成解锁标签
+func platformTags(res *Result, name string) []string {
+	var tags []string
 	for _, plat := range config.GlobalConfig.Platforms {
 		switch plat {
 		case "openai":
@@ -1206,23 +1436,7 @@ func (pc *ProxyChecker) updateProxyName(res *Result, httpClient *ProxyClient, sp
 		}
 	}
 
-	if tag, ok := res.Proxy["sub_tag"].(string); ok && tag != "" {
-		tags = append(tags, tag)
-	}
-
-	// 运营商标签
-	if config.GlobalConfig.ISPCheck {
-		if res.ISPTag != "" {
-			tags = append(tags, res.ISPTag)
-		}
-	}
-
-	// 将所有标记添加到名称中
-	if len(tags) > 0 {
-		name += "|" + strings.Join(tags, "|")
-	}
-
-	res.Proxy["name"] = name
+	return tags
 }
 
 type ProxyClient struct {
@@ -1369,6 +1583,7 @@ func (c *countingConn) Read(b []byte) (int, error) {
 	n, err := c.Conn.Read(b)
 	if n > 0 {
 		c.readCounter.Add(uint64(n))
+		addLiveBytes(uint64(n))
 		// 在连接层消耗 token
 		if Bucket != nil && c.networkLimit {
 			Bucket.Wait(int64(n))
@@ -1381,6 +1596,7 @@ func (c *countingConn) Write(b []byte) (int, error) {
 	n, err := c.Conn.Write(b)
 	if n > 0 {
 		c.writeCounter.Add(uint64(n))
+		addLiveBytes(uint64(n))
 	}
 	return n, err
 }
@@ -1463,7 +1679,7 @@ func (job *ProxyJob) checkJobLocation(db *maxminddb.Reader, ctx context.Context)
 		locTimeout = 10
 	}
 	locClient := &http.Client{
-		Transport: job.Client.Transport,
+		Transport: platform.WrapHostLimit(job.Client.Transport),
 		Timeout:   time.Duration(locTimeout) * time.Second,
 	}
 
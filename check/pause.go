@@ -0,0 +1,54 @@
+package check
+
+import (
+	"context"
+	"log/slog"
+	"sync/atomic"
+	"time"
+)
+
+// Paused 检测暂停标志：暂停期间不再分发新任务，各阶段 worker 在取下一个任务前等待
+var Paused atomic.Bool
+
+// Pause 暂停当前检测，已在进行中的单个节点检测会继续完成。
+// 未处于检测阶段或已暂停时返回 false。
+func Pause() bool {
+	if !Checking.Load() || ForceClose.Load() {
+		return false
+	}
+	if !Paused.CompareAndSwap(false, true) {
+		return false
+	}
+	slog.Warn("检测已暂停")
+	return true
+}
+
+// Resume 恢复已暂停的检测，未暂停时返回 false。
+func Resume() bool {
+	if !Paused.CompareAndSwap(true, false) {
+		return false
+	}
+	// 暂停期间的进度快照会拉低实时速率，恢复后重新采样
+	ResetETA()
+	slog.Info("检测已恢复")
+	return true
+}
+
+// waitIfPaused 在暂停期间阻塞，直到恢复、上下文结束或收到强制关闭信号。
+func waitIfPaused(ctx context.Context) {
+	if !Paused.Load() {
+		return
+	}
+	ticker := time.NewTicker(200 * time.Millisecond)
+	defer ticker.Stop()
+	for Paused.Load() {
+		if ForceClose.Load() {
+			return
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,206 @@
+package platform
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/juju/ratelimit"
+	"github.com/sinspired/subs-check-pro/v2/config"
+	"github.com/sinspired/subs-check-pro/v2/utils"
+)
+
+var (
+	// ThrottledProbes 因令牌不足、并发已满或 429 冷却而等待的探测次数
+	ThrottledProbes atomic.Uint64
+	// RateLimitedProbes 目标主机返回 429 的次数
+	RateLimitedProbes atomic.Uint64
+
+	hostStates sync.Map // host -> *hostState
+)
+
+// hostState 单个目标主机的限流状态
+type hostState struct {
+	bucket *ratelimit.Bucket
+	sem    chan struct{}
+
+	mu            sync.Mutex
+	cooldownUntil time.Time
+}
+
+// hostLimitTransport 在 RoundTrip 前按目标主机限流，并遵循 429 Retry-After
+type hostLimitTransport struct {
+	base http.RoundTripper
+}
+
+// ResetHostLimiter 清空主机限流状态与计数，新一轮检测开始前调用
+func ResetHostLimiter() {
+	hostStates.Clear()
+	ThrottledProbes.Store(0)
+	RateLimitedProbes.Store(0)
+}
+
+// WrapHostLimit 为媒体检测客户端的 Transport 加上按主机限流，未启用时原样返回
+func WrapHostLimit(base http.RoundTripper) http.RoundTripper {
+	if !config.GlobalConfig.PlatformRateLimit.Enable || base == nil {
+		return base
+	}
+	return &hostLimitTransport{base: base}
+}
+
+func (t *hostLimitTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	st := getHostState(strings.ToLower(req.URL.Hostname()))
+	ctx := req.Context()
+
+	throttled, err := st.acquire(ctx)
+	if throttled {
+		ThrottledProbes.Add(1)
+	}
+	if err != nil {
+		return nil, err
+	}
+	resp, err := t.base.RoundTrip(req)
+	st.release()
+	if err != nil || resp.StatusCode != http.StatusTooManyRequests {
+		return resp, err
+	}
+
+	RateLimitedProbes.Add(1)
+	wait := parseRetryAfter(resp.Header.Get("Retry-After"))
+	st.setCooldown(wait)
+
+	// 仅在等待时间可接受且请求可重放时重试一次
+	maxWait := time.Duration(config.GlobalConfig.PlatformRateLimit.MaxRetryAfter) * time.Second
+	if wait <= 0 || wait > maxWait || (req.Body != nil && req.GetBody == nil) {
+		return resp, nil
+	}
+	retry := req.Clone(ctx)
+	if req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return resp, nil
+		}
+		retry.Body = body
+	}
+	resp.Body.Close()
+
+	ThrottledProbes.Add(1)
+	if _, err := st.acquire(ctx); err != nil {
+		return nil, err
+	}
+	defer st.release()
+	return t.base.RoundTrip(retry)
+}
+
+// acquire 依次等待冷却期、令牌与并发槽位，返回是否发生了等待
+func (st *hostState) acquire(ctx context.Context) (bool, error) {
+	throttled := false
+
+	st.mu.Lock()
+	wait := time.Until(st.cooldownUntil)
+	st.mu.Unlock()
+	if wait > 0 {
+		throttled = true
+		if err := utils.SleepCtx(ctx, wait); err != nil {
+			return throttled, err
+		}
+	}
+
+	if st.bucket != nil {
+		if d := st.bucket.Take(1); d > 0 {
+			throttled = true
+			if err := utils.SleepCtx(ctx, d); err != nil {
+				return throttled, err
+			}
+		}
+	}
+
+	if st.sem != nil {
+		select {
+		case st.sem <- struct{}{}:
+		default:
+			throttled = true
+			select {
+			case st.sem <- struct{}{}:
+			case <-ctx.Done():
+				return throttled, ctx.Err()
+			}
+		}
+	}
+	return throttled, nil
+}
+
+// release 归还并发槽位
+func (st *hostState) release() {
+	if st.sem != nil {
+		<-st.sem
+	}
+}
+
+// setCooldown 延长主机冷却期，未提供 Retry-After 时默认冷却 1 秒
+func (st *hostState) setCooldown(wait time.Duration) {
+	if wait <= 0 {
+		wait = time.Second
+	}
+	until := time.Now().Add(wait)
+	st.mu.Lock()
+	if until.After(st.cooldownUntil) {
+		st.cooldownUntil = until
+	}
+	st.mu.Unlock()
+}
+
+// getHostState 获取或创建主机限流状态
+func getHostState(host string) *hostState {
+	if v, ok := hostStates.Load(host); ok {
+		return v.(*hostState)
+	}
+	lim := hostLimitFor(host)
+	st := &hostState{}
+	if lim.Rate > 0 {
+		burst := int64(lim.Burst)
+		if burst <= 0 {
+			burst = max(1, int64(lim.Rate))
+		}
+		st.bucket = ratelimit.NewBucketWithRate(lim.Rate, burst)
+	}
+	if lim.Concurrent > 0 {
+		st.sem = make(chan struct{}, lim.Concurrent)
+	}
+	v, _ := hostStates.LoadOrStore(host, st)
+	return v.(*hostState)
+}
+
+// hostLimitFor 返回主机适用的限流参数，优先匹配最长的域名后缀
+func hostLimitFor(host string) config.HostRateLimit {
+	cfg := config.GlobalConfig.PlatformRateLimit
+	lim := cfg.HostRateLimit
+	matched := ""
+	for domain, l := range cfg.Hosts {
+		domain = strings.ToLower(strings.TrimPrefix(domain, "."))
+		if (host == domain || strings.HasSuffix(host, "."+domain)) && len(domain) > len(matched) {
+			matched = domain
+			lim = l
+		}
+	}
+	return lim
+}
+
+// parseRetryAfter 解析 Retry-After（秒数或 HTTP 日期）
+func parseRetryAfter(v string) time.Duration {
+	v = strings.TrimSpace(v)
+	if v == "" {
+		return 0
+	}
+	if sec, err := strconv.Atoi(v); err == nil {
+		return time.Duration(max(sec, 0)) * time.Second
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		return max(time.Until(t), 0)
+	}
+	return 0
+}
@@ -0,0 +1,137 @@
+package platform
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/sinspired/subs-check-pro/v2/config"
+)
+
+func withRateLimitConfig(t *testing.T, cfg config.PlatformRateLimitConfig) {
+	t.Helper()
+	old := config.GlobalConfig
+	config.GlobalConfig = &config.Config{PlatformRateLimit: cfg}
+	ResetHostLimiter()
+	t.Cleanup(func() {
+		config.GlobalConfig = old
+		ResetHostLimiter()
+	})
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	if got := parseRetryAfter("3"); got != 3*time.Second {
+		t.Errorf("seconds: got %v", got)
+	}
+	if got := parseRetryAfter(""); got != 0 {
+		t.Errorf("empty: got %v", got)
+	}
+	future := time.Now().Add(5 * time.Second).UTC().Format(http.TimeFormat)
+	if got := parseRetryAfter(future); got <= 0 || got > 5*time.Second {
+		t.Errorf("http-date: got %v", got)
+	}
+}
+
+func TestHostLimitForSuffix(t *testing.T) {
+	withRateLimitConfig(t, config.PlatformRateLimitConfig{
+		Enable:        true,
+		HostRateLimit: config.HostRateLimit{Rate: 10},
+		Hosts: map[string]config.HostRateLimit{
+			"openai.com":     {Rate: 2},
+			"api.openai.com": {Rate: 1},
+		},
+	})
+
+	cases := map[string]float64{
+		"api.openai.com":      1,
+		"ios.chat.openai.com": 2,
+		"openai.com":          2,
+		"notopenai.com":       10,
+		"www.youtube.com":     10,
+	}
+	for host, want := range cases {
+		if got := hostLimitFor(host).Rate; got != want {
+			t.Errorf("%s: got rate %v, want %v", host, got, want)
+		}
+	}
+}
+
+func TestHostLimitRetryAfter429(t *testing.T) {
+	withRateLimitConfig(t, config.PlatformRateLimitConfig{
+		Enable:        true,
+		MaxRetryAfter: 2,
+	})
+
+	var hits atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if hits.Add(1) == 1 {
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	client := &http.Client{Transport: WrapHostLimit(http.DefaultTransport)}
+	start := time.Now()
+	resp, err := client.Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("status: got %d, want 200 after retry", resp.StatusCode)
+	}
+	if time.Since(start) < time.Second {
+		t.Errorf("retry did not honor Retry-After")
+	}
+	if RateLimitedProbes.Load() != 1 || ThrottledProbes.Load() == 0 {
+		t.Errorf("counters: 429=%d throttled=%d", RateLimitedProbes.Load(), ThrottledProbes.Load())
+	}
+}
+
+func TestHostLimitConcurrency(t *testing.T) {
+	withRateLimitConfig(t, config.PlatformRateLimitConfig{
+		Enable:        true,
+		HostRateLimit: config.HostRateLimit{Concurrent: 1},
+	})
+
+	var inflight, peak atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := inflight.Add(1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		time.Sleep(50 * time.Millisecond)
+		inflight.Add(-1)
+	}))
+	defer srv.Close()
+
+	client := &http.Client{Transport: WrapHostLimit(http.DefaultTransport)}
+	done := make(chan struct{})
+	for range 4 {
+		go func() {
+			if resp, err := client.Get(srv.URL); err == nil {
+				resp.Body.Close()
+			}
+			done <- struct{}{}
+		}()
+	}
+	for range 4 {
+		<-done
+	}
+
+	if peak.Load() != 1 {
+		t.Errorf("peak concurrency: got %d, want 1", peak.Load())
+	}
+	if ThrottledProbes.Load() == 0 {
+		t.Errorf("expected throttled probes")
+	}
+}
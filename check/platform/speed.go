@@ -25,6 +25,15 @@ func init() {
 	}
 }
 
+// ResolveSpeedTestURL 返回本次使用的测速地址，random 模式下随机选择
+func ResolveSpeedTestURL() string {
+	url := config.GlobalConfig.SpeedTestURL
+	if strings.Contains(url, "random") && len(testURLs) > 0 {
+		url = testURLs[rand.IntN(len(testURLs))]
+	}
+	return url
+}
+
 // networkLimitedReader 负责在读取 Body 时检查底层网络流量是否超限
 type networkLimitedReader struct {
 	reader      io.Reader
@@ -60,13 +69,10 @@ func (r *networkLimitedReader) Read(p []byte) (int, error) {
 	return r.reader.Read(p)
 }
 
-// CheckSpeed 执行下载测速
-func CheckSpeed(httpClient *http.Client, bucket *ratelimit.Bucket, getNetBytes func() uint64) (int, int64, error) {
+// CheckSpeed 执行下载测速，parent 取消时立即中断下载
+func CheckSpeed(parent context.Context, httpClient *http.Client, bucket *ratelimit.Bucket, getNetBytes func() uint64) (int, int64, error) {
 	// 确定测速 URL，根据配置使用随机下载测速链接
-	url := config.GlobalConfig.SpeedTestURL
-	if strings.Contains(url, "random") && len(testURLs) > 0 {
-		url = testURLs[rand.IntN(len(testURLs))]
-	}
+	url := ResolveSpeedTestURL()
 	slog.Debug("随机选择的测速URL", "url", url)
 
 	speedClient := *httpClient
@@ -74,7 +80,7 @@ func CheckSpeed(httpClient *http.Client, bucket *ratelimit.Bucket, getNetBytes f
 
 	// 下载需要根据配置文件设置较长的超时
 	timeout := time.Duration(config.GlobalConfig.DownloadTimeout) * time.Second
-	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	ctx, cancel := context.WithTimeout(parent, timeout)
 	defer cancel()
 
 	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
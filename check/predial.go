@@ -0,0 +1,166 @@
+package check
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"log/slog"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/sinspired/subs-check-pro/v2/config"
+	"github.com/sinspired/subs-check-pro/v2/proxy/parse"
+)
+
+// PreDialPruned 本轮预检剔除的不可达节点数量
+var PreDialPruned atomic.Uint32
+
+// udpProxyTypes 基于 UDP 传输的协议，预检时发送探测包而非 TCP 连接
+var udpProxyTypes = map[string]bool{
+	"hysteria":  true,
+	"hysteria2": true,
+	"tuic":      true,
+	"wireguard": true,
+	"juicity":   true,
+}
+
+// dialEndpoint 预检目标
+type dialEndpoint struct {
+	network string
+	addr    string
+}
+
+// proxyEndpoint 提取节点的直连目标，无法预检的节点返回 false（直接保留）
+func proxyEndpoint(m map[string]any) (dialEndpoint, bool) {
+	// 链式代理的首跳不是节点自身，无法直连判断
+	if _, ok := m["dialer-proxy"]; ok {
+		return dialEndpoint{}, false
+	}
+	server, _ := m["server"].(string)
+	port := parse.ToIntPort(m["port"])
+	if server == "" || port <= 0 || port > 65535 {
+		return dialEndpoint{}, false
+	}
+
+	network := "tcp"
+	pType, _ := m["type"].(string)
+	if udpProxyTypes[pType] {
+		network = "udp"
+	}
+	if transport, _ := m["transport"].(string); strings.EqualFold(transport, "udp") {
+		network = "udp"
+	}
+	return dialEndpoint{network: network, addr: net.JoinHostPort(server, strconv.Itoa(port))}, true
+}
+
+// preDialFilter 直连预检 server:port，剔除不可达节点。
+// 相同目标只探测一次；收到强制关闭信号时未探测的节点一律保留。
+func preDialFilter(proxies []map[string]any) []map[string]any {
+	PreDialPruned.Store(0)
+	cfg := config.GlobalConfig.PreDial
+	if !cfg.Enable || len(proxies) == 0 {
+		return proxies
+	}
+	timeout := time.Duration(cfg.Timeout) * time.Millisecond
+	if timeout <= 0 {
+		timeout = 1500 * time.Millisecond
+	}
+
+	// 目标去重
+	slots := make([]int, len(proxies))
+	index := make(map[dialEndpoint]int)
+	var endpoints []dialEndpoint
+	for i, m := range proxies {
+		ep, ok := proxyEndpoint(m)
+		if !ok {
+			slots[i] = -1
+			continue
+		}
+		slot, seen := index[ep]
+		if !seen {
+			slot = len(endpoints)
+			index[ep] = slot
+			endpoints = append(endpoints, ep)
+		}
+		slots[i] = slot
+	}
+
+	CurrentStepName.Store("预检")
+	ProxyCount.Store(uint32(len(endpoints)))
+	Progress.Store(0)
+
+	unreachable := make([]bool, len(endpoints))
+	concurrency := min(max(cfg.Concurrent, 1), len(endpoints))
+
+	var next atomic.Int64
+	next.Store(-1)
+	var wg sync.WaitGroup
+	for range concurrency {
+		wg.Go(func() {
+			dialer := &net.Dialer{Timeout: timeout}
+			for {
+				waitIfPaused(context.Background())
+				if ForceClose.Load() {
+					return
+				}
+				i := next.Add(1)
+				if i >= int64(len(endpoints)) {
+					return
+				}
+				unreachable[i] = !preDial(dialer, endpoints[i], timeout)
+				Progress.Add(1)
+			}
+		})
+	}
+	wg.Wait()
+
+	kept := proxies[:0]
+	pruned := 0
+	for i, m := range proxies {
+		if slot := slots[i]; slot >= 0 && unreachable[slot] {
+			pruned++
+			continue
+		}
+		kept = append(kept, m)
+	}
+	// 断开被剔除节点的引用
+	clear(proxies[len(kept):])
+
+	PreDialPruned.Store(uint32(pruned))
+	slog.Info("预检完成", "目标", len(endpoints), "剔除不可达节点", pruned, "剩余", len(kept))
+
+	Progress.Store(0)
+	ProxyCount.Store(0)
+	return kept
+}
+
+// preDial 探测目标是否可达。
+// TCP 以连接成功为准；UDP 无握手，仅在收到 ICMP 端口不可达（ECONNREFUSED）时判定为不可达。
+func preDial(dialer *net.Dialer, ep dialEndpoint, timeout time.Duration) bool {
+	conn, err := dialer.Dial(ep.network, ep.addr)
+	if err != nil {
+		return false
+	}
+	defer conn.Close()
+
+	if ep.network == "tcp" {
+		return true
+	}
+
+	probe := make([]byte, 16)
+	_, _ = rand.Read(probe)
+	_ = conn.SetDeadline(time.Now().Add(timeout))
+	if _, err := conn.Write(probe); err != nil {
+		return !errors.Is(err, syscall.ECONNREFUSED)
+	}
+	buf := make([]byte, 64)
+	if _, err := conn.Read(buf); err != nil {
+		return !errors.Is(err, syscall.ECONNREFUSED)
+	}
+	return true
+}
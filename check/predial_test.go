@@ -0,0 +1,81 @@
+package check
+
+import (
+	"net"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/sinspired/subs-check-pro/v2/config"
+)
+
+func TestProxyEndpoint(t *testing.T) {
+	cases := []struct {
+		m       map[string]any
+		network string
+		ok      bool
+	}{
+		{map[string]any{"type": "vless", "server": "1.2.3.4", "port": 443}, "tcp", true},
+		{map[string]any{"type": "hysteria2", "server": "1.2.3.4", "port": "8443"}, "udp", true},
+		{map[string]any{"type": "mieru", "server": "1.2.3.4", "port": 2000, "transport": "UDP"}, "udp", true},
+		{map[string]any{"type": "ss", "server": "1.2.3.4", "port": 8388, "dialer-proxy": "relay"}, "", false},
+		{map[string]any{"type": "ss", "server": "", "port": 8388}, "", false},
+	}
+	for i, c := range cases {
+		ep, ok := proxyEndpoint(c.m)
+		if ok != c.ok || ep.network != c.network {
+			t.Errorf("case %d: got (%v, %v), want (%s, %v)", i, ep, ok, c.network, c.ok)
+		}
+	}
+}
+
+func TestPreDialFilter(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			conn.Close()
+		}
+	}()
+	openPort := ln.Addr().(*net.TCPAddr).Port
+
+	// 获取一个已关闭的端口
+	closed, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	closedPort := closed.Addr().(*net.TCPAddr).Port
+	closed.Close()
+
+	old := config.GlobalConfig
+	config.GlobalConfig = &config.Config{PreDial: config.PreDialConfig{Enable: true, Timeout: 500, Concurrent: 4}}
+	defer func() { config.GlobalConfig = old }()
+
+	proxies := []map[string]any{
+		{"name": "open", "type": "trojan", "server": "127.0.0.1", "port": openPort},
+		{"name": "closed", "type": "trojan", "server": "127.0.0.1", "port": strconv.Itoa(closedPort)},
+		{"name": "open-dup", "type": "vmess", "server": "127.0.0.1", "port": openPort},
+		{"name": "unknown", "type": "ss"},
+	}
+
+	start := time.Now()
+	kept := preDialFilter(proxies)
+	if time.Since(start) > 5*time.Second {
+		t.Errorf("pre-dial took too long")
+	}
+	if len(kept) != 3 || PreDialPruned.Load() != 1 {
+		t.Fatalf("kept %d, pruned %d; want 3, 1", len(kept), PreDialPruned.Load())
+	}
+	for _, m := range kept {
+		if m["name"] == "closed" {
+			t.Errorf("closed port should be pruned")
+		}
+	}
+}
@@ -445,6 +445,11 @@ func renderProgressString() string {
 		etaSuffix = " ETA: \033[36m" + FormatEta(etaSec) + "\033[0m"
 	}
 
+	// 暂停时替换 ETA 显示
+	if Paused.Load() {
+		etaSuffix = " \033[33m[已暂停]\033[0m"
+	}
+
 	barWidth := 40
 	barFilled := min(int(percent/100*float64(barWidth)), barWidth) // 先限制不超过 barWidth
 
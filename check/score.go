@@ -0,0 +1,239 @@
+package check
+
+import (
+	"cmp"
+	"log/slog"
+	"math"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync/atomic"
+
+	"github.com/sinspired/subs-check-pro/v2/check/platform"
+	"github.com/sinspired/subs-check-pro/v2/config"
+)
+
+// 评分因子名称
+const (
+	factorLatency   = "latency"
+	factorSpeed     = "speed"
+	factorStability = "stability"
+	factorIPRisk    = "iprisk"
+	factorPlatforms = "platforms"
+	factorISP       = "isp"
+	factorUptime    = "uptime"
+)
+
+// NodeScore 节点评分快照，供 API 查询
+type NodeScore struct {
+	Name      string             `json:"name"`
+	Type      string             `json:"type"`
+	Country   string             `json:"country,omitempty"`
+	Score     float64            `json:"score"`
+	Speed     int                `json:"speed"`
+	Latency   int                `json:"latency"`
+	IPRisk    string             `json:"iprisk,omitempty"`
+	ISP       string             `json:"isp,omitempty"`
+	Stability float64            `json:"stability,omitempty"`
+	Factors   map[string]float64 `json:"factors"`
+}
+
+var (
+	lastScores atomic.Pointer[[]NodeScore]
+
+	scoreTagRe = regexp.MustCompile(`\s*\|S\d{1,3}$`)
+)
+
+// LastScores 返回最近一轮检测的节点评分（按评分降序）
+func LastScores() []NodeScore {
+	if p := lastScores.Load(); p != nil {
+		return *p
+	}
+	return nil
+}
+
+// scoreResults 计算综合评分，按评分降序重排结果，并按需追加评分标签
+func (pc *ProxyChecker) scoreResults() {
+	uptime := updateUptime(pc.results)
+
+	cfg := config.GlobalConfig.Score
+	if !cfg.Enable || len(pc.results) == 0 {
+		return
+	}
+
+	maxSpeed := 0
+	for _, r := range pc.results {
+		maxSpeed = max(maxSpeed, r.Speed)
+	}
+
+	factors := make([]map[string]float64, len(pc.results))
+	for i := range pc.results {
+		up := -1.0
+		if uptime != nil {
+			up = uptime[i]
+		}
+		factors[i] = scoreFactors(&pc.results[i], maxSpeed, up)
+		pc.results[i].Score = weightedScore(factors[i], cfg.Weights)
+	}
+
+	// 结果与因子同步排序
+	order := make([]int, len(pc.results))
+	for i := range order {
+		order[i] = i
+	}
+	slices.SortStableFunc(order, func(a, b int) int {
+		return cmp.Compare(pc.results[b].Score, pc.results[a].Score)
+	})
+	sorted := make([]Result, len(pc.results))
+	snapshot := make([]NodeScore, len(pc.results))
+	for i, idx := range order {
+		r := pc.results[idx]
+		if cfg.Tag {
+			name, _ := r.Proxy["name"].(string)
+			r.Proxy["name"] = scoreTagRe.ReplaceAllString(name, "") + "|S" + strconv.Itoa(int(math.Round(r.Score)))
+		}
+		sorted[i] = r
+		snapshot[i] = newNodeScore(r, factors[idx])
+	}
+	pc.results = sorted
+	lastScores.Store(&snapshot)
+
+	slog.Debug("节点评分完成", "节点", len(sorted), "最高分", sorted[0].Score, "最低分", sorted[len(sorted)-1].Score)
+}
+
+// newNodeScore 生成评分快照
+func newNodeScore(r Result, factors map[string]float64) NodeScore {
+	ns := NodeScore{
+		Country: r.Country,
+		Score:   r.Score,
+		Speed:   r.Speed,
+		Latency: r.Latency,
+		IPRisk:  r.IPRisk,
+		ISP:     r.ISPTag,
+		Factors: factors,
+	}
+	ns.Name, _ = r.Proxy["name"].(string)
+	ns.Type, _ = r.Proxy["type"].(string)
+	if r.Stability != nil {
+		ns.Stability = r.Stability.Score
+	}
+	return ns
+}
+
+// scoreFactors 将各项检测结果归一化到 0-1，未检测的因子不返回。
+// uptime 小于 0 表示无历史可用率。
+func scoreFactors(r *Result, maxSpeed int, uptime float64) map[string]float64 {
+	f := make(map[string]float64, 7)
+
+	// 延迟 500ms 约得 0.5 分
+	if r.Latency > 0 {
+		f[factorLatency] = 1 / (1 + float64(r.Latency)/500)
+	}
+	if r.Speed > 0 && maxSpeed > 0 {
+		f[factorSpeed] = float64(r.Speed) / float64(maxSpeed)
+	}
+	if r.Stability != nil {
+		f[factorStability] = r.Stability.Score / 100
+	}
+	if pct, err := strconv.ParseFloat(strings.TrimSuffix(strings.TrimSpace(r.IPRisk), "%"), 64); err == nil {
+		f[factorIPRisk] = 1 - min(max(pct, 0), 100)/100
+	}
+	if v, ok := platformFactor(r); ok {
+		f[factorPlatforms] = v
+	}
+	if r.ISPTag != "" {
+		f[factorISP] = ispFactor(r.ISPTag)
+	}
+	if uptime >= 0 {
+		f[factorUptime] = uptime
+	}
+	return f
+}
+
+// weightedScore 按权重加权，仅计入已检测的因子，返回 0-100
+func weightedScore(factors map[string]float64, w config.ScoreWeights) float64 {
+	weights := map[string]float64{
+		factorLatency:   w.Latency,
+		factorSpeed:     w.Speed,
+		factorStability: w.Stability,
+		factorIPRisk:    w.IPRisk,
+		factorPlatforms: w.Platforms,
+		factorISP:       w.ISP,
+		factorUptime:    w.Uptime,
+	}
+	var sum, total float64
+	for name, v := range factors {
+		if wt := weights[name]; wt > 0 {
+			sum += wt * v
+			total += wt
+		}
+	}
+	if total == 0 {
+		return 0
+	}
+	return math.Round(100*sum/total*10) / 10
+}
+
+// platformFactor 已解锁平台占所选平台（不含 iprisk）的比例，部分解锁计 0.5
+func platformFactor(r *Result) (float64, bool) {
+	if !config.GlobalConfig.MediaCheck {
+		return 0, false
+	}
+	var got float64
+	n := 0
+	for _, plat := range config.GlobalConfig.Platforms {
+		v := 0.0
+		switch plat {
+		case "openai":
+			v = partial(r.Openai, r.OpenaiWeb)
+		case "copilot":
+			v = partial(r.Copilot && r.CopilotAPI, r.Copilot)
+		case "x":
+			v = partial(r.X, false)
+		case "netflix":
+			v = partial(r.Netflix, false)
+		case "disney":
+			v = partial(r.Disney, false)
+		case "youtube":
+			v = partial(r.Youtube != "" && !strings.HasSuffix(r.Youtube, "⁻"), r.Youtube != "")
+		case "gemini":
+			v = partial(r.Gemini.Access == platform.AccessNormal && r.Gemini.Region != "", r.Gemini.Access == platform.AccessSuspect)
+		case "tiktok":
+			v = partial(r.TikTok != "", false)
+		default:
+			continue
+		}
+		got += v
+		n++
+	}
+	if n == 0 {
+		return 0, false
+	}
+	return got / float64(n), true
+}
+
+// partial 完全解锁计 1，部分解锁计 0.5
+func partial(full, part bool) float64 {
+	switch {
+	case full:
+		return 1
+	case part:
+		return 0.5
+	}
+	return 0
+}
+
+// ispFactor 按关键字匹配 ISP 标签得分，取最高分，未匹配时为 0.5
+func ispFactor(tag string) float64 {
+	best, matched := 0.0, false
+	for kw, v := range config.GlobalConfig.Score.ISPScores {
+		if kw != "" && strings.Contains(tag, kw) && (!matched || v > best) {
+			best, matched = v, true
+		}
+	}
+	if !matched {
+		return 0.5
+	}
+	return min(max(best, 0), 1)
+}
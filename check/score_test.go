@@ -0,0 +1,55 @@
+package check
+
+import (
+	"testing"
+
+	"github.com/sinspired/subs-check-pro/v2/config"
+)
+
+func TestWeightedScoreSkipsMissingFactors(t *testing.T) {
+	w := config.ScoreWeights{Latency: 1, Speed: 3, Uptime: 2}
+
+	if got := weightedScore(map[string]float64{factorLatency: 1, factorSpeed: 1}, w); got != 100 {
+		t.Errorf("all full: got %.1f, want 100", got)
+	}
+	// 仅有延迟因子时，其余权重不参与计算
+	if got := weightedScore(map[string]float64{factorLatency: 0.5}, w); got != 50 {
+		t.Errorf("latency only: got %.1f, want 50", got)
+	}
+	// 权重为 0 的因子被忽略
+	if got := weightedScore(map[string]float64{factorISP: 1}, w); got != 0 {
+		t.Errorf("zero weight: got %.1f, want 0", got)
+	}
+}
+
+func TestScoreFactors(t *testing.T) {
+	old := config.GlobalConfig
+	config.GlobalConfig = &config.Config{
+		MediaCheck: true,
+		Platforms:  []string{"iprisk", "openai", "netflix"},
+		Score:      config.ScoreConfig{ISPScores: map[string]float64{"住宅": 1, "机房": 0.4}},
+	}
+	defer func() { config.GlobalConfig = old }()
+
+	r := &Result{Latency: 500, Speed: 512, IPRisk: "20%", OpenaiWeb: true, ISPTag: "机房"}
+	f := scoreFactors(r, 1024, -1)
+
+	want := map[string]float64{
+		factorLatency:   0.5,
+		factorSpeed:     0.5,
+		factorIPRisk:    0.8,
+		factorPlatforms: 0.25,
+		factorISP:       0.4,
+	}
+	if len(f) != len(want) {
+		t.Fatalf("factors: got %v", f)
+	}
+	for k, v := range want {
+		if f[k] != v {
+			t.Errorf("%s: got %v, want %v", k, f[k], v)
+		}
+	}
+	if ispFactor("未知") != 0.5 {
+		t.Errorf("unmatched isp should be 0.5")
+	}
+}
@@ -0,0 +1,288 @@
+package check
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/metacubex/mihomo/common/convert"
+	"github.com/sinspired/subs-check-pro/v2/check/platform"
+	"github.com/sinspired/subs-check-pro/v2/config"
+	"github.com/sinspired/subs-check-pro/v2/utils"
+)
+
+// StabilityResult 长时稳定性测试结果
+type StabilityResult struct {
+	Duration    time.Duration
+	AvgSpeed    int     // 平均吞吐 KB/s
+	SpeedCV     float64 // 吞吐变异系数（标准差/均值），越小越平稳
+	Stalls      int     // 持续传输中吞吐为 0 的秒数
+	Disconnects int     // 传输中断次数
+	AvgLatency  int     // 平均延迟 ms
+	Jitter      int     // 延迟标准差 ms
+	LatencyLoss int     // 延迟采样失败次数
+	Score       float64 // 稳定性评分 0-100
+}
+
+// runStabilityStage 对测速最快的前 N 个节点进行长时稳定性测试，并按评分重排结果
+func (pc *ProxyChecker) runStabilityStage() {
+	cfg := config.GlobalConfig.Stability
+	if !cfg.Enable || len(pc.results) == 0 || ForceClose.Load() {
+		return
+	}
+
+	topN := cfg.TopN
+	if topN <= 0 {
+		topN = 10
+	}
+	duration := time.Duration(max(cfg.Duration, 5)) * time.Second
+
+	// 按测速结果选取候选节点
+	order := make([]int, len(pc.results))
+	for i := range order {
+		order[i] = i
+	}
+	slices.SortStableFunc(order, func(a, b int) int {
+		return cmp.Compare(pc.results[b].Speed, pc.results[a].Speed)
+	})
+	candidates := order[:min(topN, len(order))]
+
+	slog.Info("开始稳定性测试", "节点", len(candidates), "时长", duration.String())
+	CurrentStepName.Store("稳定性")
+	ProxyCount.Store(uint32(len(candidates)))
+	Progress.Store(0)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		ticker := time.NewTicker(100 * time.Millisecond)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if ForceClose.Load() {
+					cancel()
+					return
+				}
+			}
+		}
+	}()
+
+	var next atomic.Int64
+	next.Store(-1)
+	var wg sync.WaitGroup
+	for range min(max(cfg.Concurrent, 1), len(candidates)) {
+		wg.Go(func() {
+			for {
+				waitIfPaused(ctx)
+				i := next.Add(1)
+				if i >= int64(len(candidates)) || checkCtxDone(ctx) {
+					return
+				}
+				res := &pc.results[candidates[i]]
+				if st := measureStability(ctx, res.Proxy, duration); st != nil {
+					res.Stability = st
+					slog.Debug("稳定性测试完成", "name", res.Proxy["name"], "score", fmt.Sprintf("%.1f", st.Score))
+				}
+				Progress.Add(1)
+			}
+		})
+	}
+	wg.Wait()
+
+	// 已测节点按评分排在前面，其余保持原有顺序
+	slices.SortStableFunc(pc.results, func(a, b Result) int {
+		return cmp.Compare(stabilityScore(b), stabilityScore(a))
+	})
+}
+
+// stabilityScore 返回用于排序的评分，未测试的节点为 -1
+func stabilityScore(r Result) float64 {
+	if r.Stability == nil {
+		return -1
+	}
+	return r.Stability.Score
+}
+
+// measureStability 重新创建客户端，在 duration 内持续下载并定时采样延迟
+func measureStability(ctx context.Context, mapping map[string]any, duration time.Duration) *StabilityResult {
+	cli := CreateClient(mapping)
+	if cli == nil {
+		return nil
+	}
+	defer cli.Close()
+
+	ctx, cancel := context.WithTimeout(ctx, duration)
+	defer cancel()
+
+	interval := time.Duration(max(config.GlobalConfig.Stability.LatencyInterval, 1)) * time.Second
+	res := &StabilityResult{}
+	start := time.Now()
+
+	var (
+		wg        sync.WaitGroup
+		speeds    []float64
+		latencies []float64
+	)
+	if url := platform.ResolveSpeedTestURL(); url != "" && !strings.Contains(url, "random") {
+		wg.Go(func() {
+			speeds, res.Stalls, res.Disconnects = sustainedTransfer(ctx, cli, url)
+		})
+	}
+	wg.Go(func() {
+		latencies, res.LatencyLoss = sampleLatency(ctx, cli, interval)
+	})
+	wg.Wait()
+
+	res.Duration = time.Since(start)
+	avgSpeed, sdSpeed := meanStdDev(speeds)
+	avgLat, sdLat := meanStdDev(latencies)
+	res.AvgSpeed = int(avgSpeed)
+	if avgSpeed > 0 {
+		res.SpeedCV = sdSpeed / avgSpeed
+	}
+	res.AvgLatency = int(avgLat)
+	res.Jitter = int(sdLat)
+	res.Score = scoreStability(res, len(speeds), len(latencies))
+	return res
+}
+
+// sustainedTransfer 持续下载直到 ctx 结束，按秒采样吞吐（KB/s）。
+// 读取出错时计为一次断线并重新连接；文件下载完毕则直接重新请求。
+func sustainedTransfer(ctx context.Context, cli *ProxyClient, url string) (samples []float64, stalls, disconnects int) {
+	client := *cli.Client
+	client.Timeout = 0
+
+	var received atomic.Int64
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		buf := make([]byte, 32*1024)
+		for ctx.Err() == nil && !BudgetExceeded.Load() {
+			err := downloadOnce(ctx, &client, url, buf, &received)
+			if err != nil && ctx.Err() == nil {
+				disconnects++
+				_ = utils.SleepCtx(ctx, 500*time.Millisecond)
+			}
+		}
+	}()
+
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	var last int64
+	for {
+		select {
+		case <-done:
+			return samples, stalls, disconnects
+		case <-ticker.C:
+			cur := received.Load()
+			delta := cur - last
+			last = cur
+			samples = append(samples, float64(delta)/1024)
+			if delta == 0 {
+				stalls++
+			}
+		}
+	}
+}
+
+// downloadOnce 发起一次下载并持续读取，返回非正常结束的错误
+func downloadOnce(ctx context.Context, client *http.Client, url string, buf []byte, received *atomic.Int64) error {
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("User-Agent", convert.RandUserAgent())
+	req.Header.Set("Cache-Control", "no-cache")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("http status %d", resp.StatusCode)
+	}
+	for {
+		n, err := resp.Body.Read(buf)
+		received.Add(int64(n))
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		}
+	}
+}
+
+// sampleLatency 按间隔采样延迟（ms），返回成功样本与失败次数
+func sampleLatency(ctx context.Context, cli *ProxyClient, interval time.Duration) (samples []float64, loss int) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		start := time.Now()
+		ok, _ := platform.CheckGstatic(cli.Client, ctx)
+		switch {
+		case ok:
+			samples = append(samples, float64(time.Since(start).Milliseconds()))
+		case ctx.Err() == nil:
+			loss++
+		}
+		select {
+		case <-ctx.Done():
+			return samples, loss
+		case <-ticker.C:
+		}
+	}
+}
+
+// scoreStability 综合吞吐波动、卡顿、断线与延迟计算 0-100 评分
+func scoreStability(r *StabilityResult, speedSamples, latencySamples int) float64 {
+	latencyOK := 1.0
+	if total := latencySamples + r.LatencyLoss; total > 0 {
+		latencyOK = float64(latencySamples) / float64(total)
+	}
+	jitter := 1.0
+	if r.AvgLatency > 0 {
+		jitter = 1 / (1 + float64(r.Jitter)/float64(r.AvgLatency))
+	}
+
+	// 未进行持续下载时仅依据延迟评分
+	if speedSamples == 0 {
+		return math.Round(100*(0.6*latencyOK+0.4*jitter)*10) / 10
+	}
+
+	smooth := 1 / (1 + r.SpeedCV)
+	stallFree := 1 - float64(r.Stalls)/float64(speedSamples)
+	connected := 1 / (1 + float64(r.Disconnects))
+
+	score := 0.3*smooth + 0.25*stallFree + 0.2*connected + 0.15*latencyOK + 0.1*jitter
+	return math.Round(100*score*10) / 10
+}
+
+// meanStdDev 计算均值与标准差
+func meanStdDev(xs []float64) (mean, sd float64) {
+	if len(xs) == 0 {
+		return 0, 0
+	}
+	for _, x := range xs {
+		mean += x
+	}
+	mean /= float64(len(xs))
+	for _, x := range xs {
+		sd += (x - mean) * (x - mean)
+	}
+	return mean, math.Sqrt(sd / float64(len(xs)))
+}
@@ -0,0 +1,32 @@
+package check
+
+import "testing"
+
+func TestScoreStability(t *testing.T) {
+	steady := &StabilityResult{SpeedCV: 0.05, AvgLatency: 200, Jitter: 10}
+	flaky := &StabilityResult{SpeedCV: 0.8, Stalls: 12, Disconnects: 3, AvgLatency: 200, Jitter: 150, LatencyLoss: 5}
+
+	s1 := scoreStability(steady, 60, 30)
+	s2 := scoreStability(flaky, 60, 25)
+	if s1 <= s2 {
+		t.Errorf("steady node should score higher: steady=%.1f flaky=%.1f", s1, s2)
+	}
+	if s1 > 100 || s2 < 0 {
+		t.Errorf("score out of range: %.1f %.1f", s1, s2)
+	}
+
+	// 未进行持续下载时只看延迟
+	if s := scoreStability(&StabilityResult{AvgLatency: 100}, 0, 10); s != 100 {
+		t.Errorf("latency-only score: got %.1f, want 100", s)
+	}
+}
+
+func TestMeanStdDev(t *testing.T) {
+	mean, sd := meanStdDev([]float64{2, 4, 4, 4, 5, 5, 7, 9})
+	if mean != 5 || sd != 2 {
+		t.Errorf("got mean=%v sd=%v, want 5, 2", mean, sd)
+	}
+	if m, s := meanStdDev(nil); m != 0 || s != 0 {
+		t.Errorf("empty: got %v %v", m, s)
+	}
+}
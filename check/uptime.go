@@ -0,0 +1,151 @@
+package check
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/goccy/go-yaml"
+	"github.com/sinspired/subs-check-pro/v2/config"
+	"github.com/sinspired/subs-check-pro/v2/save/method"
+	"github.com/sinspired/subs-check-pro/v2/utils"
+)
+
+const (
+	nodeUptimeFile = "node-uptime.yaml"
+	// uptimeRetention 超过该时长未出现在订阅中的节点记录将被清理
+	uptimeRetention = 30 * 24 * time.Hour
+)
+
+// uptimeRecord 单个节点的历史检测记录。
+// 仅记录曾经检测成功的节点，避免统计文件随失效节点无限增长。
+type uptimeRecord struct {
+	Checked  int    `yaml:"checked"`
+	Success  int    `yaml:"success"`
+	LastSeen string `yaml:"last-seen"`
+}
+
+var (
+	uptimeMu      sync.Mutex
+	uptimeRecords map[string]*uptimeRecord // 为 nil 表示未启用
+	uptimeSeen    map[string]struct{}
+)
+
+// uptimeEnabled 历史可用率参与评分时才需要记录
+func uptimeEnabled() bool {
+	cfg := config.GlobalConfig.Score
+	return cfg.Enable && cfg.Weights.Uptime > 0
+}
+
+// initUptime 加载历史记录，每轮检测开始时调用
+func initUptime() {
+	uptimeMu.Lock()
+	defer uptimeMu.Unlock()
+	uptimeRecords, uptimeSeen = nil, nil
+	if !uptimeEnabled() {
+		return
+	}
+	uptimeRecords = loadUptime()
+	uptimeSeen = make(map[string]struct{})
+}
+
+// uptimeKey 节点指纹的短哈希
+func uptimeKey(m map[string]any) string {
+	sum := sha1.Sum([]byte(utils.GenerateProxyKey(m)))
+	return hex.EncodeToString(sum[:8])
+}
+
+// markUptimeSeen 记录本轮实际参与检测的节点
+func markUptimeSeen(m map[string]any) {
+	if m == nil || !uptimeEnabled() {
+		return
+	}
+	key := uptimeKey(m)
+	uptimeMu.Lock()
+	if uptimeSeen != nil {
+		uptimeSeen[key] = struct{}{}
+	}
+	uptimeMu.Unlock()
+}
+
+// updateUptime 合并本轮结果并保存，返回每个结果的历史可用率（平滑后），未启用时返回 nil
+func updateUptime(results []Result) []float64 {
+	uptimeMu.Lock()
+	defer uptimeMu.Unlock()
+	if uptimeRecords == nil {
+		return nil
+	}
+
+	today := time.Now().Format(time.DateOnly)
+	for key := range uptimeSeen {
+		if rec, ok := uptimeRecords[key]; ok {
+			rec.Checked++
+			rec.LastSeen = today
+		}
+	}
+
+	ratios := make([]float64, len(results))
+	for i, r := range results {
+		key := uptimeKey(r.Proxy)
+		rec, ok := uptimeRecords[key]
+		if !ok {
+			rec = &uptimeRecord{Checked: 1}
+			uptimeRecords[key] = rec
+		}
+		if _, seen := uptimeSeen[key]; !seen && ok {
+			rec.Checked++
+		}
+		rec.Success = min(rec.Success+1, rec.Checked)
+		rec.LastSeen = today
+		// 拉普拉斯平滑，新节点不会直接得满分
+		ratios[i] = float64(rec.Success+1) / float64(rec.Checked+2)
+	}
+
+	cutoff := time.Now().Add(-uptimeRetention)
+	for key, rec := range uptimeRecords {
+		if t, err := time.Parse(time.DateOnly, rec.LastSeen); err != nil || t.Before(cutoff) {
+			delete(uptimeRecords, key)
+		}
+	}
+	saveUptime(uptimeRecords)
+	uptimeSeen = nil
+	return ratios
+}
+
+// uptimePath 历史记录文件路径
+func uptimePath() string {
+	saver, err := method.NewStatsSaver()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(saver.StatsPath, nodeUptimeFile)
+}
+
+// loadUptime 读取历史记录，文件不存在或损坏时返回空表
+func loadUptime() map[string]*uptimeRecord {
+	recs := make(map[string]*uptimeRecord)
+	path := uptimePath()
+	if path == "" {
+		return recs
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return recs
+	}
+	if err := yaml.Unmarshal(data, &recs); err != nil || recs == nil {
+		return make(map[string]*uptimeRecord)
+	}
+	return recs
+}
+
+// saveUptime 写入历史记录
+func saveUptime(recs map[string]*uptimeRecord) {
+	data, err := yaml.Marshal(recs)
+	if err != nil {
+		return
+	}
+	_ = method.SaveToStats(data, nodeUptimeFile, "节点历史可用率")
+}
@@ -20,6 +20,8 @@ type ResolveDomainConfig struct {
 	Edns        string `yaml:"edns"`
 	Concurrency int    `yaml:"concurrency"`
 	Timeout     int    `yaml:"timeout"`
+	// Engine 解析引擎：sub-store（默认，推送操作到 sub-store）/ native（在获取订阅时直接解析，去重前生效）
+	Engine string `yaml:"engine"`
 }
 
 // SubProcessConfig sub 订阅的操作配置。
@@ -47,6 +49,159 @@ type SubProcessConfig struct {
 	SubInfo bool `yaml:"sub-info"`
 }
 
+// HostRateLimit 单个目标主机的限流参数
+type HostRateLimit struct {
+	// Rate 每秒允许的请求数，<=0 不限
+	Rate float64 `yaml:"rate"`
+	// Burst 令牌桶容量，<=0 时取 max(1, Rate)
+	Burst int `yaml:"burst"`
+	// Concurrent 最大并发请求数，<=0 不限
+	Concurrent int `yaml:"concurrent"`
+}
+
+// PlatformRateLimitConfig 媒体检测按目标主机限流配置，与带宽限速相互独立。
+type PlatformRateLimitConfig struct {
+	Enable bool `yaml:"enable"`
+
+	// HostRateLimit 默认限流参数，对每个目标主机单独生效
+	HostRateLimit `yaml:",inline"`
+
+	// MaxRetryAfter 收到 429 时遵循 Retry-After 等待后重试一次的最长等待秒数，
+	// 超过则不重试，仅对该主机进入冷却
+	MaxRetryAfter int `yaml:"max-retry-after"`
+
+	// Hosts 按主机覆盖默认参数，键为域名，同时匹配其子域名
+	Hosts map[string]HostRateLimit `yaml:"hosts"`
+}
+
+// PreDialConfig 测活前直连 server:port 预检配置
+type PreDialConfig struct {
+	Enable bool `yaml:"enable"`
+	// Timeout 单次直连超时(毫秒)
+	Timeout int `yaml:"timeout"`
+	// Concurrent 预检并发数
+	Concurrent int `yaml:"concurrent"`
+}
+
+// StabilityConfig 常规检测结束后对头部节点进行的长时稳定性测试配置
+type StabilityConfig struct {
+	Enable bool `yaml:"enable"`
+	// TopN 参与测试的节点数（按测速结果从高到低）
+	TopN int `yaml:"top-n"`
+	// Duration 每个节点的测试时长(秒)
+	Duration int `yaml:"duration"`
+	// Concurrent 同时测试的节点数
+	Concurrent int `yaml:"concurrent"`
+	// LatencyInterval 延迟采样间隔(秒)
+	LatencyInterval int `yaml:"latency-interval"`
+}
+
+// SubSource 订阅源，配置中可写为字符串（仅 URL）或带独立选项的对象
+type SubSource struct {
+	URL       string            `yaml:"url"`
+	Name      string            `yaml:"name,omitempty"`       // 显示名称，用于命名模板与分析报告
+	Tags      []string          `yaml:"tags,omitempty"`       // 来源标签，写入节点元数据
+	Enable    *bool             `yaml:"enable,omitempty"`     // 为 false 时跳过，默认启用
+	UserAgent string            `yaml:"user-agent,omitempty"` // 固定 UA，默认轮换内置 UA
+	Headers   map[string]string `yaml:"headers,omitempty"`
+	Cookie    string            `yaml:"cookie,omitempty"`
+	Bearer    string            `yaml:"bearer-token,omitempty"`
+	BasicAuth string            `yaml:"basic-auth,omitempty"` // user:pass
+	Proxy     string            `yaml:"proxy,omitempty"`      // direct / system / 代理地址，默认按全局策略
+	Timeout   int               `yaml:"timeout,omitempty"`    // 秒，默认 sub-urls-timeout
+	Retry     int               `yaml:"retry,omitempty"`      // 默认 sub-urls-retry
+	Local     bool              `yaml:"-"`                    // sub-dirs 目录下的文件，仅由程序生成，配置中无法指定
+	Untrusted bool              `yaml:"-"`                    // 从第三方页面发现的地址，请求时不附带本机鉴权信息
+}
+
+// UnmarshalYAML 兼容字符串与对象两种写法
+func (s *SubSource) UnmarshalYAML(unmarshal func(any) error) error {
+	var u string
+	if err := unmarshal(&u); err == nil {
+		*s = SubSource{URL: u}
+		return nil
+	}
+	type plain SubSource
+	return unmarshal((*plain)(s))
+}
+
+// MarshalYAML 仅有 URL 时输出为字符串
+func (s SubSource) MarshalYAML() (any, error) {
+	type plain SubSource
+	if s.Name == "" && len(s.Tags) == 0 && s.Enable == nil && s.UserAgent == "" && len(s.Headers) == 0 &&
+		s.Cookie == "" && s.Bearer == "" && s.BasicAuth == "" && s.Proxy == "" && s.Timeout == 0 && s.Retry == 0 {
+		return s.URL, nil
+	}
+	return plain(s), nil
+}
+
+// Enabled 是否启用
+func (s SubSource) Enabled() bool {
+	return s.Enable == nil || *s.Enable
+}
+
+// SubQuotaConfig 上游订阅流量与到期提醒
+type SubQuotaConfig struct {
+	Notify      bool    `yaml:"notify"`        // 通过通知渠道发送提醒
+	ExpireDays  int     `yaml:"expire-days"`   // 距到期不足该天数时提醒，0 关闭
+	MinRemainGB float64 `yaml:"min-remain-gb"` // 剩余流量低于该值时提醒，0 关闭
+	Aggregate   bool    `yaml:"aggregate"`     // sub-info 使用上游订阅的真实流量汇总
+}
+
+// SubDiscoveryConfig 从聚合页递归发现订阅
+type SubDiscoveryConfig struct {
+	Enable       bool     `yaml:"enable"`
+	URLs         []string `yaml:"urls"`          // 入口页面
+	MaxDepth     int      `yaml:"max-depth"`     // 从入口起最多跟随的层数
+	MaxSources   int      `yaml:"max-sources"`   // 单轮最多发现的订阅数
+	AllowDomains []string `yaml:"allow-domains"` // 只跟随这些域名及其子域名，为空不限制
+	DenyDomains  []string `yaml:"deny-domains"`  // 不跟随的域名及其子域名
+}
+
+// SourceHealthConfig 订阅源健康评分与自动停用
+type SourceHealthConfig struct {
+	Enable         bool    `yaml:"enable"`
+	DeadRuns       int     `yaml:"dead-runs"`        // 连续无效多少轮后停用
+	MinAliveRatio  float64 `yaml:"min-alive-ratio"`  // 可用率低于该值视为无效，0 表示只有无可用节点才算无效
+	RecheckRuns    int     `yaml:"recheck-runs"`     // 停用后跳过多少轮再复查，复查仍无效时翻倍
+	MaxRecheckRuns int     `yaml:"max-recheck-runs"` // 复查间隔上限
+	Notify         bool    `yaml:"notify"`           // 停用与恢复时通过通知渠道提醒
+}
+
+// SmartShuffleConfig 节点乱序的额外约束
+type SmartShuffleConfig struct {
+	// ResolveDomain 解析域名型 server，按解析出的 IP 计算网段与 ASN
+	ResolveDomain bool `yaml:"resolve-domain"`
+	// ResolveConcurrent 域名解析并发数
+	ResolveConcurrent int `yaml:"resolve-concurrent"`
+	// ASN 按 ASN 约束最小间距，使用 GeoLite2-ASN 库
+	ASN bool `yaml:"asn"`
+	// ASNMinSpacing 同一 ASN 的最小间距，0 为自动（等于 concurrent）
+	ASNMinSpacing int `yaml:"asn-min-spacing"`
+}
+
+// ScoreWeights 综合评分各因子权重，为 0 表示不参与评分
+type ScoreWeights struct {
+	Latency   float64 `yaml:"latency"`
+	Speed     float64 `yaml:"speed"`
+	Stability float64 `yaml:"stability"`
+	IPRisk    float64 `yaml:"iprisk"`
+	Platforms float64 `yaml:"platforms"`
+	ISP       float64 `yaml:"isp"`
+	Uptime    float64 `yaml:"uptime"`
+}
+
+// ScoreConfig 节点综合评分配置，结果按评分从高到低输出
+type ScoreConfig struct {
+	Enable bool `yaml:"enable"`
+	// Tag 在节点名称末尾追加评分标签，如 |S86
+	Tag bool `yaml:"tag"`
+	// Weights 各因子权重
+	Weights ScoreWeights `yaml:"weights"`
+	// ISPScores ISP 标签关键字对应的得分(0-1)，按包含关系匹配，取最高分
+	ISPScores map[string]float64 `yaml:"isp-scores"`
+}
+
 type Config struct {
 	PrintProgress        bool    `yaml:"print-progress"`
 	ProgressMode         string  `yaml:"progress-mode"`
@@ -62,6 +217,8 @@ type Config struct {
 	DownloadTimeout      int     `yaml:"download-timeout"`
 	DownloadMB           int     `yaml:"download-mb"`
 	TotalSpeedLimit      int     `yaml:"total-speed-limit"`
+	MaxTrafficPerRun     int     `yaml:"max-traffic-per-run"`
+	MaxTrafficPerDay     int     `yaml:"max-traffic-per-day"`
 	Threshold            float32 `yaml:"threshold"`
 	GCThreshold          int64   `yaml:"gc-threshold"`
 	MinSpeed             int     `yaml:"min-speed"`
@@ -85,6 +242,15 @@ type Config struct {
 	SubUrlsReTry         int     `yaml:"sub-urls-retry"`
 	SubUrlsRetryInterval int     `yaml:"sub-urls-retry-interval"`
 	SubUrlsTimeout       int     `yaml:"sub-urls-timeout"`
+	// SubsCache 按 ETag/Last-Modified 发送条件请求，订阅未变化时复用上次解析的节点
+	SubsCache bool `yaml:"subs-cache"`
+	// SubUrlsRelay 订阅无法直连或经代理获取时，依次尝试的上次检测成功节点数，0 表示关闭
+	SubUrlsRelay int `yaml:"sub-urls-relay"`
+	// SubDirs 本地订阅目录，目录下的每个文件在拉取订阅时作为一个订阅解析；
+	// 末段可写 glob 过滤文件名，如 /data/nodes/*.yaml，相对路径基于配置文件所在目录
+	SubDirs []string `yaml:"sub-dirs"`
+	// SubDirsWatch 监听本地订阅目录，有新文件时立即检测，不等待下次定时任务
+	SubDirsWatch bool `yaml:"sub-dirs-watch"`
 
 	// SubsParseBatch 每批次发往去重队列的节点数
 	// 生产者攒够该数量后整批发送，消费者逐批接收处理。
@@ -110,15 +276,15 @@ type Config struct {
 	// 注意：真正防止 OOM 的是 MemoryLimitMB；这个只是日常情况下的内存/CPU 取舍旋钮。
 	GCPercent int `yaml:"gc-percent"`
 
-	SubUrlsRemote      []string `yaml:"sub-urls-remote"`
-	SubUrls            []string `yaml:"sub-urls"`
-	SuccessRate        float64  `yaml:"success-rate"`
-	MihomoAPIURL       string   `yaml:"mihomo-api-url"`
-	MihomoAPISecret    string   `yaml:"mihomo-api-secret"`
-	ListenPort         string   `yaml:"listen-port"`
-	RenameNode         bool     `yaml:"rename-node"`
-	KeepSuccessProxies bool     `yaml:"keep-success-proxies"`
-	OutputDir          string   `yaml:"output-dir"`
+	SubUrlsRemote      []string    `yaml:"sub-urls-remote"`
+	SubUrls            []SubSource `yaml:"sub-urls"`
+	SuccessRate        float64     `yaml:"success-rate"`
+	MihomoAPIURL       string      `yaml:"mihomo-api-url"`
+	MihomoAPISecret    string      `yaml:"mihomo-api-secret"`
+	ListenPort         string      `yaml:"listen-port"`
+	RenameNode         bool        `yaml:"rename-node"`
+	KeepSuccessProxies bool        `yaml:"keep-success-proxies"`
+	OutputDir          string      `yaml:"output-dir"`
 	// ConfigDir 运行时由 app.loadConfig 注入，值为当前配置文件所在目录。
 	// 不参与 YAML 序列化，仅供 save/method/local.go 计算默认输出路径使用。
 	ConfigDir           string   `yaml:"-"`
@@ -131,6 +297,8 @@ type Config struct {
 	SubStorePushService string   `yaml:"sub-store-push-service"`
 	SubStoreProduceCron string   `yaml:"sub-store-produce-cron"`
 	MihomoOverwriteURL  string   `yaml:"mihomo-overwrite-url"`
+	// Exporters 额外生成的客户端配置：surge、loon、quanx、shadowrocket
+	Exporters []string `yaml:"exporters"`
 
 	// ISPCheck 是否开启出口 ISP 类型检测（机房/住宅/移动/商宽/教育/政府/银行等）
 	ISPCheck bool `yaml:"isp-check"`
@@ -161,12 +329,18 @@ type Config struct {
 	MediaCheck       bool     `yaml:"media-check"`
 	Platforms        []string `yaml:"platforms"`
 	MaxMindDBPath    string   `yaml:"maxmind-db-path"`
+	GeoCity          bool     `yaml:"geo-city"`
+	GeoASN           bool     `yaml:"geo-asn"`
+	MaxMindCityDB    string   `yaml:"maxmind-city-db-path"`
+	MaxMindASNDB     string   `yaml:"maxmind-asn-db-path"`
 	DropBadCfNodes   bool     `yaml:"drop-bad-cf-nodes"`
 	EnhancedTag      bool     `yaml:"enhanced-tag"`
 	SuccessLimit     int32    `yaml:"success-limit"`
 	NodePrefix       string   `yaml:"node-prefix"`
+	NameTemplate     string   `yaml:"name-template"`
 	NodeType         []string `yaml:"node-type"`
 	NodeLoc          []string `yaml:"node-loc"`
+	PreFilter        []string `yaml:"pre-filter"`
 	EnableWebUI      bool     `yaml:"enable-web-ui"`
 	APIKey           string   `yaml:"api-key"`
 	SharePassword    string   `yaml:"share-password"`
@@ -186,6 +360,29 @@ type Config struct {
 
 	// SubProcess sub 订阅操作配置
 	SubProcess SubProcessConfig `yaml:"sub-process"`
+
+	// PlatformRateLimit 媒体检测按目标主机限流
+	PlatformRateLimit PlatformRateLimitConfig `yaml:"platform-rate-limit"`
+
+	// PreDial 测活前直连预检，剔除端口不可达的节点
+	PreDial PreDialConfig `yaml:"pre-dial"`
+
+	// Stability 头部节点长时稳定性测试，结果用于发布列表排序
+	Stability StabilityConfig `yaml:"stability"`
+
+	// Score 节点综合评分，决定保存与订阅输出的顺序
+	Score ScoreConfig `yaml:"score"`
+
+	SubQuota SubQuotaConfig `yaml:"sub-quota"`
+
+	// SubDiscovery 从聚合页递归发现订阅，结果记录在 stats/sub-discovered.yaml
+	SubDiscovery SubDiscoveryConfig `yaml:"sub-discovery"`
+
+	// SourceHealth 订阅源健康评分，连续无效的订阅自动停用，记录在 stats/sub-health.yaml
+	SourceHealth SourceHealthConfig `yaml:"source-health"`
+
+	// SmartShuffle 节点乱序时的域名解析与 ASN 间距约束
+	SmartShuffle SmartShuffleConfig `yaml:"smart-shuffle"`
 }
 
 var OriginDefaultConfig = &Config{
@@ -221,6 +418,7 @@ var OriginDefaultConfig = &Config{
 			Edns:        "",
 			Concurrency: 10,
 			Timeout:     8000,
+			Engine:      "sub-store",
 		},
 		NodeSplit:       false,
 		RegexFilterKeep: true, // 默认白名单
@@ -228,6 +426,83 @@ var OriginDefaultConfig = &Config{
 	},
 
 	ISPTimeout: 5, // 默认 5 秒，最高 15 秒
+
+	SubsCache:    true,
+	SubUrlsRelay: 0,
+
+	PlatformRateLimit: PlatformRateLimitConfig{
+		Enable: false,
+		HostRateLimit: HostRateLimit{
+			Rate:       10,
+			Burst:      20,
+			Concurrent: 32,
+		},
+		MaxRetryAfter: 10,
+	},
+
+	PreDial: PreDialConfig{
+		Enable:     false,
+		Timeout:    1500,
+		Concurrent: 500,
+	},
+
+	Stability: StabilityConfig{
+		Enable:          false,
+		TopN:            10,
+		Duration:        60,
+		Concurrent:      2,
+		LatencyInterval: 2,
+	},
+
+	SmartShuffle: SmartShuffleConfig{
+		ResolveDomain:     false,
+		ResolveConcurrent: 64,
+		ASN:               false,
+		ASNMinSpacing:     0,
+	},
+
+	Score: ScoreConfig{
+		Enable: true,
+		Tag:    false,
+		Weights: ScoreWeights{
+			Latency:   2,
+			Speed:     3,
+			Stability: 2,
+			IPRisk:    1,
+			Platforms: 2,
+			ISP:       1,
+			Uptime:    2,
+		},
+		ISPScores: map[string]float64{
+			"住宅": 1,
+			"原生": 0.9,
+			"移动": 0.9,
+			"商宽": 0.8,
+			"教育": 0.8,
+			"政府": 0.7,
+			"银行": 0.7,
+			"机房": 0.4,
+		},
+	},
+
+	SubQuota: SubQuotaConfig{
+		Notify:      true,
+		ExpireDays:  3,
+		MinRemainGB: 1,
+		Aggregate:   false,
+	},
+
+	SubDiscovery: SubDiscoveryConfig{
+		MaxDepth:   2,
+		MaxSources: 100,
+	},
+
+	SourceHealth: SourceHealthConfig{
+		DeadRuns:       5,
+		RecheckRuns:    2,
+		MaxRecheckRuns: 64,
+		Notify:         true,
+	},
 }
 
 // GlobalConfig 指向当前生效配置
@@ -0,0 +1,254 @@
+package proxies
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/sinspired/subs-check-pro/v2/proxy/parse"
+	"github.com/sinspired/subs-check-pro/v2/utils"
+)
+
+// 订阅转换：复用订阅拉取与解析流程，按选项过滤、去重和重命名后直接输出，不执行检测。
+
+// ConvertOptions 订阅转换参数
+type ConvertOptions struct {
+	URLs    []string // 订阅链接
+	Raw     []byte   // 直接提交的订阅内容
+	Include string   // 仅保留名称匹配的节点（正则）
+	Exclude string   // 剔除名称匹配的节点（正则）
+	Rename  string   // 名称模板，可用变量 flag country sub name 与计数器，其余检测变量为空
+	Dedup   bool     // 按协议指纹去重
+	Emoji   bool     // 按名称识别地区并添加国旗
+}
+
+// ConvertStats 转换统计
+type ConvertStats struct {
+	Parsed   int      `json:"parsed"`           // 解析出的有效节点
+	Filtered int      `json:"filtered"`         // 被 include/exclude 过滤
+	Deduped  int      `json:"deduped"`          // 去重移除
+	Output   int      `json:"output"`           // 最终输出
+	Failed   []string `json:"failed,omitempty"` // 拉取或解析失败的来源
+}
+
+// convertConcurrency 转换时并发拉取的订阅数
+const convertConcurrency = 8
+
+// Convert 拉取并解析订阅，按选项处理后返回节点，节点顺序与来源顺序一致
+func Convert(opts ConvertOptions) ([]map[string]any, ConvertStats, error) {
+	var stats ConvertStats
+	if len(opts.URLs) == 0 && len(opts.Raw) == 0 {
+		return nil, stats, errors.New("缺少订阅链接或订阅内容")
+	}
+	include, err := compileNameFilter(opts.Include)
+	if err != nil {
+		return nil, stats, fmt.Errorf("include 正则无效: %w", err)
+	}
+	exclude, err := compileNameFilter(opts.Exclude)
+	if err != nil {
+		return nil, stats, fmt.Errorf("exclude 正则无效: %w", err)
+	}
+	var tpl *NameTemplate
+	if opts.Rename != "" {
+		// 不使用 CompiledNameTemplate，避免替换检测流程缓存的模板
+		if tpl, err = ParseNameTemplate(opts.Rename); err != nil {
+			return nil, stats, fmt.Errorf("名称模板无效: %w", err)
+		}
+	}
+
+	// 按来源顺序收集节点，raw 内容排在最前
+	type source struct {
+		label string // 日志与失败列表中的名称
+		sub   string // 模板变量 {sub}
+		data  []byte
+		nodes []map[string]any
+		err   error
+	}
+	var sources []*source
+	if len(opts.Raw) > 0 {
+		sources = append(sources, &source{label: "raw", sub: "raw", data: opts.Raw})
+	}
+	for _, u := range opts.URLs {
+		if u = strings.TrimSpace(u); u == "" {
+			continue
+		}
+		sub := u
+		if pu, err := url.Parse(u); err == nil && pu.Hostname() != "" {
+			sub = pu.Hostname()
+		}
+		sources = append(sources, &source{label: u, sub: sub})
+	}
+
+	sem := make(chan struct{}, convertConcurrency)
+	var wg sync.WaitGroup
+	for _, s := range sources {
+		wg.Go(func() {
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			if s.data == nil {
+				if s.data, s.err = FetchSubsData(s.label); s.err != nil {
+					return
+				}
+			}
+			s.nodes, s.err = parseConvertSource(s.data, s.label)
+			s.data = nil
+		})
+	}
+	wg.Wait()
+
+	var (
+		out  []map[string]any
+		seen = make(map[string]struct{})
+		// 每次转换独立计数，不影响检测流程
+		counters = make(map[string]int)
+	)
+	for _, s := range sources {
+		if s.err != nil {
+			slog.Warn("订阅转换失败", "来源", s.label, "err", s.err)
+			stats.Failed = append(stats.Failed, s.label)
+			continue
+		}
+		for _, node := range s.nodes {
+			stats.Parsed++
+			name, _ := node["name"].(string)
+			if (include != nil && !include.MatchString(name)) || (exclude != nil && exclude.MatchString(name)) {
+				stats.Filtered++
+				continue
+			}
+			if opts.Dedup {
+				key := utils.GenerateProxyKey(node)
+				if _, dup := seen[key]; dup {
+					stats.Deduped++
+					continue
+				}
+				seen[key] = struct{}{}
+			}
+
+			code := guessCountry(name)
+			if tpl != nil {
+				vars := map[string]string{
+					"country": code,
+					"sub":     s.sub,
+					"name":    strings.TrimSpace(name),
+				}
+				if code != "" {
+					vars["flag"] = CountryCodeToFlag(code)
+				}
+				name = tpl.RenderScoped(vars, counters)
+			}
+			if opts.Emoji && code != "" && !hasFlagPrefix(name) {
+				name = CountryCodeToFlag(code) + " " + name
+			}
+			node["name"] = name
+			out = append(out, node)
+		}
+	}
+	stats.Output = len(out)
+
+	if len(out) == 0 && len(stats.Failed) == len(sources) {
+		return nil, stats, errors.New("所有来源均获取或解析失败")
+	}
+	return out, stats, nil
+}
+
+// parseConvertSource 解析单个来源，丢弃未通过节点校验的节点
+func parseConvertSource(data []byte, label string) ([]map[string]any, error) {
+	var nodes []map[string]any
+	handle := func(node map[string]any) bool {
+		parse.NormalizeNode(node)
+		if reason, _ := parse.ValidateNode(node); reason != "" {
+			return true
+		}
+		nodes = append(nodes, node)
+		return true
+	}
+	if _, err := parse.ParseSubscriptionDataStream(data, label, handle); err != nil {
+		for _, node := range parse.FallbackExtractV2Ray(data, label) {
+			handle(node)
+		}
+	}
+	if len(nodes) == 0 {
+		return nil, errors.New("未解析到有效节点")
+	}
+	return nodes, nil
+}
+
+// compileNameFilter 编译名称过滤正则，空字符串返回 nil
+func compileNameFilter(expr string) (*regexp.Regexp, error) {
+	if strings.TrimSpace(expr) == "" {
+		return nil, nil
+	}
+	return regexp.Compile(expr)
+}
+
+// countryKeywords 按节点名称识别地区，按顺序匹配。
+// 香港、台湾、澳门排在中国之前，印尼排在印度之前；两位代码区分大小写且不能紧邻其他字母
+var countryKeywords = func() []struct {
+	code string
+	re   *regexp.Regexp
+} {
+	// codes 首项为国家代码，其余为常见简写
+	table := []struct{ codes, words, names string }{
+		{"HK", "香港|深港|沪港|京港", "Hong ?Kong"},
+		{"TW", "台湾|台灣|台北|新北|彰化", "Taiwan|Taipei"},
+		{"MO", "澳门|澳門", "Macao|Macau"},
+		{"JP", "日本|东京|東京|大阪|埼玉", "Japan|Tokyo|Osaka"},
+		{"SG", "新加坡|狮城|獅城", "Singapore"},
+		{"KR", "韩国|韓國|首尔|首爾|春川", "Korea|Seoul"},
+		{"US|USA", "美国|美國|洛杉矶|硅谷|圣何塞|西雅图|纽约|芝加哥|达拉斯|凤凰城", "United States|America|Los Angeles|San Jose|Silicon Valley|Seattle|New York|Chicago|Dallas"},
+		{"GB|UK", "英国|英國|伦敦|倫敦", "United Kingdom|Britain|London"},
+		{"DE", "德国|德國|法兰克福", "Germany|Frankfurt"},
+		{"FR", "法国|法國|巴黎", "France|Paris"},
+		{"NL", "荷兰|荷蘭|阿姆斯特丹", "Netherlands|Amsterdam"},
+		{"RU", "俄罗斯|俄羅斯|莫斯科", "Russia|Moscow"},
+		{"CA", "加拿大|多伦多|温哥华", "Canada|Toronto|Vancouver"},
+		{"AU", "澳大利亚|澳洲|悉尼", "Australia|Sydney"},
+		{"ID", "印尼|印度尼西亚|雅加达", "Indonesia|Jakarta"},
+		{"IN", "印度|孟买", "India|Mumbai"},
+		{"TR", "土耳其|伊斯坦布尔", "Turkey|Istanbul"},
+		{"MY", "马来西亚|馬來西亞|吉隆坡", "Malaysia"},
+		{"TH", "泰国|泰國|曼谷", "Thailand|Bangkok"},
+		{"VN", "越南|胡志明", "Vietnam"},
+		{"PH", "菲律宾|菲律賓|马尼拉", "Philippines|Manila"},
+		{"BR", "巴西|圣保罗", "Brazil|Sao Paulo"},
+		{"AR", "阿根廷", "Argentina"},
+		{"CN", "中国|回国|北京|上海|广州|深圳", "China"},
+	}
+	out := make([]struct {
+		code string
+		re   *regexp.Regexp
+	}, len(table))
+	for i, t := range table {
+		out[i].code = t.codes[:2]
+		out[i].re = regexp.MustCompile(t.words + `|(?i:` + t.names + `)|(?:^|[^A-Za-z])(?:` + t.codes + `)(?:[^A-Za-z]|$)`)
+	}
+	return out
+}()
+
+// guessCountry 返回节点名称对应的两位国家代码，已带国旗时以国旗为准，无法识别时返回空
+func guessCountry(name string) string {
+	if hasFlagPrefix(name) {
+		r := []rune(name)
+		return string([]rune{r[0] - 0x1F1E6 + 'A', r[1] - 0x1F1E6 + 'A'})
+	}
+	for _, c := range countryKeywords {
+		if c.re.MatchString(name) {
+			return c.code
+		}
+	}
+	return ""
+}
+
+// hasFlagPrefix 名称是否以国旗 Emoji 开头
+func hasFlagPrefix(name string) bool {
+	r := []rune(name)
+	return len(r) >= 2 && isRegionalIndicator(r[0]) && isRegionalIndicator(r[1])
+}
+
+func isRegionalIndicator(r rune) bool {
+	return r >= 0x1F1E6 && r <= 0x1F1FF
+}
@@ -0,0 +1,88 @@
+package proxies
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+const convertFixture = `proxies:
+  - {name: "香港 01", type: ss, server: hk.example.com, port: 8388, cipher: aes-128-gcm, password: pwd}
+  - {name: "HK-dup", type: ss, server: hk.example.com, port: 8388, cipher: aes-128-gcm, password: pwd}
+  - {name: "Japan Tokyo", type: trojan, server: jp.example.com, port: 443, password: pwd}
+  - {name: "🇺🇸 US 01", type: trojan, server: us.example.com, port: 443, password: pwd}
+  - {name: "剩余流量：10GB", type: ss, server: info.example.com, port: 1, cipher: aes-128-gcm, password: pwd}
+  - {name: "broken", type: ss, server: "", port: 0}
+`
+
+func convertNames(t *testing.T, opts ConvertOptions) ([]string, ConvertStats) {
+	t.Helper()
+	nodes, stats, err := Convert(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := make([]string, len(nodes))
+	for i, n := range nodes {
+		names[i] = n["name"].(string)
+	}
+	return names, stats
+}
+
+func TestConvertFilterAndDedup(t *testing.T) {
+	names, stats := convertNames(t, ConvertOptions{Raw: []byte(convertFixture), Exclude: "剩余流量", Dedup: true})
+	if len(names) != 3 || stats.Parsed != 5 || stats.Filtered != 1 || stats.Deduped != 1 {
+		t.Fatalf("names %v, stats %+v", names, stats)
+	}
+
+	names, _ = convertNames(t, ConvertOptions{Raw: []byte(convertFixture), Include: "(?i)japan|香港"})
+	if len(names) != 2 || names[0] != "香港 01" || names[1] != "Japan Tokyo" {
+		t.Fatalf("include: %v", names)
+	}
+}
+
+func TestConvertEmojiAndRename(t *testing.T) {
+	names, _ := convertNames(t, ConvertOptions{Raw: []byte(convertFixture), Include: "01|Tokyo|dup", Emoji: true})
+	want := []string{"🇭🇰 香港 01", "🇭🇰 HK-dup", "🇯🇵 Japan Tokyo", "🇺🇸 US 01"}
+	if len(names) != len(want) {
+		t.Fatalf("got %v", names)
+	}
+	for i := range want {
+		if names[i] != want[i] {
+			t.Errorf("emoji[%d] = %q, want %q", i, names[i], want[i])
+		}
+	}
+
+	names, _ = convertNames(t, ConvertOptions{
+		Raw: []byte(convertFixture), Include: "01|Tokyo|dup", Dedup: true, Rename: "{flag}{country}_{index:2}[|{speed}]",
+	})
+	want = []string{"🇭🇰HK_01", "🇯🇵JP_01", "🇺🇸US_01"}
+	for i := range want {
+		if i >= len(names) || names[i] != want[i] {
+			t.Errorf("rename: got %v, want %v", names, want)
+			break
+		}
+	}
+}
+
+func TestConvertURLs(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing" {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write([]byte(convertFixture))
+	}))
+	defer srv.Close()
+
+	_, stats, err := Convert(ConvertOptions{URLs: []string{srv.URL + "/sub", srv.URL + "/missing"}, Dedup: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Output != 4 || len(stats.Failed) != 1 {
+		t.Fatalf("stats %+v", stats)
+	}
+
+	if _, _, err := Convert(ConvertOptions{Raw: []byte(convertFixture), Include: "("}); err == nil {
+		t.Error("invalid include regex should fail")
+	}
+}
@@ -0,0 +1,127 @@
+package proxies
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/goccy/go-yaml"
+	"github.com/sinspired/subs-check-pro/v2/proxy/parse"
+	"github.com/sinspired/subs-check-pro/v2/save/method"
+)
+
+const subDiagnosticsFile = "sub-diagnostics.yaml"
+
+var (
+	diagMu         sync.Mutex
+	subDiagnostics map[string]*parse.Diagnostics // key: 订阅 URL
+	diagLoaded     bool                          // 是否已从统计文件加载
+)
+
+// SourceID 订阅源的短标识，由 URL 计算，用于 /api/sources/:id 路由
+func SourceID(urlStr string) string {
+	sum := sha1.Sum([]byte(strings.TrimSpace(urlStr)))
+	return hex.EncodeToString(sum[:6])
+}
+
+// initDiagnostics 每轮拉取前清空上次的诊断
+func initDiagnostics() {
+	diagMu.Lock()
+	defer diagMu.Unlock()
+	subDiagnostics = make(map[string]*parse.Diagnostics)
+	diagLoaded = true
+}
+
+// recordDiagnostics 记录单个订阅的诊断结果
+func recordDiagnostics(d *parse.Diagnostics) {
+	d.ID = SourceID(d.URL)
+	diagMu.Lock()
+	defer diagMu.Unlock()
+	ensureDiagnosticsLoaded()
+	subDiagnostics[d.URL] = d
+}
+
+// ensureDiagnosticsLoaded 首次访问时读取上次保存的诊断，调用方需持有 diagMu
+func ensureDiagnosticsLoaded() {
+	if diagLoaded {
+		return
+	}
+	diagLoaded = true
+	subDiagnostics = make(map[string]*parse.Diagnostics)
+
+	saver, err := method.NewStatsSaver()
+	if err != nil {
+		return
+	}
+	data, err := os.ReadFile(filepath.Join(saver.StatsPath, subDiagnosticsFile))
+	if err != nil {
+		return
+	}
+	var list []*parse.Diagnostics
+	if err := yaml.Unmarshal(data, &list); err != nil {
+		return
+	}
+	for _, d := range list {
+		subDiagnostics[d.URL] = d
+	}
+}
+
+// Diagnostics 返回各订阅源的解析诊断，按 URL 排序
+func Diagnostics() []*parse.Diagnostics {
+	diagMu.Lock()
+	defer diagMu.Unlock()
+	ensureDiagnosticsLoaded()
+
+	out := make([]*parse.Diagnostics, 0, len(subDiagnostics))
+	for _, d := range subDiagnostics {
+		out = append(out, d)
+	}
+	slices.SortFunc(out, func(a, b *parse.Diagnostics) int { return strings.Compare(a.URL, b.URL) })
+	return out
+}
+
+// DiagnosticsOf 按订阅 URL 或 SourceID 查找诊断
+func DiagnosticsOf(key string) (*parse.Diagnostics, bool) {
+	diagMu.Lock()
+	defer diagMu.Unlock()
+	ensureDiagnosticsLoaded()
+
+	key = strings.TrimSpace(key)
+	if d, ok := subDiagnostics[key]; ok {
+		return d, true
+	}
+	for _, d := range subDiagnostics {
+		if d.ID == key {
+			return d, true
+		}
+	}
+	return nil, false
+}
+
+// validationTotals 本轮各订阅节点校验的剔除数与修复数
+func validationTotals() (rejected, repaired int) {
+	diagMu.Lock()
+	defer diagMu.Unlock()
+	for _, d := range subDiagnostics {
+		rejected += d.RejectedCount()
+		repaired += d.Repaired
+	}
+	return rejected, repaired
+}
+
+// saveDiagnostics 保存本轮诊断
+func saveDiagnostics() {
+	list := Diagnostics()
+	if len(list) == 0 {
+		return
+	}
+	data, err := yaml.Marshal(list)
+	if err != nil {
+		return
+	}
+	_ = method.SaveToStats(data, subDiagnosticsFile, "订阅解析诊断")
+}
@@ -0,0 +1,395 @@
+package proxies
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/goccy/go-yaml"
+	"github.com/sinspired/subs-check-pro/v2/config"
+	"github.com/sinspired/subs-check-pro/v2/proxy/parse"
+	"github.com/sinspired/subs-check-pro/v2/save/method"
+	"github.com/sinspired/subs-check-pro/v2/utils"
+)
+
+// 订阅发现：从 sub-discovery.urls 配置的聚合页出发，广度优先跟随页面中的链接，
+// 能解析出节点的链接记为订阅并加入本轮检测。发现结果保存在 stats/sub-discovered.yaml，
+// 可通过 /api/discovered 查看，确认后转为 sub-urls 中的固定订阅，或忽略。
+// 页面内容不可信：只跟随解析到公网地址的链接，请求时不附带本机鉴权信息，
+// 且不经系统代理，由 untrustedClient 直连并在连接时再次检查地址。
+
+const (
+	subDiscoveredFile = "sub-discovered.yaml"
+	// discoverPageFactor 单轮最多请求的页面数为 max-sources 的倍数，避免在无订阅的页面间无限游走
+	discoverPageFactor = 5
+	// discoverLookupTimeout 检查链接主机地址时的 DNS 超时
+	discoverLookupTimeout = 5 * time.Second
+)
+
+// DiscoveredSource 递归发现的订阅
+type DiscoveredSource struct {
+	URL          string    `yaml:"url" json:"url"`
+	From         string    `yaml:"from" json:"from"`   // 发现该订阅的页面
+	Depth        int       `yaml:"depth" json:"depth"` // 距入口页的层数
+	Nodes        int       `yaml:"nodes" json:"nodes"` // 最近一次发现时解析出的有效节点数
+	Dismissed    bool      `yaml:"dismissed,omitempty" json:"dismissed,omitempty"`
+	DiscoveredAt time.Time `yaml:"discovered-at" json:"discoveredAt"`
+	LastSeen     time.Time `yaml:"last-seen" json:"lastSeen"`
+}
+
+var discoveredMu sync.Mutex
+
+// discoverer 单轮发现的状态
+type discoverer struct {
+	cfg     config.SubDiscoveryConfig
+	fetch   func(string) ([]byte, error)
+	lookup  func(host string) ([]net.IP, error) // 为 nil 时使用系统 DNS
+	visited map[string]struct{}
+}
+
+// discoverSources 执行订阅发现，返回本轮加入检测的订阅，已忽略与已在 sub-urls 中的订阅不再加入
+func discoverSources() []config.SubSource {
+	cfg := config.GlobalConfig.SubDiscovery
+	if !cfg.Enable || len(cfg.URLs) == 0 {
+		return nil
+	}
+	slog.Info("从聚合页发现订阅", "入口", len(cfg.URLs), "最大层数", cfg.MaxDepth)
+
+	d := &discoverer{cfg: cfg, fetch: fetchUntrusted, visited: make(map[string]struct{})}
+	found := d.run(cfg.URLs)
+	list := mergeDiscovered(found)
+
+	fresh := make(map[string]struct{}, len(found))
+	for _, f := range found {
+		fresh[f.URL] = struct{}{}
+	}
+	var out []config.SubSource
+	for _, s := range list {
+		if _, ok := fresh[s.URL]; ok && !s.Dismissed {
+			out = append(out, config.SubSource{URL: s.URL, Untrusted: true})
+		}
+	}
+	slog.Info("订阅发现完成", "发现", len(found), "加入检测", len(out))
+	return out
+}
+
+// run 广度优先遍历入口页，返回能解析出节点的链接
+func (d *discoverer) run(entries []string) []DiscoveredSource {
+	type page struct {
+		url, from string
+		depth     int
+	}
+	maxSources := max(1, d.cfg.MaxSources)
+	maxPages := maxSources * discoverPageFactor
+
+	var queue []page
+	for _, e := range entries {
+		e = parse.NormalizeGitHubRawURL(parse.CleanURL(e))
+		if d.visit(e) {
+			queue = append(queue, page{url: e})
+		}
+	}
+
+	var found []DiscoveredSource
+	for fetched := 0; len(queue) > 0 && len(found) < maxSources && fetched < maxPages; fetched++ {
+		p := queue[0]
+		queue = queue[1:]
+
+		// 入口页由用户配置，其余链接来自第三方页面，只请求公网地址
+		if p.depth > 0 && !d.resolvesPublic(p.url) {
+			slog.Debug("订阅发现：跳过非公网地址", "URL", p.url)
+			continue
+		}
+		data, err := d.fetch(p.url)
+		if err != nil {
+			slog.Debug("订阅发现：页面获取失败", "URL", p.url, "error", err)
+			continue
+		}
+		if p.depth > 0 {
+			if n := countValidNodes(data, p.url); n > 0 {
+				now := time.Now()
+				found = append(found, DiscoveredSource{URL: p.url, From: p.from, Depth: p.depth, Nodes: n, DiscoveredAt: now, LastSeen: now})
+			}
+		}
+		if p.depth >= d.cfg.MaxDepth {
+			continue
+		}
+		for _, link := range parse.ExtractLinkedURLs(data) {
+			if d.allowed(link) && d.visit(link) {
+				queue = append(queue, page{url: link, from: p.url, depth: p.depth + 1})
+			}
+		}
+	}
+	return found
+}
+
+// visit 标记页面已访问，已访问过（忽略 fragment 与主机名大小写）时返回 false，用于防止循环
+func (d *discoverer) visit(rawURL string) bool {
+	key := rawURL
+	if u, err := url.Parse(rawURL); err == nil {
+		u.Fragment = ""
+		u.Host = strings.ToLower(u.Host)
+		key = u.String()
+	}
+	if _, ok := d.visited[key]; ok {
+		return false
+	}
+	d.visited[key] = struct{}{}
+	return true
+}
+
+// allowed 链接是否看起来像订阅、不指向本机或局域网，且域名符合允许与禁止列表
+func (d *discoverer) allowed(link string) bool {
+	if !parse.LooksLikeSubscriptionURL(link) || utils.IsLocalURL(link) {
+		return false
+	}
+	u, err := url.Parse(link)
+	if err != nil {
+		return false
+	}
+	host := strings.ToLower(u.Hostname())
+	if ip := net.ParseIP(host); ip != nil && !isPublicIP(ip) {
+		return false
+	}
+	if slices.ContainsFunc(d.cfg.DenyDomains, func(s string) bool { return domainMatch(host, s) }) {
+		return false
+	}
+	return len(d.cfg.AllowDomains) == 0 ||
+		slices.ContainsFunc(d.cfg.AllowDomains, func(s string) bool { return domainMatch(host, s) })
+}
+
+// resolvesPublic 链接主机的全部地址是否均为公网地址，解析失败视为否
+func (d *discoverer) resolvesPublic(rawURL string) bool {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return false
+	}
+	host := u.Hostname()
+	if ip := net.ParseIP(host); ip != nil {
+		return isPublicIP(ip)
+	}
+	lookup := d.lookup
+	if lookup == nil {
+		lookup = lookupHostIPs
+	}
+	ips, err := lookup(host)
+	if err != nil || len(ips) == 0 {
+		return false
+	}
+	for _, ip := range ips {
+		if !isPublicIP(ip) {
+			return false
+		}
+	}
+	return true
+}
+
+// lookupHostIPs 使用系统 DNS 解析主机地址
+func lookupHostIPs(host string) ([]net.IP, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), discoverLookupTimeout)
+	defer cancel()
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+	ips := make([]net.IP, 0, len(addrs))
+	for _, a := range addrs {
+		ips = append(ips, a.IP)
+	}
+	return ips, nil
+}
+
+// cgnatNet 运营商级 NAT 地址段 100.64.0.0/10
+var cgnatNet = &net.IPNet{IP: net.IPv4(100, 64, 0, 0), Mask: net.CIDRMask(10, 32)}
+
+// isPublicIP 是否为公网单播地址
+func isPublicIP(ip net.IP) bool {
+	return ip.IsGlobalUnicast() && !ip.IsPrivate() && !cgnatNet.Contains(ip)
+}
+
+// isPublicAddr 连接地址 ip:port 是否为公网地址
+func isPublicAddr(addr string) bool {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return false
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && isPublicIP(ip)
+}
+
+// errNonPublicAddr 第三方来源指向本机或局域网
+var errNonPublicAddr = errors.New("拒绝访问非公网地址")
+
+// untrustedClient 第三方来源专用的直连 Client
+var untrustedClient = sync.OnceValue(func() *http.Client { return newUntrustedClient(isPublicAddr) })
+
+// newUntrustedClient 建立连接时按实际连接的地址检查，DNS 重绑定与重定向到内网都会被拒绝；
+// 每一跳重定向另外检查协议与主机
+func newUntrustedClient(allow func(addr string) bool) *http.Client {
+	dialer := &net.Dialer{
+		Timeout: 30 * time.Second,
+		Control: func(_, address string, _ syscall.RawConn) error {
+			if !allow(address) {
+				return fmt.Errorf("%w: %s", errNonPublicAddr, address)
+			}
+			return nil
+		},
+	}
+	return &http.Client{
+		Transport: &http.Transport{
+			Proxy:               nil,
+			DialContext:         dialer.DialContext,
+			TLSHandshakeTimeout: 10 * time.Second,
+			MaxIdleConnsPerHost: 4,
+			IdleConnTimeout:     90 * time.Second,
+		},
+		Timeout: 60 * time.Second,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= 10 {
+				return errors.New("重定向次数过多")
+			}
+			if (req.URL.Scheme != "http" && req.URL.Scheme != "https") || utils.IsLocalURL(req.URL.String()) {
+				return fmt.Errorf("%w: %s", errNonPublicAddr, req.URL.Redacted())
+			}
+			return nil
+		},
+	}
+}
+
+// fetchUntrusted 获取第三方页面与发现的订阅，不附带本机鉴权信息
+func fetchUntrusted(rawURL string) ([]byte, error) {
+	return fetchSubsDataWith(rawURL, &config.SubSource{URL: rawURL, Untrusted: true}, nil, nil)
+}
+
+// domainMatch host 是否为 domain 本身或其子域名
+func domainMatch(host, domain string) bool {
+	domain = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(domain), "."))
+	return domain != "" && (host == domain || strings.HasSuffix(host, "."+domain))
+}
+
+// countValidNodes 统计内容中通过节点校验的节点数
+func countValidNodes(data []byte, label string) int {
+	n := 0
+	_, _ = parse.ParseSubscriptionDataStream(data, label, func(node map[string]any) bool {
+		parse.NormalizeNode(node)
+		if reason, _ := parse.ValidateNode(node); reason == "" {
+			n++
+		}
+		return true
+	})
+	return n
+}
+
+// loadDiscovered 读取发现记录，调用方需持有 discoveredMu
+func loadDiscovered() []DiscoveredSource {
+	saver, err := method.NewStatsSaver()
+	if err != nil {
+		return nil
+	}
+	data, err := os.ReadFile(filepath.Join(saver.StatsPath, subDiscoveredFile))
+	if err != nil {
+		return nil
+	}
+	var list []DiscoveredSource
+	if err := yaml.Unmarshal(data, &list); err != nil {
+		slog.Warn("读取订阅发现记录失败", "error", err)
+		return nil
+	}
+	return list
+}
+
+// storeDiscovered 保存发现记录，调用方需持有 discoveredMu
+func storeDiscovered(list []DiscoveredSource) error {
+	data, err := yaml.Marshal(list)
+	if err != nil {
+		return err
+	}
+	return method.SaveToStats(data, subDiscoveredFile, "订阅发现记录")
+}
+
+// mergeDiscovered 将本轮结果并入发现记录，去掉已在 sub-urls 中的订阅，返回合并后的列表
+func mergeDiscovered(found []DiscoveredSource) []DiscoveredSource {
+	discoveredMu.Lock()
+	defer discoveredMu.Unlock()
+
+	configured := make(map[string]struct{}, len(config.GlobalConfig.SubUrls))
+	for _, s := range config.GlobalConfig.SubUrls {
+		configured[strings.TrimSpace(s.URL)] = struct{}{}
+	}
+
+	list := slices.DeleteFunc(loadDiscovered(), func(s DiscoveredSource) bool {
+		_, ok := configured[s.URL]
+		return ok
+	})
+	index := make(map[string]int, len(list))
+	for i, s := range list {
+		index[s.URL] = i
+	}
+	for _, f := range found {
+		if _, ok := configured[f.URL]; ok {
+			continue
+		}
+		if i, ok := index[f.URL]; ok {
+			list[i].Nodes, list[i].LastSeen = f.Nodes, f.LastSeen
+			continue
+		}
+		index[f.URL] = len(list)
+		list = append(list, f)
+	}
+	if err := storeDiscovered(list); err != nil {
+		slog.Warn("保存订阅发现记录失败", "error", err)
+	}
+	return list
+}
+
+// DiscoveredSources 返回发现记录，all 为 false 时不含已忽略的订阅
+func DiscoveredSources(all bool) []DiscoveredSource {
+	discoveredMu.Lock()
+	defer discoveredMu.Unlock()
+	list := loadDiscovered()
+	if !all {
+		list = slices.DeleteFunc(list, func(s DiscoveredSource) bool { return s.Dismissed })
+	}
+	return list
+}
+
+// UpdateDiscovered 处理审阅结果：dismiss 为 true 时标记为忽略，否则从记录中移除（已转为固定订阅）。
+// 返回实际处理的数量
+func UpdateDiscovered(urls []string, dismiss bool) (int, error) {
+	discoveredMu.Lock()
+	defer discoveredMu.Unlock()
+
+	targets := make(map[string]struct{}, len(urls))
+	for _, u := range urls {
+		targets[strings.TrimSpace(u)] = struct{}{}
+	}
+	list := loadDiscovered()
+	n := 0
+	for i := range list {
+		if _, ok := targets[list[i].URL]; ok {
+			list[i].Dismissed = dismiss
+			n++
+		}
+	}
+	if !dismiss {
+		list = slices.DeleteFunc(list, func(s DiscoveredSource) bool {
+			_, ok := targets[s.URL]
+			return ok
+		})
+	}
+	if n == 0 {
+		return 0, nil
+	}
+	return n, storeDiscovered(list)
+}
@@ -0,0 +1,152 @@
+package proxies
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/sinspired/subs-check-pro/v2/config"
+)
+
+const discoverSub = `proxies:
+  - {name: a, type: trojan, server: a.example.com, port: 443, password: p}
+`
+
+func TestDiscovererRun(t *testing.T) {
+	pages := map[string]string{
+		"https://hub.example.com/": `# 每日更新
+[订阅一](https://sub.example.com/a.yaml)
+https://sub.example.com/b.yaml
+https://hub.example.com/more
+https://evil.example.net/c.yaml
+https://hub.example.com/logo.png
+http://127.0.0.1:8199/all.yaml
+http://192.168.1.1/sub
+http://intranet/sub
+https://rebind.example.com/sub`,
+		"https://hub.example.com/more":      "https://hub.example.com/ https://sub.example.com/deep.yaml",
+		"https://sub.example.com/a.yaml":    discoverSub,
+		"https://sub.example.com/b.yaml":    "not a subscription",
+		"https://sub.example.com/deep.yaml": discoverSub,
+		"https://evil.example.net/c.yaml":   discoverSub,
+	}
+	var fetched []string
+	fetch := func(u string) ([]byte, error) {
+		fetched = append(fetched, u)
+		if p, ok := pages[u]; ok {
+			return []byte(p), nil
+		}
+		return nil, errors.New("404")
+	}
+	lookup := func(host string) ([]net.IP, error) {
+		if host == "rebind.example.com" {
+			return []net.IP{net.ParseIP("203.0.113.9"), net.ParseIP("10.0.0.1")}, nil
+		}
+		return []net.IP{net.ParseIP("203.0.113.10")}, nil
+	}
+	newDiscoverer := func(cfg config.SubDiscoveryConfig) *discoverer {
+		fetched = nil
+		return &discoverer{cfg: cfg, fetch: fetch, lookup: lookup, visited: make(map[string]struct{})}
+	}
+
+	found := newDiscoverer(config.SubDiscoveryConfig{MaxDepth: 2, MaxSources: 10, DenyDomains: []string{"example.net"}}).run([]string{"https://hub.example.com/"})
+	if len(found) != 2 || found[0].URL != "https://sub.example.com/a.yaml" || found[1].URL != "https://sub.example.com/deep.yaml" {
+		t.Fatalf("found %+v", found)
+	}
+	if found[1].Depth != 2 || found[1].From != "https://hub.example.com/more" || found[0].Nodes != 1 {
+		t.Errorf("found %+v", found)
+	}
+	for _, u := range fetched {
+		switch u {
+		case "https://evil.example.net/c.yaml", "https://hub.example.com/logo.png",
+			"http://127.0.0.1:8199/all.yaml", "http://192.168.1.1/sub", "http://intranet/sub", "https://rebind.example.com/sub":
+			t.Errorf("should not fetch %s", u)
+		}
+	}
+	// 入口页被 /more 再次引用，不应重复请求
+	if len(fetched) != 5 {
+		t.Errorf("fetched %v", fetched)
+	}
+
+	found = newDiscoverer(config.SubDiscoveryConfig{MaxDepth: 1, MaxSources: 10, AllowDomains: []string{"sub.example.com"}}).run([]string{"https://hub.example.com/"})
+	if len(found) != 1 || found[0].URL != "https://sub.example.com/a.yaml" {
+		t.Errorf("allow + depth 1: %+v", found)
+	}
+
+	found = newDiscoverer(config.SubDiscoveryConfig{MaxDepth: 2, MaxSources: 1}).run([]string{"https://hub.example.com/"})
+	if len(found) != 1 {
+		t.Errorf("max sources: %+v", found)
+	}
+}
+
+func TestDomainMatch(t *testing.T) {
+	for _, c := range []struct {
+		host, domain string
+		want         bool
+	}{
+		{"example.com", "example.com", true},
+		{"a.example.com", ".example.com", true},
+		{"badexample.com", "example.com", false},
+		{"example.com", "", false},
+	} {
+		if got := domainMatch(c.host, c.domain); got != c.want {
+			t.Errorf("domainMatch(%q, %q) = %v", c.host, c.domain, got)
+		}
+	}
+}
+
+func TestIsLocalRequestNeedsLocalHost(t *testing.T) {
+	for raw, want := range map[string]bool{
+		"http://127.0.0.1:8199/all.yaml#Succeed": true,
+		"http://localhost:8199/history.yaml":     true,
+		"https://evil.example.com/all.yaml":      false,
+		"https://evil.example.com/history":       false,
+	} {
+		u, _ := url.Parse(raw)
+		if got := isLocalRequest(u); got != want {
+			t.Errorf("isLocalRequest(%s) = %v", raw, got)
+		}
+	}
+}
+
+func TestUntrustedClientRejectsPrivateTargets(t *testing.T) {
+	internal := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("internal server must not be reached")
+	}))
+	defer internal.Close()
+	// 模拟公网页面：重定向到本机地址
+	public := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/redirect" {
+			http.Redirect(w, r, internal.URL+"/secret", http.StatusFound)
+			return
+		}
+		_, _ = w.Write([]byte("ok"))
+	}))
+	defer public.Close()
+
+	publicAddr := public.Listener.Addr().String()
+	client := newUntrustedClient(func(addr string) bool { return addr == publicAddr })
+
+	resp, err := client.Get(public.URL + "/page")
+	if err != nil {
+		t.Fatalf("public page: %v", err)
+	}
+	resp.Body.Close()
+
+	if _, err := client.Get(public.URL + "/redirect"); !errors.Is(err, errNonPublicAddr) {
+		t.Errorf("redirect to loopback should be rejected, got %v", err)
+	}
+	// 解析结果在请求时才变为内网地址（DNS 重绑定）时由连接检查拦截
+	if _, err := client.Get(internal.URL); !errors.Is(err, errNonPublicAddr) {
+		t.Errorf("dial to non-public address should be rejected, got %v", err)
+	}
+
+	for addr, want := range map[string]bool{"1.1.1.1:443": true, "127.0.0.1:80": false, "10.0.0.1:80": false, "[::1]:80": false, "100.64.1.1:80": false} {
+		if got := isPublicAddr(addr); got != want {
+			t.Errorf("isPublicAddr(%s) = %v", addr, got)
+		}
+	}
+}
@@ -13,6 +13,12 @@
 //
 //   - proxies.go：主流程与并发调度（GetProxies、processSubscription、resolveSubUrls）
 //   - fetch.go：网络 I/O 层（FetchSubsData、fetchOnce、连接池管理）
+//   - github.go：github:// 仓库订阅源，列出仓库文件并逐个作为订阅拉取
+//   - localdir.go：sub-dirs 本地目录订阅源，目录下的文件以 file:// 地址参与拉取
+//   - discover.go：从聚合页递归发现订阅，记录在 sub-discovered.yaml 供审阅
+//   - diagnostics.go：各订阅的解析诊断记录与保存（sub-diagnostics.yaml）
+//   - health.go：订阅源健康评分，连续无效的订阅自动停用并按指数退避复查（sub-health.yaml）
+//   - convert.go：订阅转换（Convert），复用拉取与解析流程，不执行检测
 //   - info.go：获取代理地理位置信息
 //   - isp.go：获取代理地址的isp信息
 //   - rename.go：重命名代理节点
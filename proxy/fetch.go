@@ -3,6 +3,7 @@ package proxies
 import (
 	"context"
 	"crypto/tls"
+	"errors"
 	"fmt"
 	"io"
 	"log/slog"
@@ -44,8 +45,34 @@ var (
 // Key: proxyAddr (string), Value: *http.Client
 var clientMapCache sync.Map
 
+// httpStatusError 上游返回的错误状态码，Error 仅输出状态码以便 logFatal 按码分类
+type httpStatusError struct {
+	Code int
+}
+
+func (e *httpStatusError) Error() string { return strconv.Itoa(e.Code) }
+
 // FetchSubsData 获取数据 (包含重试、占位符处理、代理策略)
 func FetchSubsData(rawURL string) ([]byte, error) {
+	return fetchSubsDataWith(rawURL, nil, nil, nil)
+}
+
+// fetchSubsDataWith 按订阅源的独立选项获取数据，src 为 nil 时使用全局配置。
+// v 不为 nil 时发送条件请求，内容未变化返回 errNotModified。
+// rs 为本轮的中继节点，为 nil 时不使用中继。
+func fetchSubsDataWith(rawURL string, src *config.SubSource, v *cacheValidators, rs *relaySet) ([]byte, error) {
+	// 本地目录订阅源：只读取 sub-dirs 生成的订阅，其他来源（sub-urls、远程列表、订阅转换等）的 file:// 地址拒绝
+	if isFileURL(rawURL) {
+		if src == nil || !src.Local {
+			return nil, errors.New("不支持 file:// 订阅地址，本地文件请通过 sub-dirs 配置")
+		}
+		p, err := subDirFile(rawURL)
+		if err != nil {
+			return nil, err
+		}
+		return readLocalSub(p)
+	}
+
 	// 清洗 URL
 	rawURL = parse.CleanURL(rawURL)
 
@@ -58,6 +85,12 @@ func FetchSubsData(rawURL string) ([]byte, error) {
 	conf := config.GlobalConfig
 	maxRetries := max(1, conf.SubUrlsReTry)
 	timeout := max(10, conf.SubUrlsTimeout)
+	if src != nil && src.Retry > 0 {
+		maxRetries = src.Retry
+	}
+	if src != nil && src.Timeout > 0 {
+		timeout = src.Timeout
+	}
 
 	// 处理为标准的GitHub raw地址
 	rawURL = parse.NormalizeGitHubRawURL(rawURL)
@@ -76,9 +109,26 @@ func FetchSubsData(rawURL string) ([]byte, error) {
 	warpFunc := func(s string) string { return utils.WarpURL(parse.EnsureScheme(s), true) }
 	originFunc := parse.EnsureScheme
 
-	if utils.IsLocalURL(rawURL) {
+	proxyMode := ""
+	if src != nil {
+		proxyMode = strings.ToLower(strings.TrimSpace(src.Proxy))
+	}
+
+	switch {
+	case src != nil && src.Untrusted:
+		// 第三方来源不经系统代理（代理侧的连接无法检查目标地址），仅 Github 代理与直连
+		if utils.IsGhProxyAvailable {
+			strategies = append(strategies, strategy{false, warpFunc})
+		}
+		strategies = append(strategies, strategy{false, originFunc})
+	case utils.IsLocalURL(rawURL):
 		strategies = append(strategies, strategy{false, warpFunc})
-	} else {
+	case proxyMode == "direct":
+		strategies = append(strategies, strategy{false, originFunc})
+	case proxyMode != "":
+		// system 或自定义代理地址，仅走代理
+		strategies = append(strategies, strategy{true, originFunc})
+	default:
 		// 1. 系统代理 (External utils)
 		if utils.IsSysProxyAvailable {
 			strategies = append(strategies, strategy{true, originFunc})
@@ -108,6 +158,11 @@ func FetchSubsData(rawURL string) ([]byte, error) {
 		}
 	}
 
+	// 订阅源指定 UA 时不再轮换
+	if src != nil && src.UserAgent != "" {
+		uaList = []string{src.UserAgent}
+	}
+
 	for i := range maxRetries + 1 {
 		ua := uaList[i%len(uaList)]
 		if i > 0 {
@@ -130,9 +185,9 @@ func FetchSubsData(rawURL string) ([]byte, error) {
 				// 保持 Debug，过于频繁的尝试详情不需要 Info
 				slog.Debug("尝试下载", "Target", targetURL, "Proxy", strat.useProxy)
 
-				body, err, fatal := fetchOnce(targetURL, strat.useProxy, timeout, ua)
-				if err == nil {
-					return body, nil
+				body, err, fatal := fetchOnce(targetURL, strat.useProxy, timeout, ua, src, v)
+				if err == nil || errors.Is(err, errNotModified) {
+					return body, err
 				}
 				lastErr = err
 
@@ -141,9 +196,9 @@ func FetchSubsData(rawURL string) ([]byte, error) {
 				}
 
 				// 401/403 时给一个提示，方便调试
-				if code, convErr := strconv.Atoi(err.Error()); convErr == nil &&
-					(code == 401 || code == 403) && strat.useProxy {
-					slog.Debug("代理访问被拒，尝试下一策略", "URL", targetURL, "status", code)
+				var se *httpStatusError
+				if errors.As(err, &se) && (se.Code == 401 || se.Code == 403) && strat.useProxy {
+					slog.Debug("代理访问被拒，尝试下一策略", "URL", targetURL, "status", se.Code)
 				}
 			}
 		}
@@ -152,6 +207,16 @@ func FetchSubsData(rawURL string) ([]byte, error) {
 		}
 	}
 
+	// 直连与代理均失败时，通过上次检测成功的节点再试一次
+	if rs != nil && proxyMode == "" && !utils.IsLocalURL(rawURL) && relayWorthy(lastErr) {
+		for _, candidate := range candidates {
+			body, err := rs.fetch(originFunc(candidate), timeout, uaList[0], src, v)
+			if err == nil || errors.Is(err, errNotModified) {
+				return body, err
+			}
+		}
+	}
+
 	return nil, fmt.Errorf("%d次重试后失败: %v", maxRetries, lastErr)
 }
 
@@ -193,18 +258,29 @@ func getClient(proxyAddr string) *http.Client {
 }
 
 // fetchOnce 执行单次 HTTP 请求 (使用连接池)
-func fetchOnce(target string, useProxy bool, timeoutSec int, ua string) ([]byte, error, bool) {
+func fetchOnce(target string, useProxy bool, timeoutSec int, ua string, src *config.SubSource, v *cacheValidators) ([]byte, error, bool) {
 	// 1. 确定 Client Key
 	proxyKey := "direct"
 	if useProxy {
 		if p := config.GlobalConfig.SystemProxy; p != "" {
 			proxyKey = p // 使用代理地址作为 Key
 		}
+		// 订阅源自定义代理
+		if src != nil && src.Proxy != "" && !strings.EqualFold(src.Proxy, "system") {
+			proxyKey = src.Proxy
+		}
 	}
 
-	// 2. 获取复用的 Client
+	// 2. 获取复用的 Client，第三方来源使用连接时检查地址的 Client
 	client := getClient(proxyKey)
+	if src != nil && src.Untrusted && !useProxy {
+		client = untrustedClient()
+	}
+	return fetchWith(client, target, useProxy, timeoutSec, ua, src, v)
+}
 
+// fetchWith 使用指定 Client 执行单次请求，返回内容、错误以及是否无需再尝试其他策略
+func fetchWith(client *http.Client, target string, useProxy bool, timeoutSec int, ua string, src *config.SubSource, v *cacheValidators) ([]byte, error, bool) {
 	// 3. 创建带超时的连接
 	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(timeoutSec)*time.Second)
 
@@ -228,8 +304,21 @@ func fetchOnce(target string, useProxy bool, timeoutSec int, ua string) ([]byte,
 		}
 	}
 
+	// 4.1.1 订阅源自定义 Header 与认证，优先于上面的默认值
+	applySourceHeaders(req, src)
+
+	// 4.1.2 条件请求
+	if v != nil {
+		if v.ETag != "" {
+			req.Header.Set("If-None-Match", v.ETag)
+		}
+		if v.LastModified != "" {
+			req.Header.Set("If-Modified-Since", v.LastModified)
+		}
+	}
+
 	// 4.2 处理本地请求特殊 Header
-	if isLocalRequest(req.URL) {
+	if isLocalRequest(req.URL) && (src == nil || !src.Untrusted) {
 		req.Header.Set("X-From-Subs-Check-pro", "true")
 		req.Header.Set("X-API-Key", config.GlobalConfig.APIKey)
 		q := req.URL.Query()
@@ -244,13 +333,18 @@ func fetchOnce(target string, useProxy bool, timeoutSec int, ua string) ([]byte,
 	}
 	defer resp.Body.Close()
 
+	if resp.StatusCode == http.StatusNotModified && v != nil {
+		recordQuota(src, resp.Header.Get("subscription-userinfo"))
+		return nil, errNotModified, true
+	}
+
 	if resp.StatusCode >= 400 {
 		// 读取128KB，超过的放弃连接复用
 		_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 128*1024))
 		slog.Debug("错误", "url", req.URL, "代理", useProxy, "状态码", resp.StatusCode, "UA", req.UserAgent())
 		// 401/403 仅是"访问受阻"，不阻断后续策略
 		fatal := resp.StatusCode == 404 || resp.StatusCode == 410
-		return nil, fmt.Errorf("%d", resp.StatusCode), fatal
+		return nil, &httpStatusError{Code: resp.StatusCode}, fatal
 	}
 
 	// 限制最大读取 100MB
@@ -266,6 +360,13 @@ func fetchOnce(target string, useProxy bool, timeoutSec int, ua string) ([]byte,
 		return nil, err, false
 	}
 
+	// 记录上游订阅的流量与到期信息
+	recordQuota(src, resp.Header.Get("subscription-userinfo"))
+
+	if v != nil {
+		v.ETag, v.LastModified = resp.Header.Get("ETag"), resp.Header.Get("Last-Modified")
+	}
+
 	if len(body) >= MaxLimit {
 		return nil, fmt.Errorf("订阅文件超过 50MB 限制"), true
 	}
@@ -306,7 +407,7 @@ func replaceDatePlaceholders(s string, t time.Time) string {
 }
 
 func isLocalRequest(u *url.URL) bool {
-	return utils.IsLocalURL(u.Hostname()) &&
+	return utils.IsLocalURL(u.String()) &&
 		(strings.Contains(u.Fragment, "Keep") || strings.Contains(u.Path, "history") || strings.Contains(u.Path, "all"))
 }
 
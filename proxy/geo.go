@@ -0,0 +1,52 @@
+package proxies
+
+import (
+	"net/netip"
+
+	"github.com/oschwald/maxminddb-golang/v2"
+)
+
+// GeoDetail 出口 IP 的城市与 ASN 信息
+type GeoDetail struct {
+	City  string
+	ASN   uint
+	ASOrg string
+}
+
+type cityRecord struct {
+	City struct {
+		Names map[string]string `maxminddb:"names"`
+	} `maxminddb:"city"`
+}
+
+type asnRecord struct {
+	Number uint   `maxminddb:"autonomous_system_number"`
+	Org    string `maxminddb:"autonomous_system_organization"`
+}
+
+// LookupGeoDetail 从城市库与 ASN 库查询 IP，数据库为 nil 时跳过对应字段
+func LookupGeoDetail(cityDB, asnDB *maxminddb.Reader, ip string) GeoDetail {
+	var d GeoDetail
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return d
+	}
+	addr = addr.Unmap()
+
+	if cityDB != nil {
+		var rec cityRecord
+		if err := cityDB.Lookup(addr).Decode(&rec); err == nil {
+			d.City = rec.City.Names["en"]
+			if d.City == "" {
+				d.City = rec.City.Names["zh-CN"]
+			}
+		}
+	}
+	if asnDB != nil {
+		var rec asnRecord
+		if err := asnDB.Lookup(addr).Decode(&rec); err == nil {
+			d.ASN, d.ASOrg = rec.Number, rec.Org
+		}
+	}
+	return d
+}
@@ -0,0 +1,151 @@
+package proxies
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"path"
+	"strings"
+
+	"github.com/goccy/go-json"
+	"github.com/sinspired/subs-check-pro/v2/config"
+)
+
+// GitHub 仓库订阅源：github://owner/repo/path@ref
+//
+// 通过 trees API 列出 path 下的全部文件，每个文件作为一个订阅拉取。
+// path 的最后一段可以是 glob（如 sub/*.yaml），不含 glob 时匹配 path 下所有文件（含子目录）。
+// 启用订阅缓存时按 blob SHA 判断文件是否变化，未变化的文件直接复用缓存节点，不再下载。
+
+const githubScheme = "github://"
+
+// githubBlobs 本轮展开出的 raw 地址对应的 blob SHA，由 resolveSubUrls 构建，之后只读
+var githubBlobs map[string]string
+
+// githubRepoSource 解析后的仓库订阅源
+type githubRepoSource struct {
+	Owner, Repo string
+	Dir         string // 列出文件的目录，空表示仓库根目录
+	Pattern     string // 文件名 glob，空表示不过滤
+	Ref         string
+	Remark      string // #备注，透传给展开出的订阅
+}
+
+// githubTree trees API 返回结构
+type githubTree struct {
+	Tree []struct {
+		Path string `json:"path"`
+		Type string `json:"type"`
+		SHA  string `json:"sha"`
+	} `json:"tree"`
+	Truncated bool `json:"truncated"`
+}
+
+// isGitHubSource 是否为 github:// 仓库订阅源
+func isGitHubSource(urlStr string) bool {
+	return strings.HasPrefix(strings.ToLower(urlStr), githubScheme)
+}
+
+// parseGitHubSource 解析 github://owner/repo/path@ref#备注，ref 默认为 HEAD
+func parseGitHubSource(raw string) (*githubRepoSource, error) {
+	rest := raw[len(githubScheme):]
+	rest, remark, _ := strings.Cut(rest, "#")
+	rest, ref, _ := strings.Cut(rest, "@")
+
+	parts := strings.SplitN(strings.Trim(rest, "/"), "/", 3)
+	if len(parts) < 2 || parts[0] == "" || parts[1] == "" {
+		return nil, fmt.Errorf("无效的 GitHub 订阅源: %s", raw)
+	}
+	s := &githubRepoSource{Owner: parts[0], Repo: parts[1], Ref: cmp.Or(ref, "HEAD"), Remark: remark}
+	if len(parts) == 3 {
+		s.Dir = strings.Trim(parts[2], "/")
+		if base := path.Base(s.Dir); strings.ContainsAny(base, "*?[") {
+			s.Pattern = base
+			s.Dir = strings.TrimSuffix(strings.TrimSuffix(s.Dir, base), "/")
+		}
+	}
+	if s.Pattern != "" {
+		if _, err := path.Match(s.Pattern, ""); err != nil {
+			return nil, fmt.Errorf("无效的文件匹配规则 %q: %w", s.Pattern, err)
+		}
+	}
+	return s, nil
+}
+
+// match 文件是否位于目录下且文件名匹配 glob
+func (s *githubRepoSource) match(file string) bool {
+	if s.Dir != "" && !strings.HasPrefix(file, s.Dir+"/") {
+		return false
+	}
+	if s.Pattern == "" {
+		return true
+	}
+	ok, _ := path.Match(s.Pattern, path.Base(file))
+	return ok
+}
+
+// treeURL trees API 地址，配置了 github-api-mirror 时使用镜像
+func (s *githubRepoSource) treeURL() string {
+	base := strings.TrimSuffix(cmp.Or(config.GlobalConfig.GithubAPIMirror, "https://api.github.com"), "/")
+	return fmt.Sprintf("%s/repos/%s/%s/git/trees/%s?recursive=1", base, s.Owner, s.Repo, url.PathEscape(s.Ref))
+}
+
+// rawURL 文件的 raw 地址，ghproxy 加速与 github-token 由 FetchSubsData 统一处理
+func (s *githubRepoSource) rawURL(file string) string {
+	escaped := make([]string, 0, 4)
+	for seg := range strings.SplitSeq(file, "/") {
+		escaped = append(escaped, url.PathEscape(seg))
+	}
+	u := fmt.Sprintf("https://raw.githubusercontent.com/%s/%s/%s/%s", s.Owner, s.Repo, s.Ref, strings.Join(escaped, "/"))
+	if s.Remark != "" {
+		u += "#" + s.Remark
+	}
+	return u
+}
+
+// expandGitHubSource 列出仓库中匹配的文件，每个文件生成一个继承原选项的订阅源
+func expandGitHubSource(src config.SubSource) ([]config.SubSource, error) {
+	repo, err := parseGitHubSource(src.URL)
+	if err != nil {
+		return nil, err
+	}
+	data, err := FetchSubsData(repo.treeURL())
+	if err != nil {
+		return nil, err
+	}
+	var tree githubTree
+	if err := json.Unmarshal(data, &tree); err != nil {
+		return nil, fmt.Errorf("解析 GitHub 文件列表失败: %w", err)
+	}
+	if tree.Truncated {
+		slog.Warn("GitHub 仓库文件过多，列表已被截断", "订阅", src.URL)
+	}
+
+	var out []config.SubSource
+	for _, entry := range tree.Tree {
+		if entry.Type != "blob" || !repo.match(entry.Path) {
+			continue
+		}
+		sub := src
+		sub.URL = repo.rawURL(entry.Path)
+		if src.Name != "" {
+			sub.Name = src.Name + "/" + path.Base(entry.Path)
+		}
+		if githubBlobs != nil {
+			githubBlobs[sub.URL] = entry.SHA
+		}
+		out = append(out, sub)
+	}
+	if len(out) == 0 {
+		return nil, errors.New("仓库中没有匹配的文件")
+	}
+	slog.Debug("展开 GitHub 仓库订阅", "订阅", src.URL, "文件数", len(out))
+	return out, nil
+}
+
+// githubBlobSHA 返回订阅对应的 blob SHA，非仓库展开的订阅返回空
+func githubBlobSHA(urlStr string) string {
+	return githubBlobs[urlStr]
+}
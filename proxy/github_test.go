@@ -0,0 +1,71 @@
+package proxies
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/sinspired/subs-check-pro/v2/config"
+)
+
+func TestParseGitHubSource(t *testing.T) {
+	s, err := parseGitHubSource("github://owner/repo/sub/*.yaml@dev#备注")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Owner != "owner" || s.Repo != "repo" || s.Dir != "sub" || s.Pattern != "*.yaml" || s.Ref != "dev" || s.Remark != "备注" {
+		t.Fatalf("got %+v", s)
+	}
+	for file, want := range map[string]bool{"sub/a.yaml": true, "sub/x/b.yaml": true, "sub/a.txt": false, "other/a.yaml": false} {
+		if s.match(file) != want {
+			t.Errorf("match(%q) = %v", file, !want)
+		}
+	}
+	if got := s.rawURL("sub/a b.yaml"); got != "https://raw.githubusercontent.com/owner/repo/dev/sub/a%20b.yaml#备注" {
+		t.Errorf("rawURL = %q", got)
+	}
+
+	s, err = parseGitHubSource("github://owner/repo")
+	if err != nil || s.Ref != "HEAD" || s.Dir != "" || !s.match("any/file.txt") {
+		t.Fatalf("root: %+v, %v", s, err)
+	}
+	if _, err := parseGitHubSource("github://owner"); err == nil {
+		t.Error("missing repo should fail")
+	}
+}
+
+func TestExpandGitHubSource(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/repos/owner/repo/git/trees/main" {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write([]byte(`{"tree": [
+			{"path": "sub", "type": "tree", "sha": "t1"},
+			{"path": "sub/a.txt", "type": "blob", "sha": "b1"},
+			{"path": "sub/b.yaml", "type": "blob", "sha": "b2"},
+			{"path": "README.md", "type": "blob", "sha": "b3"}
+		], "truncated": false}`))
+	}))
+	defer srv.Close()
+
+	old := config.GlobalConfig.GithubAPIMirror
+	config.GlobalConfig.GithubAPIMirror = srv.URL
+	githubBlobs = make(map[string]string)
+	defer func() { config.GlobalConfig.GithubAPIMirror, githubBlobs = old, nil }()
+
+	subs, err := expandGitHubSource(config.SubSource{URL: "github://owner/repo/sub@main", Name: "repo", Tags: []string{"gh"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(subs) != 2 || subs[0].Name != "repo/a.txt" || subs[1].Tags[0] != "gh" {
+		t.Fatalf("got %+v", subs)
+	}
+	if githubBlobSHA(subs[1].URL) != "b2" {
+		t.Errorf("blobs = %v", githubBlobs)
+	}
+
+	if _, err := expandGitHubSource(config.SubSource{URL: "github://owner/repo/sub/*.json@main"}); err == nil {
+		t.Error("no matching files should fail")
+	}
+}
@@ -0,0 +1,357 @@
+package proxies
+
+import (
+	"cmp"
+	"fmt"
+	"log/slog"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/goccy/go-yaml"
+	"github.com/sinspired/subs-check-pro/v2/config"
+	"github.com/sinspired/subs-check-pro/v2/save/method"
+)
+
+// 订阅源健康记录：每轮检测后按拉取结果、入队节点数、可用节点数与独有节点数为订阅源打分，
+// 连续 dead-runs 轮无效的订阅自动停用，之后按指数退避的间隔复查，复查有效即恢复。
+// 记录保存在 stats/sub-health.yaml，可通过 /api/sources/health 查看，/api/sources/:id/enable 手动恢复。
+
+const (
+	subHealthFile = "sub-health.yaml"
+	// healthScoreDecay 历史得分的权重，本轮得分占 1-healthScoreDecay
+	healthScoreDecay = 0.7
+)
+
+// SourceHealth 单个订阅源的健康记录
+type SourceHealth struct {
+	ID          string    `yaml:"-" json:"id"`
+	URL         string    `yaml:"url" json:"url"`
+	Name        string    `yaml:"name,omitempty" json:"name,omitempty"`
+	Runs        int       `yaml:"runs" json:"runs"`              // 参与检测的轮数
+	FetchFails  int       `yaml:"fetch-fails" json:"fetchFails"` // 拉取失败的轮数
+	Nodes       int       `yaml:"nodes" json:"nodes"`            // 最近一轮入队的节点数
+	Alive       int       `yaml:"alive" json:"alive"`            // 最近一轮可用的节点数
+	Unique      int       `yaml:"unique" json:"unique"`          // 最近一轮没有其他订阅提供的节点数
+	Score       float64   `yaml:"score" json:"score"`            // 0-100，历次得分的指数平均
+	BadStreak   int       `yaml:"bad-streak" json:"badStreak"`   // 连续无效的轮数
+	LastReason  string    `yaml:"last-reason,omitempty" json:"lastReason,omitempty"`
+	Disabled    bool      `yaml:"disabled,omitempty" json:"disabled,omitempty"`
+	DisabledAt  time.Time `yaml:"disabled-at,omitempty" json:"disabledAt,omitempty"`
+	Backoff     int       `yaml:"backoff,omitempty" json:"backoff,omitempty"`    // 当前复查间隔（轮）
+	SkipLeft    int       `yaml:"skip-left,omitempty" json:"skipLeft,omitempty"` // 距下次复查还需跳过的轮数
+	LastChecked time.Time `yaml:"last-checked" json:"lastChecked"`
+}
+
+// AliveRatio 最近一轮的可用率
+func (h SourceHealth) AliveRatio() float64 {
+	if h.Nodes == 0 {
+		return 0
+	}
+	return float64(h.Alive) / float64(h.Nodes)
+}
+
+var (
+	healthMu      sync.Mutex
+	subHealth     map[string]*SourceHealth // key: 订阅 URL
+	healthLoaded  bool                     // 是否已从统计文件加载
+	healthSkipped map[string]struct{}      // 本轮因停用而跳过的订阅
+	healthUnique  map[string]int           // 本轮各订阅的独有节点数
+	healthPartial bool                     // 本轮只检测了部分订阅（目录监听），不清理记录
+	healthAlerts  []string                 // 本轮停用与恢复的提示
+)
+
+// ensureHealthLoaded 首次访问时读取上次保存的记录，调用方需持有 healthMu
+func ensureHealthLoaded() {
+	if healthLoaded {
+		return
+	}
+	healthLoaded = true
+	subHealth = make(map[string]*SourceHealth)
+
+	saver, err := method.NewStatsSaver()
+	if err != nil {
+		return
+	}
+	data, err := os.ReadFile(filepath.Join(saver.StatsPath, subHealthFile))
+	if err != nil {
+		return
+	}
+	var list []*SourceHealth
+	if err := yaml.Unmarshal(data, &list); err != nil {
+		slog.Warn("读取订阅健康记录失败", "error", err)
+		return
+	}
+	for _, h := range list {
+		subHealth[h.URL] = h
+	}
+}
+
+// beginHealthRound 每轮拉取订阅前重置本轮状态
+func beginHealthRound(partial bool) {
+	healthMu.Lock()
+	defer healthMu.Unlock()
+	healthSkipped = make(map[string]struct{})
+	healthUnique = nil
+	healthPartial = partial
+	healthAlerts = nil
+}
+
+// skipUnhealthy 已停用且未到复查轮次的订阅返回 true，同一轮内多次出现只计一次
+func skipUnhealthy(urlStr string) bool {
+	if !config.GlobalConfig.SourceHealth.Enable {
+		return false
+	}
+	healthMu.Lock()
+	defer healthMu.Unlock()
+	ensureHealthLoaded()
+
+	h, ok := subHealth[urlStr]
+	if !ok || !h.Disabled {
+		return false
+	}
+	if _, ok := healthSkipped[urlStr]; ok {
+		return true
+	}
+	if h.SkipLeft <= 0 {
+		slog.Info("复查已停用的订阅", "订阅", urlStr, "间隔", h.Backoff)
+		return false
+	}
+	h.SkipLeft--
+	if healthSkipped != nil {
+		healthSkipped[urlStr] = struct{}{}
+	}
+	return true
+}
+
+// recordUniqueNodes 记录本轮去重后各订阅的独有节点数
+func recordUniqueNodes(unique map[string]int) {
+	healthMu.Lock()
+	defer healthMu.Unlock()
+	healthUnique = unique
+}
+
+// healthSample 单轮结果
+type healthSample struct {
+	fetchErr           string
+	nodes, alive, uniq int
+}
+
+// bad 本轮是否无效，返回原因
+func (s healthSample) bad(minAliveRatio float64) (string, bool) {
+	switch {
+	case s.fetchErr != "":
+		return "拉取失败", true
+	case s.nodes == 0:
+		return "无节点", true
+	case s.alive == 0:
+		return "无可用节点", true
+	case float64(s.alive)/float64(s.nodes) < minAliveRatio:
+		return fmt.Sprintf("可用率 %.2f%% 低于 %.2f%%",
+			float64(s.alive)/float64(s.nodes)*100, minAliveRatio*100), true
+	}
+	return "", false
+}
+
+// score 本轮得分：拉取成功 30，可用率与独有率各占 40 与 30
+func (s healthSample) score() float64 {
+	if s.fetchErr != "" {
+		return 0
+	}
+	v := 30.0
+	if s.nodes > 0 {
+		v += 40*float64(s.alive)/float64(s.nodes) + 30*float64(s.uniq)/float64(s.nodes)
+	}
+	return v
+}
+
+// apply 将本轮结果计入记录，状态变化时返回提示
+func (h *SourceHealth) apply(s healthSample, cfg config.SourceHealthConfig, now time.Time) string {
+	if h.Runs == 0 {
+		h.Score = s.score()
+	} else {
+		h.Score = healthScoreDecay*h.Score + (1-healthScoreDecay)*s.score()
+	}
+	h.Score = math.Round(h.Score*10) / 10
+	h.Runs++
+	if s.fetchErr != "" {
+		h.FetchFails++
+	}
+	h.Nodes, h.Alive, h.Unique = s.nodes, s.alive, s.uniq
+	h.LastChecked = now
+
+	reason, bad := s.bad(cfg.MinAliveRatio)
+	label := cmp.Or(h.Name, h.URL)
+	if !bad {
+		h.BadStreak, h.LastReason = 0, ""
+		if h.Disabled {
+			h.Disabled, h.DisabledAt, h.Backoff, h.SkipLeft = false, time.Time{}, 0, 0
+			return label + " 复查有效，已恢复"
+		}
+		return ""
+	}
+
+	h.BadStreak++
+	h.LastReason = reason
+	if !cfg.Enable {
+		return ""
+	}
+	if h.Disabled {
+		// 复查仍无效，间隔翻倍
+		h.Backoff = min(max(1, h.Backoff*2), max(1, cfg.MaxRecheckRuns))
+		h.SkipLeft = h.Backoff
+		return ""
+	}
+	if h.BadStreak >= max(1, cfg.DeadRuns) {
+		h.Disabled, h.DisabledAt = true, now
+		h.Backoff = max(1, cfg.RecheckRuns)
+		h.SkipLeft = h.Backoff
+		return fmt.Sprintf("%s 连续 %d 轮无效（%s），已停用，%d 轮后复查", label, h.BadStreak, reason, h.Backoff)
+	}
+	return ""
+}
+
+// UpdateSourceHealth 按本轮检测结果更新订阅源健康记录，alive 为各订阅的可用节点数（多个订阅提供的节点计入每个来源）。
+// 拉取与入队数据来自本轮的解析诊断，未产生诊断的订阅（如被忽略的错误）不计入
+func UpdateSourceHealth(alive map[string]int) {
+	cfg := config.GlobalConfig.SourceHealth
+	now := time.Now()
+
+	samples := make(map[string]healthSample)
+	diagMu.Lock()
+	for u, d := range subDiagnostics {
+		if _, ok := subSources[u]; ok {
+			samples[u] = healthSample{fetchErr: d.Error, nodes: d.Accepted}
+		}
+	}
+	diagMu.Unlock()
+
+	healthMu.Lock()
+	ensureHealthLoaded()
+	for u, s := range samples {
+		s.alive, s.uniq = alive[u], healthUnique[u]
+		h, ok := subHealth[u]
+		if !ok {
+			h = &SourceHealth{URL: u}
+			subHealth[u] = h
+		}
+		if src := sourceOf(u); src != nil && src.Name != "" {
+			h.Name = src.Name
+		}
+		if msg := h.apply(s, cfg, now); msg != "" {
+			healthAlerts = append(healthAlerts, msg)
+			slog.Warn("订阅健康状态变化", "info", msg)
+		}
+	}
+	if !healthPartial {
+		for u := range subHealth {
+			_, inRound := subSources[u]
+			_, skipped := healthSkipped[u]
+			if !inRound && !skipped {
+				delete(subHealth, u)
+			}
+		}
+	}
+	disabled := 0
+	for _, h := range subHealth {
+		if h.Disabled {
+			disabled++
+		}
+	}
+	skipped := len(healthSkipped)
+	healthMu.Unlock()
+
+	if disabled > 0 {
+		slog.Info("订阅健康", "已停用", disabled, "本轮跳过", skipped)
+	}
+	if err := saveSourceHealth(); err != nil {
+		slog.Warn("保存订阅健康记录失败", "error", err)
+	}
+}
+
+// saveSourceHealth 保存健康记录
+func saveSourceHealth() error {
+	list := SourceHealthList()
+	if len(list) == 0 {
+		return nil
+	}
+	data, err := yaml.Marshal(list)
+	if err != nil {
+		return err
+	}
+	return method.SaveToStats(data, subHealthFile, "订阅健康记录")
+}
+
+// SourceHealthList 返回健康记录，已停用的在前，其余按得分升序
+func SourceHealthList() []SourceHealth {
+	healthMu.Lock()
+	defer healthMu.Unlock()
+	ensureHealthLoaded()
+
+	out := make([]SourceHealth, 0, len(subHealth))
+	for _, h := range subHealth {
+		c := *h
+		c.ID = SourceID(c.URL)
+		out = append(out, c)
+	}
+	slices.SortFunc(out, func(a, b SourceHealth) int {
+		if a.Disabled != b.Disabled {
+			if a.Disabled {
+				return -1
+			}
+			return 1
+		}
+		return cmp.Or(cmp.Compare(a.Score, b.Score), strings.Compare(a.URL, b.URL))
+	})
+	return out
+}
+
+// SourceHealthOf 按订阅 URL 返回健康记录
+func SourceHealthOf(urlStr string) (SourceHealth, bool) {
+	healthMu.Lock()
+	defer healthMu.Unlock()
+	ensureHealthLoaded()
+	h, ok := subHealth[strings.TrimSpace(urlStr)]
+	if !ok {
+		return SourceHealth{}, false
+	}
+	c := *h
+	c.ID = SourceID(c.URL)
+	return c, true
+}
+
+// EnableSource 手动恢复停用的订阅，key 为订阅 URL 或 SourceID
+func EnableSource(key string) (SourceHealth, bool, error) {
+	healthMu.Lock()
+	ensureHealthLoaded()
+	key = strings.TrimSpace(key)
+	h, ok := subHealth[key]
+	if !ok {
+		for _, v := range subHealth {
+			if SourceID(v.URL) == key {
+				h, ok = v, true
+				break
+			}
+		}
+	}
+	if !ok {
+		healthMu.Unlock()
+		return SourceHealth{}, false, nil
+	}
+	h.Disabled, h.DisabledAt, h.Backoff, h.SkipLeft, h.BadStreak = false, time.Time{}, 0, 0, 0
+	c := *h
+	c.ID = SourceID(c.URL)
+	healthMu.Unlock()
+	return c, true, saveSourceHealth()
+}
+
+// SourceHealthAlerts 返回本轮停用与恢复的订阅提示
+func SourceHealthAlerts() []string {
+	healthMu.Lock()
+	defer healthMu.Unlock()
+	return slices.Clone(healthAlerts)
+}
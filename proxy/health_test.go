@@ -0,0 +1,98 @@
+package proxies
+
+import (
+	"testing"
+	"time"
+
+	"github.com/sinspired/subs-check-pro/v2/config"
+)
+
+func TestSourceHealthDisableAndBackoff(t *testing.T) {
+	cfg := config.SourceHealthConfig{Enable: true, DeadRuns: 3, RecheckRuns: 2, MaxRecheckRuns: 4}
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	h := &SourceHealth{URL: "a"}
+	dead := healthSample{nodes: 10}
+
+	for i := range 2 {
+		if msg := h.apply(dead, cfg, now); msg != "" || h.Disabled {
+			t.Fatalf("run %d: disabled too early: %q", i, msg)
+		}
+	}
+	if msg := h.apply(dead, cfg, now); msg == "" || !h.Disabled || h.SkipLeft != 2 {
+		t.Fatalf("expected disabled after 3 bad runs, got %+v %q", h, msg)
+	}
+	if h.LastReason != "无可用节点" {
+		t.Errorf("reason: %q", h.LastReason)
+	}
+
+	// 复查仍无效：间隔翻倍，不超过上限
+	h.apply(healthSample{fetchErr: "timeout"}, cfg, now)
+	if h.Backoff != 4 || h.SkipLeft != 4 || h.FetchFails != 1 {
+		t.Errorf("expected backoff 4, got %+v", h)
+	}
+	h.apply(dead, cfg, now)
+	if h.Backoff != 4 {
+		t.Errorf("backoff should be capped at 4, got %d", h.Backoff)
+	}
+
+	// 复查有效：恢复
+	if msg := h.apply(healthSample{nodes: 10, alive: 2, uniq: 5}, cfg, now); msg == "" || h.Disabled || h.BadStreak != 0 || h.SkipLeft != 0 {
+		t.Errorf("expected re-enabled, got %+v %q", h, msg)
+	}
+	if h.Runs != 6 {
+		t.Errorf("runs: %d", h.Runs)
+	}
+}
+
+func TestSourceHealthThresholdAndDisabledFeature(t *testing.T) {
+	s := healthSample{nodes: 100, alive: 1, uniq: 100}
+	if _, bad := s.bad(0); bad {
+		t.Error("alive node with zero threshold should be good")
+	}
+	if _, bad := s.bad(0.05); !bad {
+		t.Error("1% alive should be bad with 5% threshold")
+	}
+
+	// 未启用时只记录，不停用
+	h := &SourceHealth{URL: "a"}
+	for range 10 {
+		h.apply(healthSample{}, config.SourceHealthConfig{DeadRuns: 1}, time.Now())
+	}
+	if h.Disabled || h.BadStreak != 10 {
+		t.Errorf("got %+v", h)
+	}
+}
+
+func TestSkipUnhealthy(t *testing.T) {
+	oldCfg := config.GlobalConfig
+	config.GlobalConfig = &config.Config{SourceHealth: config.SourceHealthConfig{Enable: true}}
+	healthMu.Lock()
+	oldHealth, oldLoaded := subHealth, healthLoaded
+	subHealth = map[string]*SourceHealth{
+		"dead":    {URL: "dead", Disabled: true, Backoff: 2, SkipLeft: 1},
+		"recheck": {URL: "recheck", Disabled: true, Backoff: 2},
+		"ok":      {URL: "ok"},
+	}
+	healthLoaded = true
+	healthMu.Unlock()
+	defer func() {
+		config.GlobalConfig = oldCfg
+		subHealth, healthLoaded = oldHealth, oldLoaded
+	}()
+
+	beginHealthRound(false)
+	if !skipUnhealthy("dead") || !skipUnhealthy("dead") {
+		t.Error("disabled source should be skipped")
+	}
+	if subHealth["dead"].SkipLeft != 0 {
+		t.Errorf("skip counter should drop once per round, got %d", subHealth["dead"].SkipLeft)
+	}
+	if skipUnhealthy("recheck") || skipUnhealthy("ok") || skipUnhealthy("unknown") {
+		t.Error("recheck, healthy and unknown sources should not be skipped")
+	}
+
+	beginHealthRound(false)
+	if skipUnhealthy("dead") {
+		t.Error("source should be rechecked once the counter reaches zero")
+	}
+}
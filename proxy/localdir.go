@@ -0,0 +1,162 @@
+package proxies
+
+import (
+	"fmt"
+	"log/slog"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+
+	"github.com/sinspired/subs-check-pro/v2/config"
+	"github.com/sinspired/subs-check-pro/v2/utils"
+)
+
+// 本地目录订阅源：sub-dirs 中每个目录下的文件在拉取订阅时各作为一个订阅，以 file:// 地址参与后续流程。
+// 目录末段可以是 glob（如 /data/nodes/*.yaml），只匹配该目录下的文件，不递归子目录。
+// 只有 sub-dirs 生成的订阅源（SubSource.Local）才会读取本地文件，其他来源的 file:// 地址一律拒绝。
+
+// maxLocalSubSize 本地订阅文件大小上限，与远程订阅一致
+const maxLocalSubSize = 100 * 1024 * 1024
+
+var (
+	queuedMu    sync.Mutex
+	queuedFiles []string // 下一轮仅检测的本地文件，由目录监听写入
+)
+
+// SubDirPattern 拆分 sub-dirs 配置项为绝对目录与文件名 glob，glob 为空表示不过滤
+func SubDirPattern(entry string) (dir, pattern string) {
+	dir = filepath.Clean(strings.TrimSpace(entry))
+	if base := filepath.Base(dir); strings.ContainsAny(base, "*?[") {
+		pattern, dir = base, filepath.Dir(dir)
+	}
+	if !filepath.IsAbs(dir) {
+		baseDir := config.GlobalConfig.ConfigDir
+		if baseDir == "" {
+			baseDir = utils.GetExecutablePath()
+		}
+		dir = filepath.Join(baseDir, dir)
+	}
+	return dir, pattern
+}
+
+// MatchSubFile 文件名是否匹配 glob，隐藏文件与编辑器临时文件不参与
+func MatchSubFile(name, pattern string) bool {
+	if strings.HasPrefix(name, ".") || strings.HasSuffix(name, "~") ||
+		strings.HasSuffix(name, ".tmp") || strings.HasSuffix(name, ".swp") {
+		return false
+	}
+	if pattern == "" {
+		return true
+	}
+	ok, _ := filepath.Match(pattern, name)
+	return ok
+}
+
+// localDirSources 列出 sub-dirs 下的全部订阅文件
+func localDirSources() []config.SubSource {
+	var out []config.SubSource
+	for _, entry := range config.GlobalConfig.SubDirs {
+		if strings.TrimSpace(entry) == "" {
+			continue
+		}
+		dir, pattern := SubDirPattern(entry)
+		files, err := os.ReadDir(dir)
+		if err != nil {
+			slog.Warn("读取本地订阅目录失败", "目录", dir, "error", err)
+			continue
+		}
+		n := 0
+		for _, f := range files {
+			if f.IsDir() || !MatchSubFile(f.Name(), pattern) {
+				continue
+			}
+			out = append(out, config.SubSource{URL: fileURL(filepath.Join(dir, f.Name())), Local: true})
+			n++
+		}
+		slog.Debug("读取本地订阅目录", "目录", dir, "文件数", n)
+	}
+	return out
+}
+
+// fileURL 本地文件路径转为 file:// 地址
+func fileURL(p string) string {
+	p = filepath.ToSlash(p)
+	if !strings.HasPrefix(p, "/") {
+		p = "/" + p // Windows 盘符路径
+	}
+	return (&url.URL{Scheme: "file", Path: p}).String()
+}
+
+// localFilePath 解析 file:// 地址，返回本地路径
+func localFilePath(rawURL string) (string, bool) {
+	if !isFileURL(rawURL) {
+		return "", false
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Path == "" {
+		return "", false
+	}
+	p := u.Path
+	if runtime.GOOS == "windows" {
+		p = strings.TrimPrefix(p, "/")
+	}
+	return filepath.FromSlash(p), true
+}
+
+// isFileURL 是否为 file:// 地址
+func isFileURL(rawURL string) bool {
+	return strings.HasPrefix(strings.ToLower(strings.TrimSpace(rawURL)), "file://")
+}
+
+// subDirFile 解析 sub-dirs 订阅源的 file:// 地址，只接受直接位于已配置目录下且匹配文件名规则的文件
+func subDirFile(rawURL string) (string, error) {
+	p, ok := localFilePath(rawURL)
+	if !ok {
+		return "", fmt.Errorf("无效的本地订阅地址: %s", rawURL)
+	}
+	p = filepath.Clean(p)
+	for _, entry := range config.GlobalConfig.SubDirs {
+		if strings.TrimSpace(entry) == "" {
+			continue
+		}
+		dir, pattern := SubDirPattern(entry)
+		if filepath.Dir(p) == dir && MatchSubFile(filepath.Base(p), pattern) {
+			return p, nil
+		}
+	}
+	return "", fmt.Errorf("文件不在 sub-dirs 配置的目录中: %s", p)
+}
+
+// readLocalSub 读取本地订阅文件
+func readLocalSub(p string) ([]byte, error) {
+	info, err := os.Stat(p)
+	if err != nil {
+		return nil, err
+	}
+	if info.Size() > maxLocalSubSize {
+		return nil, fmt.Errorf("订阅文件过大: %d MB", info.Size()/1024/1024)
+	}
+	return os.ReadFile(p)
+}
+
+// QueueLocalFiles 登记新增的本地订阅文件，下一轮拉取订阅时只处理这些文件（以及保留的成功节点）
+func QueueLocalFiles(paths []string) {
+	queuedMu.Lock()
+	defer queuedMu.Unlock()
+	queuedFiles = append(queuedFiles, paths...)
+}
+
+// takeQueuedFiles 取出并清空登记的本地文件
+func takeQueuedFiles() []config.SubSource {
+	queuedMu.Lock()
+	defer queuedMu.Unlock()
+	out := make([]config.SubSource, 0, len(queuedFiles))
+	for _, p := range queuedFiles {
+		out = append(out, config.SubSource{URL: fileURL(p), Local: true})
+	}
+	queuedFiles = nil
+	return out
+}
@@ -0,0 +1,84 @@
+package proxies
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sinspired/subs-check-pro/v2/config"
+)
+
+func TestLocalDirSources(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"a.yaml", "b.txt", "c d.yaml", ".hidden.yaml", "e.yaml.swp"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("proxies: []"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Mkdir(filepath.Join(dir, "sub.yaml"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	old := config.GlobalConfig.SubDirs
+	defer func() { config.GlobalConfig.SubDirs = old }()
+
+	config.GlobalConfig.SubDirs = []string{filepath.Join(dir, "*.yaml")}
+	subs := localDirSources()
+	if len(subs) != 2 {
+		t.Fatalf("got %+v", subs)
+	}
+	p, ok := localFilePath(subs[1].URL)
+	if !ok || p != filepath.Join(dir, "c d.yaml") {
+		t.Fatalf("localFilePath(%q) = %q, %v", subs[1].URL, p, ok)
+	}
+	if data, err := readLocalSub(p); err != nil || string(data) != "proxies: []" {
+		t.Fatalf("readLocalSub: %q, %v", data, err)
+	}
+
+	config.GlobalConfig.SubDirs = []string{dir}
+	if subs := localDirSources(); len(subs) != 3 || !subs[0].Local {
+		t.Fatalf("no pattern: %+v", subs)
+	}
+}
+
+func TestSubDirFileRestricted(t *testing.T) {
+	dir := t.TempDir()
+	old := config.GlobalConfig.SubDirs
+	defer func() { config.GlobalConfig.SubDirs = old }()
+	config.GlobalConfig.SubDirs = []string{filepath.Join(dir, "*.yaml")}
+
+	if p, err := subDirFile(fileURL(filepath.Join(dir, "a.yaml"))); err != nil || p != filepath.Join(dir, "a.yaml") {
+		t.Errorf("file in sub-dir: %q, %v", p, err)
+	}
+	for _, p := range []string{
+		"/etc/passwd",
+		filepath.Join(dir, "a.txt"),
+		filepath.Join(dir, "sub", "a.yaml"),
+		filepath.Join(dir, "..", "a.yaml"),
+	} {
+		if _, err := subDirFile(fileURL(p)); err == nil {
+			t.Errorf("%s should be rejected", p)
+		}
+	}
+
+	// 非 sub-dirs 来源的 file:// 地址不读取
+	if _, err := fetchSubsDataWith(fileURL(filepath.Join(dir, "a.yaml")), nil, nil, nil); err == nil {
+		t.Error("file:// without a local source should be rejected")
+	}
+	if _, err := fetchSubsDataWith("file:///etc/passwd", &config.SubSource{URL: "file:///etc/passwd", Local: true}, nil, nil); err == nil {
+		t.Error("file outside sub-dirs should be rejected")
+	}
+}
+
+func TestQueueLocalFiles(t *testing.T) {
+	QueueLocalFiles([]string{"/tmp/a.yaml"})
+	if got := takeQueuedFiles(); len(got) != 1 || got[0].URL != "file:///tmp/a.yaml" || !got[0].Local {
+		t.Fatalf("got %+v", got)
+	}
+	if got := takeQueuedFiles(); len(got) != 0 {
+		t.Fatalf("queue not cleared: %+v", got)
+	}
+	if _, ok := localFilePath("https://example.com/a.yaml"); ok {
+		t.Error("http url is not a local file")
+	}
+}
@@ -0,0 +1,130 @@
+package proxies
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/goccy/go-yaml"
+	"github.com/sinspired/subs-check-pro/v2/save/method"
+	"github.com/sinspired/subs-check-pro/v2/utils"
+)
+
+// 名称模板的原始名称：渲染时记在节点元数据 NameOriginKey 中，检测结束后按节点指纹保存到统计文件。
+// 保留的成功节点与历史节点下轮读回时名称已是渲染结果，由此还原 {name}，而不是反向解析渲染结果。
+
+const (
+	nameOriginFile = "name-origin.yaml"
+	// NameOriginKey 节点元数据：名称模板渲染前的原始名称，输出前由 CleanupMetadata 删除
+	NameOriginKey = "name_origin"
+	// nameOriginRetention 超过该时长未出现在订阅中的节点记录将被清理
+	nameOriginRetention = 30 * 24 * time.Hour
+)
+
+// nameOrigin 单个节点的原始名称记录
+type nameOrigin struct {
+	Name     string `yaml:"name"`
+	LastSeen string `yaml:"last-seen"`
+}
+
+var (
+	nameOriginMu     sync.Mutex
+	nameOrigins      map[string]*nameOrigin // key: 节点指纹的短哈希
+	nameOriginLoaded bool                   // 是否已从统计文件加载
+)
+
+// SourceName 返回节点渲染前的原始名称并记在 NameOriginKey 中，同一节点重复渲染时始终使用该名称
+func SourceName(node map[string]any) string {
+	if s, ok := node[NameOriginKey].(string); ok && s != "" {
+		return s
+	}
+	s, _ := node["name"].(string)
+	s = strings.TrimSpace(s)
+	node[NameOriginKey] = s
+	return s
+}
+
+// nameOriginKey 节点指纹的短哈希，优先使用拉取时已计算的 _node_key
+func nameOriginKey(node map[string]any) string {
+	key, ok := node["_node_key"].(string)
+	if !ok {
+		key = utils.GenerateProxyKey(node)
+	}
+	sum := sha1.Sum([]byte(key))
+	return hex.EncodeToString(sum[:8])
+}
+
+// restoreNameOrigins 为本轮拉取的节点写回记录中的原始名称，并刷新记录的出现时间
+func restoreNameOrigins(nodes []map[string]any, now time.Time) {
+	nameOriginMu.Lock()
+	defer nameOriginMu.Unlock()
+	ensureNameOriginsLoaded()
+	if len(nameOrigins) == 0 {
+		return
+	}
+	today := now.Format(time.DateOnly)
+	for _, node := range nodes {
+		if rec, ok := nameOrigins[nameOriginKey(node)]; ok {
+			node[NameOriginKey] = rec.Name
+			rec.LastSeen = today
+		}
+	}
+}
+
+// recordNameOrigins 记录已渲染节点的原始名称，清理长期未出现的记录
+func recordNameOrigins(nodes []map[string]any, now time.Time) {
+	nameOriginMu.Lock()
+	defer nameOriginMu.Unlock()
+	ensureNameOriginsLoaded()
+
+	today := now.Format(time.DateOnly)
+	for _, node := range nodes {
+		if name, ok := node[NameOriginKey].(string); ok {
+			nameOrigins[nameOriginKey(node)] = &nameOrigin{Name: name, LastSeen: today}
+		}
+	}
+	cutoff := now.Add(-nameOriginRetention)
+	for key, rec := range nameOrigins {
+		if t, err := time.Parse(time.DateOnly, rec.LastSeen); err != nil || t.Before(cutoff) {
+			delete(nameOrigins, key)
+		}
+	}
+}
+
+// SaveNameOrigins 检测结束后记录节点的原始名称并保存
+func SaveNameOrigins(nodes []map[string]any) {
+	recordNameOrigins(nodes, time.Now())
+
+	nameOriginMu.Lock()
+	data, err := yaml.Marshal(nameOrigins)
+	nameOriginMu.Unlock()
+	if err != nil {
+		return
+	}
+	_ = method.SaveToStats(data, nameOriginFile, "节点原始名称")
+}
+
+// ensureNameOriginsLoaded 首次访问时读取上次保存的记录，调用方需持有 nameOriginMu
+func ensureNameOriginsLoaded() {
+	if nameOriginLoaded {
+		return
+	}
+	nameOriginLoaded = true
+	nameOrigins = make(map[string]*nameOrigin)
+
+	saver, err := method.NewStatsSaver()
+	if err != nil {
+		return
+	}
+	data, err := os.ReadFile(filepath.Join(saver.StatsPath, nameOriginFile))
+	if err != nil {
+		return
+	}
+	if err := yaml.Unmarshal(data, &nameOrigins); err != nil || nameOrigins == nil {
+		nameOrigins = make(map[string]*nameOrigin)
+	}
+}
@@ -0,0 +1,291 @@
+package proxies
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// 名称模板语法：
+//
+//	{var}         变量：flag country city asn asorg isp speed latency tags sub subtags name
+//	              sub 依次取订阅源名称、#备注、订阅域名；subtags 为订阅源标签，以 | 连接
+//	{index}       按国家分组的序号，等价于 {#country}
+//	{#a+b}        自定义计数器，按变量 a、b 的取值分组计数；{#} 为全局序号
+//	{index:2}     序号补零到指定宽度，同样适用于 {#a+b:2}
+//	[...]         条件片段，片段内任一变量为空时整个片段不输出，可嵌套
+//	\{ \} \[ \]   转义字面量
+//
+// 示例："{flag}{country}_{index}[|{speed}][|{tags}]"
+//
+// {name} 取渲染前的原始名称（见 SourceName），对已重命名的节点重复渲染不会叠加标签。
+
+// NameTemplateVars 模板支持的变量
+var NameTemplateVars = []string{"flag", "country", "city", "asn", "asorg", "isp", "speed", "latency", "tags", "sub", "subtags", "name"}
+
+type tplNodeKind int
+
+const (
+	tplLiteral tplNodeKind = iota
+	tplVar
+	tplCounter
+	tplSection
+)
+
+type tplNode struct {
+	kind     tplNodeKind
+	text     string    // 字面量或变量名
+	keys     []string  // 计数器分组变量
+	width    int       // 序号补零宽度
+	children []tplNode // 条件片段内容
+}
+
+// NameTemplate 已编译的名称模板
+type NameTemplate struct {
+	src   string
+	nodes []tplNode
+}
+
+var (
+	tplCounters   = make(map[string]int)
+	tplCounterMu  sync.Mutex
+	tplCache      *NameTemplate
+	tplCacheMu    sync.Mutex
+	tplValidNames = func() map[string]bool {
+		m := make(map[string]bool, len(NameTemplateVars))
+		for _, v := range NameTemplateVars {
+			m[v] = true
+		}
+		return m
+	}()
+)
+
+// CompiledNameTemplate 返回 src 对应的已编译模板，模板未变化时复用缓存
+func CompiledNameTemplate(src string) (*NameTemplate, error) {
+	tplCacheMu.Lock()
+	defer tplCacheMu.Unlock()
+	if tplCache != nil && tplCache.src == src {
+		return tplCache, nil
+	}
+	t, err := ParseNameTemplate(src)
+	if err != nil {
+		return nil, err
+	}
+	tplCache = t
+	return t, nil
+}
+
+// ParseNameTemplate 解析名称模板
+func ParseNameTemplate(src string) (*NameTemplate, error) {
+	p := &tplParser{src: []rune(src)}
+	nodes, err := p.parse(false)
+	if err != nil {
+		return nil, err
+	}
+	return &NameTemplate{src: src, nodes: nodes}, nil
+}
+
+// Uses 模板是否引用了任一变量（计数器按其分组变量计算）
+func (t *NameTemplate) Uses(vars ...string) bool {
+	var walk func([]tplNode) bool
+	walk = func(nodes []tplNode) bool {
+		for _, n := range nodes {
+			switch n.kind {
+			case tplVar:
+				if slices.Contains(vars, n.text) {
+					return true
+				}
+			case tplCounter:
+				for _, k := range n.keys {
+					if slices.Contains(vars, k) {
+						return true
+					}
+				}
+			case tplSection:
+				if walk(n.children) {
+					return true
+				}
+			}
+		}
+		return false
+	}
+	return walk(t.nodes)
+}
+
+// Render 按变量渲染名称，计数器在渲染时递增
+func (t *NameTemplate) Render(vars map[string]string) string {
+	return t.RenderScoped(vars, nil)
+}
+
+// RenderScoped 与 Render 相同，但计数器记录在 counters 中，不影响检测流程的全局计数。
+// counters 为 nil 时使用全局计数器
+func (t *NameTemplate) RenderScoped(vars map[string]string, counters map[string]int) string {
+	var sb strings.Builder
+	renderNodes(&sb, t.nodes, vars, counters)
+	return strings.TrimSpace(sb.String())
+}
+
+// renderNodes 渲染节点序列
+func renderNodes(sb *strings.Builder, nodes []tplNode, vars map[string]string, counters map[string]int) {
+	for _, n := range nodes {
+		switch n.kind {
+		case tplLiteral:
+			sb.WriteString(n.text)
+		case tplVar:
+			sb.WriteString(vars[n.text])
+		case tplCounter:
+			sb.WriteString(nextCounter(n, vars, counters))
+		case tplSection:
+			if sectionFilled(n.children, vars) {
+				renderNodes(sb, n.children, vars, counters)
+			}
+		}
+	}
+}
+
+// sectionFilled 条件片段内的变量是否均非空，嵌套片段单独判断
+func sectionFilled(nodes []tplNode, vars map[string]string) bool {
+	for _, n := range nodes {
+		if n.kind == tplVar && vars[n.text] == "" {
+			return false
+		}
+	}
+	return true
+}
+
+// nextCounter 递增并返回计数器值
+func nextCounter(n tplNode, vars map[string]string, counters map[string]int) string {
+	var key strings.Builder
+	key.WriteString("#")
+	for _, k := range n.keys {
+		key.WriteString(k + "=" + vars[k] + "\x00")
+	}
+	var v int
+	if counters != nil {
+		counters[key.String()]++
+		v = counters[key.String()]
+	} else {
+		tplCounterMu.Lock()
+		tplCounters[key.String()]++
+		v = tplCounters[key.String()]
+		tplCounterMu.Unlock()
+	}
+
+	s := strconv.Itoa(v)
+	if len(s) < n.width {
+		s = strings.Repeat("0", n.width-len(s)) + s
+	}
+	return s
+}
+
+// resetTemplateCounters 清空模板计数器
+func resetTemplateCounters() {
+	tplCounterMu.Lock()
+	defer tplCounterMu.Unlock()
+	tplCounters = make(map[string]int)
+}
+
+// tplParser 模板解析器
+type tplParser struct {
+	src []rune
+	pos int
+}
+
+func (p *tplParser) parse(inSection bool) ([]tplNode, error) {
+	var nodes []tplNode
+	var lit strings.Builder
+	flush := func() {
+		if lit.Len() > 0 {
+			nodes = append(nodes, tplNode{kind: tplLiteral, text: lit.String()})
+			lit.Reset()
+		}
+	}
+
+	for p.pos < len(p.src) {
+		c := p.src[p.pos]
+		p.pos++
+		switch c {
+		case '\\':
+			if p.pos < len(p.src) {
+				lit.WriteRune(p.src[p.pos])
+				p.pos++
+			} else {
+				lit.WriteRune(c)
+			}
+		case '{':
+			flush()
+			end := p.pos
+			for end < len(p.src) && p.src[end] != '}' {
+				end++
+			}
+			if end >= len(p.src) {
+				return nil, fmt.Errorf("名称模板第 %d 个字符处的 { 未闭合", p.pos)
+			}
+			n, err := parsePlaceholder(string(p.src[p.pos:end]))
+			if err != nil {
+				return nil, err
+			}
+			nodes = append(nodes, n)
+			p.pos = end + 1
+		case '[':
+			flush()
+			children, err := p.parse(true)
+			if err != nil {
+				return nil, err
+			}
+			nodes = append(nodes, tplNode{kind: tplSection, children: children})
+		case ']':
+			if !inSection {
+				return nil, fmt.Errorf("名称模板第 %d 个字符处的 ] 没有对应的 [", p.pos)
+			}
+			flush()
+			return nodes, nil
+		default:
+			lit.WriteRune(c)
+		}
+	}
+	if inSection {
+		return nil, fmt.Errorf("名称模板中的 [ 未闭合")
+	}
+	flush()
+	return nodes, nil
+}
+
+// parsePlaceholder 解析 {} 内的变量或计数器
+func parsePlaceholder(s string) (tplNode, error) {
+	s = strings.TrimSpace(s)
+	width := 0
+	if i := strings.LastIndexByte(s, ':'); i >= 0 {
+		w, err := strconv.Atoi(s[i+1:])
+		if err != nil || w < 0 || w > 10 {
+			return tplNode{}, fmt.Errorf("名称模板 {%s} 的补零宽度无效", s)
+		}
+		width, s = w, s[:i]
+	}
+
+	switch {
+	case s == "index":
+		return tplNode{kind: tplCounter, keys: []string{"country"}, width: width}, nil
+	case strings.HasPrefix(s, "#"):
+		var keys []string
+		for k := range strings.SplitSeq(s[1:], "+") {
+			k = strings.TrimSpace(k)
+			if k == "" {
+				continue
+			}
+			if !tplValidNames[k] {
+				return tplNode{}, fmt.Errorf("名称模板计数器 {%s} 使用了未知变量 %q", s, k)
+			}
+			keys = append(keys, k)
+		}
+		return tplNode{kind: tplCounter, keys: keys, width: width}, nil
+	case tplValidNames[s]:
+		if width > 0 {
+			return tplNode{}, fmt.Errorf("名称模板变量 {%s} 不支持补零宽度", s)
+		}
+		return tplNode{kind: tplVar, text: s}, nil
+	}
+	return tplNode{}, fmt.Errorf("名称模板包含未知变量 {%s}", s)
+}
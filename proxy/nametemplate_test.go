@@ -0,0 +1,127 @@
+package proxies
+
+import (
+	"maps"
+	"testing"
+	"time"
+)
+
+func TestNameTemplateRender(t *testing.T) {
+	resetTemplateCounters()
+	tpl, err := ParseNameTemplate("{flag}{country}_{index:2}[|{speed}][|{tags}] {name}")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	vars := map[string]string{"flag": "🇺🇸", "country": "US", "speed": "1.2MB/s", "name": "node-a"}
+	if got := tpl.Render(vars); got != "🇺🇸US_01|1.2MB/s node-a" {
+		t.Errorf("got %q", got)
+	}
+	vars["tags"] = "GPT⁺|NF"
+	if got := tpl.Render(vars); got != "🇺🇸US_02|1.2MB/s|GPT⁺|NF node-a" {
+		t.Errorf("got %q", got)
+	}
+	if got := tpl.Render(map[string]string{"country": "HK", "name": "b"}); got != "HK_01 b" {
+		t.Errorf("per-country index: got %q", got)
+	}
+}
+
+func TestNameTemplateCustomCounter(t *testing.T) {
+	resetTemplateCounters()
+	tpl, err := ParseNameTemplate("{#}-{isp}{#isp}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"1-住宅1", "2-机房1", "3-住宅2"}
+	for i, isp := range []string{"住宅", "机房", "住宅"} {
+		if got := tpl.Render(map[string]string{"isp": isp}); got != want[i] {
+			t.Errorf("render %d: got %q, want %q", i, got, want[i])
+		}
+	}
+}
+
+func TestNameTemplateIdempotent(t *testing.T) {
+	cases := []struct {
+		tpl  string
+		vars map[string]string
+	}{
+		{"{flag}{country}_{index}[|{speed}][|{tags}] \\[{name}\\]", map[string]string{"flag": "🇯🇵", "country": "JP", "speed": "900KB/s", "tags": "GPT|YT", "name": "Tokyo 01|x"}},
+		{"{name}[|{tags}]", map[string]string{"name": "node", "tags": "GPT|NF"}},
+		{"{name}[|{tags}]", map[string]string{"name": "香港 | IPLC 01", "tags": "GPT"}},
+		{"{name}[|{isp}]", map[string]string{"name": "a|b", "isp": "住宅"}},
+		{"{name}[ {sub}]", map[string]string{"name": "HK 01", "sub": "机场 A"}},
+		{"{name}[|{speed}][|{tags}]", map[string]string{"name": "JP|1.0MB/s|foo", "speed": "2.0MB/s", "tags": "NF|D+"}},
+		{"[{flag} ]{name}[ {latency}][|{speed}]", map[string]string{"name": "HK 01", "flag": "🇭🇰", "latency": "120ms", "speed": "2.5MB/s"}},
+		{"{name}[-{asn}]", map[string]string{"name": "node-1", "asn": "AS13335"}},
+	}
+	for _, c := range cases {
+		tpl, err := ParseNameTemplate(c.tpl)
+		if err != nil {
+			t.Fatal(err)
+		}
+		raw := c.vars["name"]
+		node := map[string]any{"name": raw}
+		var first string
+		for i := range 3 {
+			vars := maps.Clone(c.vars)
+			vars["name"] = SourceName(node)
+			node["name"] = tpl.RenderScoped(vars, make(map[string]int))
+			if i == 0 {
+				first = node["name"].(string)
+			} else if node["name"] != first {
+				t.Fatalf("%s run %d: %q re-rendered as %q", c.tpl, i, first, node["name"])
+			}
+			if node[NameOriginKey] != raw {
+				t.Fatalf("%s run %d: origin %q, want %q", c.tpl, i, node[NameOriginKey], raw)
+			}
+		}
+	}
+}
+
+func TestNameOriginAcrossRuns(t *testing.T) {
+	nameOriginMu.Lock()
+	oldOrigins, oldLoaded := nameOrigins, nameOriginLoaded
+	nameOrigins, nameOriginLoaded = make(map[string]*nameOrigin), true
+	nameOriginMu.Unlock()
+	defer func() { nameOrigins, nameOriginLoaded = oldOrigins, oldLoaded }()
+
+	tpl, err := ParseNameTemplate("{name}[|{tags}]")
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	node := map[string]any{"name": "香港 | IPLC 01", "type": "ss", "server": "a.example.com", "port": 443}
+	node["name"] = tpl.Render(map[string]string{"name": SourceName(node), "tags": "GPT|NF"})
+	recordNameOrigins([]map[string]any{node}, now)
+
+	// 下轮从 all.yaml 读回：只有渲染后的名称
+	reloaded := map[string]any{"name": node["name"], "type": "ss", "server": "a.example.com", "port": 443}
+	restoreNameOrigins([]map[string]any{reloaded}, now.Add(24*time.Hour))
+	if got := SourceName(reloaded); got != "香港 | IPLC 01" {
+		t.Fatalf("restored origin %q", got)
+	}
+	if got := tpl.Render(map[string]string{"name": SourceName(reloaded), "tags": "GPT|NF"}); got != node["name"] {
+		t.Errorf("re-render %q, want %q", got, node["name"])
+	}
+
+	// 没有记录的节点按原名渲染
+	fresh := map[string]any{"name": "plain", "type": "ss", "server": "b.example.com", "port": 443}
+	restoreNameOrigins([]map[string]any{fresh}, now)
+	if got := SourceName(fresh); got != "plain" {
+		t.Errorf("unrecorded node: %q", got)
+	}
+
+	// 长期未出现的记录被清理
+	recordNameOrigins(nil, now.Add(nameOriginRetention+48*time.Hour))
+	if len(nameOrigins) != 0 {
+		t.Errorf("stale records kept: %v", nameOrigins)
+	}
+}
+
+func TestParseNameTemplateErrors(t *testing.T) {
+	for _, src := range []string{"{unknown}", "{country", "[{speed}", "{speed}]", "{#foo}", "{index:x}", "{speed:2}"} {
+		if _, err := ParseNameTemplate(src); err == nil {
+			t.Errorf("%q: expected error", src)
+		}
+	}
+}
@@ -0,0 +1,505 @@
+package parse
+
+import (
+	"bufio"
+	"bytes"
+	"cmp"
+	"strings"
+)
+
+// 客户端配置方言：Quantumult X、Loon、Surge
+//
+//	Quantumult X  [server_local]  shadowsocks=host:port, method=aes-128-gcm, password=pwd, tag=名称
+//	Loon          [Proxy]         名称 = Shadowsocks,host,port,aes-128-gcm,"pwd",udp=true
+//	Surge         [Proxy]         名称 = ss, host, port, encrypt-method=aes-128-gcm, password=pwd
+//
+// Loon 在端口之后使用位置参数，Surge 全部使用 key=value，据此区分两者。
+// 配置中含段落标记时只解析代理所在的段落，否则逐行尝试（节点列表形式的订阅）。
+
+// qxTypes Quantumult X 协议名到 mihomo 类型
+var qxTypes = map[string]string{
+	"shadowsocks": "ss",
+	"vmess":       "vmess",
+	"vless":       "vless",
+	"trojan":      "trojan",
+	"http":        "http",
+	"socks5":      "socks5",
+}
+
+// loonTypes Loon 中端口后跟位置参数的协议
+var loonTypes = map[string]string{
+	"shadowsocks":  "ss",
+	"shadowsocksr": "ssr",
+	"vmess":        "vmess",
+	"vless":        "vless",
+	"trojan":       "trojan",
+	"hysteria2":    "hysteria2",
+}
+
+// ParseQuantumultXProxies 解析 Quantumult X 的 server_local 行
+func ParseQuantumultXProxies(data []byte) []map[string]any {
+	var nodes []map[string]any
+	for _, line := range clientConfLines(data, "server_local") {
+		if node := parseQuantumultXLine(line); node != nil {
+			nodes = append(nodes, node)
+		}
+	}
+	return nodes
+}
+
+// ParseLoonProxies 解析 Loon 的 [Proxy] 段
+func ParseLoonProxies(data []byte) []map[string]any {
+	var nodes []map[string]any
+	for _, line := range clientConfLines(data, "proxy") {
+		name, args, ok := splitProxyLine(line)
+		if !ok || !isLoonLine(args) {
+			continue
+		}
+		if node := parseLoonArgs(name, args); node != nil {
+			nodes = append(nodes, node)
+		}
+	}
+	return nodes
+}
+
+// ParseSurgeProxies 解析 Surge 的 [Proxy] 段，Surfboard 与之兼容
+func ParseSurgeProxies(data []byte) []map[string]any {
+	var nodes []map[string]any
+	for _, line := range clientConfLines(data, "proxy") {
+		name, args, ok := splitProxyLine(line)
+		if !ok || isLoonLine(args) {
+			continue
+		}
+		if node := parseSurgeArgs(name, args); node != nil {
+			nodes = append(nodes, node)
+		}
+	}
+	return nodes
+}
+
+// clientConfLines 返回指定段落中的有效行；没有任何段落标记时返回全部有效行
+func clientConfLines(data []byte, section string) []string {
+	var (
+		lines      []string
+		hasSection bool
+		inSection  bool
+	)
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || line[0] == '#' || line[0] == ';' || strings.HasPrefix(line, "//") {
+			continue
+		}
+		if line[0] == '[' && line[len(line)-1] == ']' && !strings.Contains(line, "=") {
+			hasSection = true
+			inSection = strings.EqualFold(strings.Trim(line, "[] "), section)
+			continue
+		}
+		if hasSection && !inSection {
+			continue
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// splitProxyLine 拆分 "名称 = 类型, 参数..." 形式的行
+func splitProxyLine(line string) (string, []string, bool) {
+	name, right, ok := strings.Cut(line, "=")
+	if !ok {
+		return "", nil, false
+	}
+	name = unquoteConf(strings.TrimSpace(name))
+	args := splitConfArgs(right)
+	if name == "" || len(args) < 3 || strings.Contains(args[0], "=") || args[2] == "" || !isDigit(args[2]) {
+		return "", nil, false
+	}
+	return name, args, true
+}
+
+// isLoonLine 端口后的第一个参数为位置参数时视为 Loon 写法，带引号的参数（密码中可能含等号）总是位置参数
+func isLoonLine(args []string) bool {
+	if len(args) < 4 || strings.Contains(args[3], "=") && !strings.HasPrefix(args[3], `"`) {
+		return false
+	}
+	_, ok := loonTypes[strings.ToLower(args[0])]
+	return ok
+}
+
+// splitConfArgs 按逗号拆分参数，忽略双引号内的逗号
+func splitConfArgs(s string) []string {
+	var (
+		args    []string
+		quoted  bool
+		current strings.Builder
+	)
+	for _, r := range s {
+		switch {
+		case r == '"':
+			quoted = !quoted
+			current.WriteRune(r)
+		case r == ',' && !quoted:
+			args = append(args, strings.TrimSpace(current.String()))
+			current.Reset()
+		default:
+			current.WriteRune(r)
+		}
+	}
+	if last := strings.TrimSpace(current.String()); last != "" {
+		args = append(args, last)
+	}
+	return args
+}
+
+// confKV 解析 key=value 参数，key 统一小写，value 去掉引号
+func confKV(args []string) map[string]string {
+	kv := make(map[string]string, len(args))
+	for _, a := range args {
+		if k, v, ok := strings.Cut(a, "="); ok {
+			kv[strings.ToLower(strings.TrimSpace(k))] = unquoteConf(strings.TrimSpace(v))
+		}
+	}
+	return kv
+}
+
+func unquoteConf(s string) string {
+	if len(s) >= 2 && s[0] == '"' && s[len(s)-1] == '"' {
+		return s[1 : len(s)-1]
+	}
+	return s
+}
+
+// setConfBool 将存在的布尔参数写入节点
+func setConfBool(node map[string]any, field string, kv map[string]string, keys ...string) {
+	for _, k := range keys {
+		if v, ok := kv[k]; ok {
+			node[field] = ToBool(v)
+			return
+		}
+	}
+}
+
+// setConfSNI vmess/vless 使用 servername，其余协议使用 sni
+func setConfSNI(node map[string]any, sni string) {
+	if sni == "" {
+		return
+	}
+	switch node["type"] {
+	case "vmess", "vless":
+		node["servername"] = sni
+	default:
+		node["sni"] = sni
+	}
+}
+
+// applyConfTransport 写入 ws/http 传输层选项
+func applyConfTransport(node map[string]any, network, path, host string) {
+	switch strings.ToLower(network) {
+	case "ws", "wss", "websocket":
+		opts := map[string]any{"path": cmp.Or(path, "/")}
+		if host != "" {
+			opts["headers"] = map[string]any{"Host": host}
+		}
+		node["network"] = "ws"
+		node["ws-opts"] = opts
+	case "http":
+		opts := map[string]any{"path": []any{cmp.Or(path, "/")}}
+		if host != "" {
+			opts["headers"] = map[string]any{"Host": []any{host}}
+		}
+		node["network"] = "http"
+		node["http-opts"] = opts
+	}
+}
+
+// parseWsHeaders 解析 Surge 的 ws-headers：Host:a.com|X-Key:value
+func parseWsHeaders(s string) map[string]any {
+	headers := make(map[string]any)
+	for part := range strings.SplitSeq(s, "|") {
+		if k, v, ok := strings.Cut(part, ":"); ok {
+			headers[strings.TrimSpace(k)] = unquoteConf(strings.TrimSpace(v))
+		}
+	}
+	return headers
+}
+
+// vmessCipher 各客户端的加密方式名转换为 mihomo 写法
+func vmessCipher(method string) string {
+	switch strings.ToLower(method) {
+	case "", "auto":
+		return "auto"
+	case "chacha20-ietf-poly1305", "chacha20-poly1305":
+		return "chacha20-poly1305"
+	default:
+		return strings.ToLower(method)
+	}
+}
+
+// parseQuantumultXLine 解析单行 Quantumult X 节点
+func parseQuantumultXLine(line string) map[string]any {
+	left, right, ok := strings.Cut(line, "=")
+	if !ok {
+		return nil
+	}
+	typ, ok := qxTypes[strings.ToLower(strings.TrimSpace(left))]
+	if !ok {
+		return nil
+	}
+	args := splitConfArgs(right)
+	if len(args) < 2 {
+		return nil
+	}
+	host, port := SplitHostPortLoose(args[0])
+	if host == "" || port == "" || !isDigit(port) {
+		return nil
+	}
+	kv := confKV(args[1:])
+
+	node := map[string]any{
+		"name":   cmp.Or(kv["tag"], args[0]),
+		"type":   typ,
+		"server": strings.Trim(host, "[]"),
+		"port":   ToIntPort(port),
+	}
+	setConfBool(node, "udp", kv, "udp-relay")
+	setConfBool(node, "tfo", kv, "fast-open")
+	if v, ok := kv["tls-verification"]; ok {
+		node["skip-cert-verify"] = !ToBool(v)
+	}
+
+	obfs := strings.ToLower(kv["obfs"])
+	obfsHost, obfsURI := kv["obfs-host"], kv["obfs-uri"]
+
+	switch typ {
+	case "ss":
+		node["cipher"] = kv["method"]
+		node["password"] = kv["password"]
+		if protocol := kv["ssr-protocol"]; protocol != "" {
+			node["type"] = "ssr"
+			node["protocol"] = protocol
+			node["protocol-param"] = kv["ssr-protocol-param"]
+			node["obfs"] = cmp.Or(obfs, "plain")
+			node["obfs-param"] = obfsHost
+			break
+		}
+		switch obfs {
+		case "http", "tls":
+			node["plugin"] = "obfs"
+			node["plugin-opts"] = map[string]any{"mode": obfs, "host": obfsHost}
+		case "ws", "wss":
+			node["plugin"] = "v2ray-plugin"
+			node["plugin-opts"] = map[string]any{
+				"mode": "websocket",
+				"tls":  obfs == "wss",
+				"host": obfsHost,
+				"path": cmp.Or(obfsURI, "/"),
+			}
+		}
+
+	case "vmess", "vless", "trojan":
+		switch typ {
+		case "vmess":
+			node["uuid"] = kv["password"]
+			node["cipher"] = vmessCipher(kv["method"])
+			node["alterId"] = 0
+		case "vless":
+			node["uuid"] = kv["password"]
+			if flow := kv["vless-flow"]; flow != "" {
+				node["flow"] = flow
+			}
+			if pub := kv["reality-base64-pubkey"]; pub != "" {
+				node["tls"] = true
+				node["reality-opts"] = map[string]any{"public-key": pub, "short-id": kv["reality-hex-shortid"]}
+			}
+		case "trojan":
+			node["password"] = kv["password"]
+		}
+		switch obfs {
+		case "ws", "http":
+			applyConfTransport(node, obfs, obfsURI, obfsHost)
+		case "wss":
+			applyConfTransport(node, "ws", obfsURI, obfsHost)
+			node["tls"] = true
+		case "over-tls":
+			node["tls"] = true
+		}
+		setConfBool(node, "tls", kv, "over-tls")
+		if ToBool(node["tls"]) {
+			setConfSNI(node, cmp.Or(kv["tls-host"], obfsHost))
+		}
+
+	case "http", "socks5":
+		if user := kv["username"]; user != "" {
+			node["username"] = user
+			node["password"] = kv["password"]
+		}
+		setConfBool(node, "tls", kv, "over-tls")
+		if typ == "http" && ToBool(node["tls"]) {
+			setConfSNI(node, kv["tls-host"])
+		}
+	}
+
+	NormalizeNode(node)
+	return node
+}
+
+// parseLoonArgs 解析 Loon 位置参数写法
+func parseLoonArgs(name string, args []string) map[string]any {
+	typ := loonTypes[strings.ToLower(args[0])]
+	pos := args[3:]
+	// 位置参数个数：ss/ssr/vmess 为 加密方式+密码，其余为 密码/uuid
+	n := 1
+	if typ == "ss" || typ == "ssr" || typ == "vmess" {
+		n = 2
+	}
+	if len(pos) < n {
+		return nil
+	}
+	kv := confKV(pos[n:])
+
+	node := map[string]any{
+		"name":   name,
+		"type":   typ,
+		"server": args[1],
+		"port":   ToIntPort(args[2]),
+	}
+	setConfBool(node, "udp", kv, "udp")
+	setConfBool(node, "tfo", kv, "fast-open")
+	setConfBool(node, "skip-cert-verify", kv, "skip-cert-verify")
+
+	switch typ {
+	case "ss":
+		node["cipher"], node["password"] = pos[0], unquoteConf(pos[1])
+		if mode := strings.ToLower(kv["obfs-name"]); mode == "http" || mode == "tls" {
+			node["plugin"] = "obfs"
+			node["plugin-opts"] = map[string]any{"mode": mode, "host": kv["obfs-host"]}
+		}
+	case "ssr":
+		node["cipher"], node["password"] = pos[0], unquoteConf(pos[1])
+		node["protocol"] = cmp.Or(kv["protocol"], "origin")
+		node["protocol-param"] = kv["protocol-param"]
+		node["obfs"] = cmp.Or(kv["obfs"], "plain")
+		node["obfs-param"] = kv["obfs-param"]
+	case "vmess":
+		node["cipher"], node["uuid"] = vmessCipher(pos[0]), unquoteConf(pos[1])
+		node["alterId"] = ToIntPort(cmp.Or(kv["alterid"], "0"))
+	case "vless":
+		node["uuid"] = unquoteConf(pos[0])
+		if flow := kv["flow"]; flow != "" {
+			node["flow"] = flow
+		}
+		if pub := kv["public-key"]; pub != "" {
+			node["tls"] = true
+			node["reality-opts"] = map[string]any{"public-key": pub, "short-id": kv["short-id"]}
+		}
+	case "trojan", "hysteria2":
+		node["password"] = unquoteConf(pos[0])
+		if bw := kv["download-bandwidth"]; bw != "" && typ == "hysteria2" {
+			node["down"] = bw + " Mbps"
+		}
+	}
+
+	if typ == "vmess" || typ == "vless" || typ == "trojan" {
+		applyConfTransport(node, kv["transport"], kv["path"], kv["host"])
+		setConfBool(node, "tls", kv, "over-tls")
+	}
+	if typ != "ss" && typ != "ssr" {
+		setConfSNI(node, cmp.Or(kv["sni"], kv["tls-name"]))
+	}
+
+	NormalizeNode(node)
+	return node
+}
+
+// parseSurgeArgs 解析 Surge key=value 写法
+func parseSurgeArgs(name string, args []string) map[string]any {
+	typ := strings.ToLower(args[0])
+	rest := args[3:]
+	kv := confKV(rest)
+
+	node := map[string]any{
+		"name":   name,
+		"server": args[1],
+		"port":   ToIntPort(args[2]),
+	}
+	setConfBool(node, "udp", kv, "udp-relay", "udp")
+	setConfBool(node, "tfo", kv, "tfo")
+	setConfBool(node, "skip-cert-verify", kv, "skip-cert-verify")
+
+	switch typ {
+	case "ss", "shadowsocks":
+		node["type"] = "ss"
+		node["cipher"] = kv["encrypt-method"]
+		node["password"] = kv["password"]
+		if mode := strings.ToLower(kv["obfs"]); mode == "http" || mode == "tls" {
+			node["plugin"] = "obfs"
+			node["plugin-opts"] = map[string]any{"mode": mode, "host": kv["obfs-host"]}
+		}
+	case "vmess":
+		node["type"] = "vmess"
+		node["uuid"] = kv["username"]
+		node["cipher"] = vmessCipher(kv["encrypt-method"])
+		node["alterId"] = 0
+		setConfBool(node, "tls", kv, "tls")
+	case "trojan":
+		node["type"] = "trojan"
+		node["password"] = kv["password"]
+	case "tuic", "tuic-v5":
+		node["type"] = "tuic"
+		if token := kv["token"]; token != "" {
+			node["token"] = token
+		} else {
+			node["uuid"] = kv["uuid"]
+			node["password"] = kv["password"]
+		}
+		if alpn := kv["alpn"]; alpn != "" {
+			node["alpn"] = []any{alpn}
+		}
+	case "hysteria2", "hy2":
+		node["type"] = "hysteria2"
+		node["password"] = kv["password"]
+		if bw := kv["download-bandwidth"]; bw != "" {
+			node["down"] = bw + " Mbps"
+		}
+	case "snell":
+		node["type"] = "snell"
+		node["psk"] = kv["psk"]
+		if v := kv["version"]; v != "" {
+			node["version"] = ToIntPort(v)
+		}
+		if mode := strings.ToLower(kv["obfs"]); mode == "http" || mode == "tls" {
+			node["obfs-opts"] = map[string]any{"mode": mode, "host": kv["obfs-host"]}
+		}
+	case "http", "https", "socks5", "socks5-tls":
+		node["type"] = strings.TrimSuffix(typ, "-tls")
+		if typ == "https" || typ == "socks5-tls" {
+			node["tls"] = true
+		}
+		// 用户名与密码可为位置参数
+		if len(rest) >= 2 && !strings.Contains(rest[0], "=") && !strings.Contains(rest[1], "=") {
+			node["username"], node["password"] = unquoteConf(rest[0]), unquoteConf(rest[1])
+		} else if user := kv["username"]; user != "" {
+			node["username"], node["password"] = user, kv["password"]
+		}
+		setConfBool(node, "tls", kv, "over-tls", "tls")
+	default:
+		// direct、reject、wireguard 等非节点或需额外段落的类型
+		return nil
+	}
+
+	if ToBool(kv["ws"]) {
+		applyConfTransport(node, "ws", kv["ws-path"], "")
+		if h := kv["ws-headers"]; h != "" {
+			node["ws-opts"].(map[string]any)["headers"] = parseWsHeaders(h)
+		}
+	}
+	switch node["type"] {
+	case "ss", "snell", "socks5":
+	default:
+		setConfSNI(node, kv["sni"])
+	}
+
+	NormalizeNode(node)
+	return node
+}
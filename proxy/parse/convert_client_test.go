@@ -0,0 +1,150 @@
+package parse
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func readFixture(t *testing.T, name string) []byte {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+// byName 按节点名称索引，便于逐项断言
+func byName(nodes []map[string]any) map[string]map[string]any {
+	out := make(map[string]map[string]any, len(nodes))
+	for _, n := range nodes {
+		out[n["name"].(string)] = n
+	}
+	return out
+}
+
+// expectFields 断言节点包含给定字段与值
+func expectFields(t *testing.T, nodes map[string]map[string]any, name string, want map[string]any) {
+	t.Helper()
+	n, ok := nodes[name]
+	if !ok {
+		t.Errorf("missing node %s", name)
+		return
+	}
+	for k, v := range want {
+		if !reflect.DeepEqual(n[k], v) {
+			t.Errorf("%s.%s = %#v, want %#v", name, k, n[k], v)
+		}
+	}
+}
+
+func TestParseQuantumultXProxies(t *testing.T) {
+	nodes := byName(ParseQuantumultXProxies(readFixture(t, "quantumultx.conf")))
+	if len(nodes) != 6 {
+		t.Fatalf("got %d nodes, want 6", len(nodes))
+	}
+	expectFields(t, nodes, "QX-SS", map[string]any{
+		"type": "ss", "server": "ss.example.com", "port": 8388, "cipher": "chacha20-ietf-poly1305",
+		"udp": true, "plugin": "obfs", "plugin-opts": map[string]any{"mode": "http", "host": "bing.com"},
+	})
+	expectFields(t, nodes, "QX-SSR", map[string]any{
+		"type": "ssr", "protocol": "auth_chain_a", "obfs": "tls1.2_ticket_auth", "obfs-param": "cloudflare.com",
+	})
+	expectFields(t, nodes, "QX-VMess", map[string]any{
+		"type": "vmess", "uuid": "b831381d-6324-4d53-ad4f-8cda48b30811", "cipher": "chacha20-poly1305",
+		"tls": true, "skip-cert-verify": true, "network": "ws", "servername": "cdn.example.com",
+		"ws-opts": map[string]any{"path": "/ws", "headers": map[string]any{"Host": "cdn.example.com"}},
+	})
+	expectFields(t, nodes, "QX-VLESS", map[string]any{
+		"type": "vless", "flow": "xtls-rprx-vision", "tls": true,
+		"reality-opts": map[string]any{"public-key": "k4Hq", "short-id": "0123"},
+	})
+	expectFields(t, nodes, "QX-Trojan", map[string]any{
+		"type": "trojan", "password": "pwd", "sni": "trojan.example.com", "skip-cert-verify": false,
+	})
+	expectFields(t, nodes, "QX-HTTP", map[string]any{
+		"type": "http", "username": "user", "password": "pass", "tls": true,
+	})
+}
+
+func TestParseLoonProxies(t *testing.T) {
+	data := readFixture(t, "loon.conf")
+	nodes := byName(ParseLoonProxies(data))
+	if len(nodes) != 6 {
+		t.Fatalf("got %d nodes, want 6", len(nodes))
+	}
+	expectFields(t, nodes, "Loon-SS", map[string]any{
+		"type": "ss", "cipher": "aes-128-gcm", "password": "pwd", "udp": true, "tfo": false,
+		"plugin-opts": map[string]any{"mode": "http", "host": "bing.com"},
+	})
+	expectFields(t, nodes, "Loon-SSR", map[string]any{
+		"type": "ssr", "protocol": "auth_aes128_md5", "protocol-param": "1:abc", "obfs-param": "cloudflare.com",
+	})
+	expectFields(t, nodes, "Loon-VMess", map[string]any{
+		"type": "vmess", "cipher": "auto", "alterId": 0, "tls": true, "servername": "cdn.example.com",
+		"network": "ws", "ws-opts": map[string]any{"path": "/ws", "headers": map[string]any{"Host": "cdn.example.com"}},
+	})
+	expectFields(t, nodes, "Loon-VLESS", map[string]any{
+		"type": "vless", "uuid": "b831381d-6324-4d53-ad4f-8cda48b30811", "servername": "www.microsoft.com",
+		"reality-opts": map[string]any{"public-key": "k4Hq", "short-id": "0123"},
+	})
+	expectFields(t, nodes, "Loon-Trojan", map[string]any{"type": "trojan", "tls": true, "sni": "trojan.example.com"})
+	expectFields(t, nodes, "Loon-Hy2", map[string]any{"type": "hysteria2", "port": 8443, "down": "100 Mbps"})
+
+	// Loon 写法不应被 Surge 解析器重复识别
+	if n := len(ParseSurgeProxies(data)); n != 0 {
+		t.Errorf("surge parser picked %d loon lines", n)
+	}
+}
+
+func TestParseSurgeProxies(t *testing.T) {
+	data := readFixture(t, "surge.conf")
+	nodes := byName(ParseSurgeProxies(data))
+	if len(nodes) != 8 {
+		t.Fatalf("got %d nodes, want 8", len(nodes))
+	}
+	expectFields(t, nodes, "Surge-SS", map[string]any{
+		"type": "ss", "cipher": "aes-128-gcm", "plugin": "obfs", "udp": true,
+	})
+	expectFields(t, nodes, "Surge-VMess", map[string]any{
+		"type": "vmess", "tls": true, "servername": "cdn.example.com", "network": "ws",
+		"ws-opts": map[string]any{"path": "/ws", "headers": map[string]any{"Host": "cdn.example.com", "X-Forwarded-For": "1.1.1.1"}},
+	})
+	expectFields(t, nodes, "Surge-Trojan", map[string]any{"type": "trojan", "sni": "trojan.example.com", "skip-cert-verify": true})
+	expectFields(t, nodes, "Surge-TUIC", map[string]any{"type": "tuic", "token": "tk", "alpn": []any{"h3"}})
+	expectFields(t, nodes, "Surge-TUIC5", map[string]any{"type": "tuic", "uuid": "b831381d-6324-4d53-ad4f-8cda48b30811", "password": "pwd"})
+	expectFields(t, nodes, "Surge-Hy2", map[string]any{"type": "hysteria2", "sni": "hy2.example.com", "down": "200 Mbps"})
+	expectFields(t, nodes, "Surge-Snell", map[string]any{
+		"type": "snell", "psk": "secret", "version": 4, "obfs-opts": map[string]any{"mode": "http", "host": "bing.com"},
+	})
+	expectFields(t, nodes, "Surge-HTTPS", map[string]any{"type": "http", "tls": true, "username": "user", "password": "p,ass"})
+
+	if n := len(ParseLoonProxies(data)); n != 0 {
+		t.Errorf("loon parser picked %d surge lines", n)
+	}
+}
+
+func TestParseSubscriptionDataStreamClientConf(t *testing.T) {
+	for name, want := range map[string]int{"quantumultx.conf": 6, "loon.conf": 6, "surge.conf": 8} {
+		data := readFixture(t, name)
+		var got int
+		stats, err := ParseSubscriptionDataStream(data, "https://example.com/"+name, func(map[string]any) bool {
+			got++
+			return true
+		})
+		if err != nil {
+			t.Errorf("%s: %v", name, err)
+			continue
+		}
+		if got != want {
+			t.Errorf("%s: got %d nodes, want %d (stats %v)", name, got, want, stats)
+		}
+
+		nodes, err := parseLineBasedFormats(data, name)
+		if err != nil || len(nodes) != want {
+			t.Errorf("%s: parseLineBasedFormats got %d nodes, err %v", name, len(nodes), err)
+		}
+	}
+}
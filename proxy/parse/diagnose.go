@@ -0,0 +1,489 @@
+package parse
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"maps"
+	"net"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/goccy/go-json"
+)
+
+// 解析诊断：记录订阅的识别格式、条目数与转换数，以及未能转换的行和原因，
+// 用于排查"订阅拉取成功却没有节点"一类问题。
+
+const (
+	diagMaxSamples   = 10    // 每个订阅保留的失败样例数
+	diagMaxSampleLen = 160   // 样例截断长度（字符）
+	diagMaxProbe     = 20000 // 逐行检查的最大行数
+	diagRedacted     = "***" // 样例中替代凭据等敏感内容
+)
+
+// 失败原因
+const (
+	ReasonMissingType     = "missing type"
+	ReasonMissingServer   = "missing server"
+	ReasonMissingPort     = "missing port"
+	ReasonBadBase64       = "bad base64"
+	ReasonBadJSON         = "bad json"
+	ReasonUnknownCipher   = "unknown cipher"
+	ReasonUnsupported     = "unsupported protocol"
+	ReasonInvalidURL      = "invalid url"
+	ReasonUnrecognized    = "unrecognized line"
+	ReasonMalformedConfig = "malformed entry"
+)
+
+// Diagnostics 单个订阅的解析诊断
+type Diagnostics struct {
+	ID           string         `yaml:"id" json:"id"`
+	URL          string         `yaml:"url" json:"url"`
+	Error        string         `yaml:"error,omitempty" json:"error,omitempty"`   // 拉取或解析失败原因
+	Format       string         `yaml:"format,omitempty" json:"format,omitempty"` // 命中的格式，多个以 + 连接
+	Cached       bool           `yaml:"cached,omitempty" json:"cached,omitempty"` // 订阅未变化，复用缓存节点
+	Entries      int            `yaml:"entries" json:"entries"`                   // 看到的行或条目数
+	Converted    int            `yaml:"converted" json:"converted"`               // 解析产出的节点数
+	Accepted     int            `yaml:"accepted" json:"accepted"`                 // 通过校验与过滤后入队的节点数
+	TypeFiltered int            `yaml:"type-filtered,omitempty" json:"typeFiltered,omitempty"`
+	PreFiltered  int            `yaml:"pre-filtered,omitempty" json:"preFiltered,omitempty"`
+	Unsupported  map[string]int `yaml:"unsupported,omitempty" json:"unsupported,omitempty"` // 不支持的协议及数量
+	Reasons      map[string]int `yaml:"reasons,omitempty" json:"reasons,omitempty"`         // 解析失败原因及数量
+	Rejected     map[string]int `yaml:"rejected,omitempty" json:"rejected,omitempty"`       // 节点校验拒绝原因及数量
+	Repaired     int            `yaml:"repaired,omitempty" json:"repaired,omitempty"`       // 校验时修复的节点数
+	Samples      []FailedSample `yaml:"samples,omitempty" json:"samples,omitempty"`
+	UpdatedAt    time.Time      `yaml:"updated-at" json:"updatedAt"`
+}
+
+// FailedSample 失败样例
+type FailedSample struct {
+	Line   string `yaml:"line" json:"line"`
+	Reason string `yaml:"reason" json:"reason"`
+}
+
+// NewDiagnostics 创建订阅的诊断记录
+func NewDiagnostics(subURL string) *Diagnostics {
+	return &Diagnostics{URL: subURL, UpdatedAt: time.Now()}
+}
+
+// Fail 记录一条失败及其样例，d 为 nil 时忽略
+func (d *Diagnostics) Fail(reason, sample string) {
+	if d == nil {
+		return
+	}
+	if d.Reasons == nil {
+		d.Reasons = make(map[string]int)
+	}
+	d.Reasons[reason]++
+	d.sample(reason, sample)
+}
+
+func (d *Diagnostics) sample(reason, sample string) {
+	if len(d.Samples) < diagMaxSamples {
+		if r := []rune(sample); len(r) > diagMaxSampleLen {
+			sample = string(r[:diagMaxSampleLen]) + "…"
+		}
+		d.Samples = append(d.Samples, FailedSample{Line: sample, Reason: reason})
+	}
+}
+
+func (d *Diagnostics) count(m *map[string]int, key string) {
+	if *m == nil {
+		*m = make(map[string]int)
+	}
+	(*m)[key]++
+}
+
+// NodeDefect 返回节点缺失的必要字段，完整时返回空
+func NodeDefect(node map[string]any) string {
+	if node["type"] == nil {
+		return ReasonMissingType
+	}
+	if typ, _ := node["type"].(string); serverlessTypes[typ] {
+		return ""
+	}
+	server := strings.TrimSpace(fmt.Sprintf("%v", node["server"]))
+	if server == "" || server == "<nil>" {
+		return ReasonMissingServer
+	}
+	if port := ToIntPort(node["port"]); port <= 0 || port > 65535 {
+		return ReasonMissingPort
+	}
+	return ""
+}
+
+// Reject 记录未通过校验的节点，d 为 nil 时忽略
+func (d *Diagnostics) Reject(node map[string]any, reason string) {
+	if d == nil {
+		return
+	}
+	d.count(&d.Rejected, reason)
+	if reason == ReasonUnsupported {
+		d.count(&d.Unsupported, fmt.Sprint(node["type"]))
+	}
+	d.sample(reason, fmt.Sprintf("%v %v:%v %v", node["type"], node["server"], node["port"], node["name"]))
+}
+
+// Validate 校验节点并记录拒绝原因与修复数，返回非空原因时节点应丢弃
+func (d *Diagnostics) Validate(node map[string]any) string {
+	reason, repaired := ValidateNode(node)
+	if reason != "" {
+		d.Reject(node, reason)
+	} else if repaired && d != nil {
+		d.Repaired++
+	}
+	return reason
+}
+
+// RejectedCount 校验拒绝的节点总数
+func (d *Diagnostics) RejectedCount() int {
+	if d == nil {
+		return 0
+	}
+	n := 0
+	for _, c := range d.Rejected {
+		n += c
+	}
+	return n
+}
+
+// Finish 汇总解析结果。stats 为解析器返回的统计，converted 为解析产出的节点数；
+// 行级格式转换数少于行数时逐行检查失败原因
+func (d *Diagnostics) Finish(data []byte, stats map[string]int, converted int) {
+	if d == nil {
+		return
+	}
+	d.Converted = converted
+
+	var formats []string
+	linear := len(stats) == 0
+	for k, n := range stats {
+		switch k {
+		case "LineDedup", "BatchDedup", "Entries":
+			continue
+		case "V2Ray-Base64", "RawLines", "StringList", "Fallback":
+			linear = true
+		}
+		if n > 0 {
+			formats = append(formats, k)
+		}
+	}
+	slices.Sort(formats)
+	d.Format = strings.Join(formats, "+")
+	if d.Format == "" {
+		d.Format = "unknown"
+		linear = true
+	}
+
+	if n, ok := stats["Entries"]; ok {
+		d.Entries = n
+		// 结构化格式中不是对象的条目
+		if n > converted {
+			if d.Reasons == nil {
+				d.Reasons = make(map[string]int)
+			}
+			d.Reasons[ReasonMalformedConfig] += n - converted
+		}
+		return
+	}
+	if !linear {
+		d.Entries = converted
+		return
+	}
+	d.probeLines(data)
+	d.Entries = max(d.Entries, converted)
+}
+
+// probeLines 逐行检查链接格式的订阅，记录明显无法转换的行
+func (d *Diagnostics) probeLines(data []byte) {
+	text := data
+	if !bytes.Contains(data, []byte("://")) {
+		decoded, err := TryDecodeBase64WithError(string(data))
+		if err != nil {
+			if looksLikeBase64(data) {
+				d.Entries = 1
+				d.Fail(ReasonBadBase64, diagRedacted)
+				return
+			}
+		} else {
+			text = decoded
+		}
+	}
+
+	// 先计数，全部转换成功（纯 IP:端口 行会生成多个节点）时无需逐行检查
+	lines := 0
+	eachContentLine(text, func(string) bool { lines++; return true })
+	if lines == 0 {
+		return
+	}
+	d.Entries = lines
+	if d.Converted >= lines {
+		return
+	}
+	// 转换成功但在节点校验阶段被拒绝的行（如端口为空）已记录过，同类原因按数量抵消
+	recorded := maps.Clone(d.Rejected)
+	probed := 0
+	eachContentLine(text, func(line string) bool {
+		reason := probeLine(line)
+		if recorded[reason] > 0 {
+			recorded[reason]--
+			reason = ""
+		}
+		if reason != "" {
+			if reason == ReasonUnsupported {
+				scheme, _, _ := strings.Cut(line, "://")
+				d.count(&d.Unsupported, strings.ToLower(scheme))
+			}
+			d.Fail(reason, redactLine(line))
+		}
+		probed++
+		return probed < diagMaxProbe
+	})
+}
+
+// eachContentLine 遍历非空、非注释行，fn 返回 false 时停止
+func eachContentLine(text []byte, fn func(line string) bool) {
+	scanner := bufio.NewScanner(bytes.NewReader(text))
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, "//") {
+			continue
+		}
+		if !fn(line) {
+			return
+		}
+	}
+}
+
+// redactLine 去掉链接中的密码、UUID 等凭据，样例只保留协议、主机和端口
+func redactLine(line string) string {
+	scheme, rest, ok := strings.Cut(line, "://")
+	if !ok {
+		host, port := SplitHostPortLoose(strings.TrimLeft(line, "- "))
+		if host == "" || port == "" || !isDigit(port) {
+			return diagRedacted
+		}
+		return net.JoinHostPort(host, port)
+	}
+	scheme = strings.ToLower(scheme)
+	host, port := lineHostPort(scheme, rest)
+	switch {
+	case host == "":
+		return scheme + "://" + diagRedacted
+	case port == "":
+		return scheme + "://" + host
+	}
+	return scheme + "://" + net.JoinHostPort(host, port)
+}
+
+// lineHostPort 尽量从链接中取出主机和端口，无法识别时返回空
+func lineHostPort(scheme, rest string) (host, port string) {
+	rest, _, _ = strings.Cut(rest, "#")
+	payload, _, _ := strings.Cut(rest, "?")
+	switch {
+	case scheme == "vmess" && !strings.Contains(rest, "@"):
+		decoded, err := TryDecodeBase64WithError(payload)
+		if err != nil {
+			return "", ""
+		}
+		var m map[string]any
+		if json.Unmarshal(decoded, &m) != nil || m["add"] == nil {
+			return "", ""
+		}
+		if p := ToIntPort(m["port"]); p > 0 {
+			port = strconv.Itoa(p)
+		}
+		return fmt.Sprint(m["add"]), port
+	case scheme == "ssr":
+		decoded, err := TryDecodeBase64WithError(payload)
+		if err != nil {
+			return "", ""
+		}
+		main, _, _ := strings.Cut(string(decoded), "/")
+		parts := strings.Split(main, ":")
+		if len(parts) < 6 {
+			return "", ""
+		}
+		return strings.Join(parts[:len(parts)-5], ":"), parts[len(parts)-5]
+	case scheme == "ss" && !strings.Contains(rest, "@"):
+		decoded, err := TryDecodeBase64WithError(strings.TrimSuffix(payload, "/"))
+		if err != nil {
+			return "", ""
+		}
+		payload = string(decoded)
+	}
+	authority, _, _ := strings.Cut(payload, "/")
+	if _, hp, ok := cutLast(authority, "@"); ok {
+		authority = hp
+	}
+	host, port = SplitHostPortLoose(authority)
+	if port != "" && !isDigit(port) {
+		port = ""
+	}
+	return host, port
+}
+
+// probeLine 检查单行链接，返回失败原因，看起来可以转换时返回空
+func probeLine(line string) string {
+	scheme, rest, ok := strings.Cut(line, "://")
+	if !ok {
+		host, port := SplitHostPortLoose(strings.TrimLeft(line, "- "))
+		if host != "" && port != "" && isDigit(port) {
+			return ""
+		}
+		return ReasonUnrecognized
+	}
+	scheme = strings.ToLower(scheme)
+	if !knownSchemes[scheme] {
+		return ReasonUnsupported
+	}
+	rest, _, _ = strings.Cut(rest, "#")
+
+	switch scheme {
+	case "vmess":
+		if strings.Contains(rest, "@") {
+			break // 非 base64 的 vmess 链接按 URL 检查
+		}
+		payload, _, _ := strings.Cut(rest, "?")
+		decoded, err := TryDecodeBase64WithError(payload)
+		if err != nil {
+			return ReasonBadBase64
+		}
+		var m map[string]any
+		if err := json.Unmarshal(decoded, &m); err != nil {
+			return ReasonBadJSON
+		}
+		if m["add"] == nil || fmt.Sprint(m["add"]) == "" {
+			return ReasonMissingServer
+		}
+		if p := ToIntPort(m["port"]); p <= 0 || p > 65535 {
+			return ReasonMissingPort
+		}
+		return ""
+	case "ss":
+		return probeSS(rest)
+	case "ssr":
+		payload, _, _ := strings.Cut(rest, "?")
+		decoded, err := TryDecodeBase64WithError(payload)
+		if err != nil {
+			return ReasonBadBase64
+		}
+		// server:port:protocol:method:obfs:password_base64/?params，server 可能是 IPv6
+		main, _, _ := strings.Cut(string(decoded), "/")
+		parts := strings.Split(main, ":")
+		if len(parts) < 6 {
+			return ReasonMalformedConfig
+		}
+		if port := parts[len(parts)-5]; port == "" || !isDigit(port) {
+			return ReasonMissingPort
+		}
+		return ""
+	}
+
+	u, err := url.Parse(scheme + "://" + rest)
+	if err != nil {
+		return ReasonInvalidURL
+	}
+	if u.Hostname() == "" {
+		return ReasonMissingServer
+	}
+	if u.Port() == "" {
+		return ReasonMissingPort
+	}
+	return ""
+}
+
+// probeSS 检查 ss 链接，支持 SIP002 与整体 base64 两种写法
+func probeSS(rest string) string {
+	rest, _, _ = strings.Cut(rest, "?")
+	rest = strings.TrimSuffix(rest, "/")
+	userinfo, hostport, ok := cutLast(rest, "@")
+	if !ok {
+		decoded, err := TryDecodeBase64WithError(rest)
+		if err != nil {
+			return ReasonBadBase64
+		}
+		if userinfo, hostport, ok = cutLast(string(decoded), "@"); !ok {
+			return ReasonMissingServer
+		}
+	}
+	if u, err := url.PathUnescape(userinfo); err == nil {
+		userinfo = u
+	}
+	if !strings.Contains(userinfo, ":") {
+		decoded, err := TryDecodeBase64WithError(userinfo)
+		if err != nil {
+			return ReasonBadBase64
+		}
+		userinfo = string(decoded)
+	}
+	method, _, _ := strings.Cut(userinfo, ":")
+	if !knownCiphers[strings.ToLower(method)] {
+		return ReasonUnknownCipher
+	}
+	host, port := SplitHostPortLoose(hostport)
+	if host == "" {
+		return ReasonMissingServer
+	}
+	if port == "" || !isDigit(port) {
+		return ReasonMissingPort
+	}
+	return ""
+}
+
+func cutLast(s, sep string) (before, after string, found bool) {
+	if i := strings.LastIndex(s, sep); i >= 0 {
+		return s[:i], s[i+len(sep):], true
+	}
+	return s, "", false
+}
+
+// looksLikeBase64 内容是否只由 base64 字符组成
+func looksLikeBase64(data []byte) bool {
+	data = bytes.TrimSpace(data)
+	if len(data) < 16 {
+		return false
+	}
+	for _, c := range data {
+		switch {
+		case c >= 'A' && c <= 'Z', c >= 'a' && c <= 'z', c >= '0' && c <= '9':
+		case c == '+', c == '/', c == '=', c == '-', c == '_', c == '\n', c == '\r':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// knownSchemes 可识别的协议名与链接头
+var knownSchemes = func() map[string]bool {
+	m := map[string]bool{"socks5": true, "tuic": true, "mierus": true, "tailscale": true}
+	for k, v := range protocolSchemes {
+		m[k] = true
+		m[strings.TrimSuffix(v, "://")] = true
+	}
+	return m
+}()
+
+// knownCiphers mihomo 支持的 Shadowsocks 加密方式
+var knownCiphers = func() map[string]bool {
+	m := make(map[string]bool)
+	for _, c := range []string{
+		"none", "plain", "dummy", "rc4-md5", "rc4",
+		"aes-128-gcm", "aes-192-gcm", "aes-256-gcm",
+		"aes-128-cfb", "aes-192-cfb", "aes-256-cfb",
+		"aes-128-ctr", "aes-192-ctr", "aes-256-ctr",
+		"chacha20", "chacha20-ietf", "xchacha20",
+		"chacha20-poly1305", "chacha20-ietf-poly1305", "xchacha20-ietf-poly1305",
+		"aead_aes_128_gcm", "aead_aes_192_gcm", "aead_aes_256_gcm", "aead_chacha20_poly1305",
+		"2022-blake3-aes-128-gcm", "2022-blake3-aes-256-gcm", "2022-blake3-chacha20-poly1305",
+	} {
+		m[c] = true
+	}
+	return m
+}()
@@ -0,0 +1,119 @@
+package parse
+
+import (
+	"encoding/base64"
+	"maps"
+	"strings"
+	"testing"
+)
+
+// diagnose 走一遍流式解析并汇总诊断
+func diagnose(t *testing.T, data []byte) *Diagnostics {
+	t.Helper()
+	d := NewDiagnostics("https://example.com/sub")
+	converted := 0
+	stats, _ := ParseSubscriptionDataStream(data, d.URL, func(node map[string]any) bool {
+		converted++
+		NormalizeNode(node)
+		d.Validate(node)
+		return true
+	})
+	d.Finish(data, stats, converted)
+	return d
+}
+
+func TestDiagnoseLinks(t *testing.T) {
+	ssGood := "ss://" + base64.RawURLEncoding.EncodeToString([]byte("aes-128-gcm:pwd")) + "@ss.example.com:8388#good"
+	lines := []string{
+		ssGood,
+		"ss://" + base64.RawURLEncoding.EncodeToString([]byte("rot13:pwd")) + "@ss.example.com:8389#cipher",
+		"vmess://not*base64",
+		"trojan://pwd@trojan.example.com#noport",
+		"foo://bar@example.com:1#unknown",
+		"剩余流量：10GB",
+	}
+	data := []byte(base64.StdEncoding.EncodeToString([]byte(strings.Join(lines, "\n"))))
+	d := diagnose(t, data)
+
+	if d.Entries != len(lines) {
+		t.Errorf("entries = %d, want %d", d.Entries, len(lines))
+	}
+	if d.Converted < 1 || d.Converted >= len(lines) {
+		t.Errorf("converted = %d", d.Converted)
+	}
+	failed := maps.Clone(d.Reasons)
+	for reason, n := range d.Rejected {
+		failed[reason] += n
+	}
+	for _, reason := range []string{ReasonUnknownCipher, ReasonBadBase64, ReasonMissingPort, ReasonUnsupported, ReasonUnrecognized} {
+		if failed[reason] != 1 {
+			t.Errorf("reason %q = %d, reasons %v, rejected %v", reason, failed[reason], d.Reasons, d.Rejected)
+		}
+	}
+	if d.Unsupported["foo"] != 1 {
+		t.Errorf("unsupported = %v", d.Unsupported)
+	}
+	if len(d.Samples) != 5 {
+		t.Errorf("samples = %v", d.Samples)
+	}
+	for _, s := range d.Samples {
+		if strings.Contains(s.Line, "pwd") || strings.Contains(s.Line, "bar@") {
+			t.Errorf("sample leaks credentials: %q", s.Line)
+		}
+	}
+}
+
+func TestRedactLine(t *testing.T) {
+	vmess := "vmess://" + base64.StdEncoding.EncodeToString([]byte(`{"add":"v.example.com","port":"443","id":"b831381d-6324-4d53-ad4f-8cda48b30811"}`))
+	ssr := "ssr://" + base64.RawURLEncoding.EncodeToString([]byte("r.example.com:8989:origin:aes-256-cfb:plain:cGFzcw/?remarks=eA"))
+	ss := "ss://" + base64.StdEncoding.EncodeToString([]byte("rot13:secret@[2001:db8::1]:8388"))
+	cases := map[string]string{
+		"trojan://secret@t.example.com:443?sni=x#name":                   "trojan://t.example.com:443",
+		"vless://b831381d-6324-4d53-ad4f-8cda48b30811@1.2.3.4:8443/path": "vless://1.2.3.4:8443",
+		"hysteria2://secret@h.example.com#noport":                        "hysteria2://h.example.com",
+		vmess:                    "vmess://v.example.com:443",
+		ssr:                      "ssr://r.example.com:8989",
+		ss:                       "ss://[2001:db8::1]:8388",
+		"vmess://not*base64":     "vmess://***",
+		"1.2.3.4:80":             "1.2.3.4:80",
+		"token=abcdef1234567890": "***",
+	}
+	for line, want := range cases {
+		if got := redactLine(line); got != want {
+			t.Errorf("redactLine(%q) = %q, want %q", line, got, want)
+		}
+	}
+}
+
+func TestDiagnoseClash(t *testing.T) {
+	data := []byte(`proxies:
+  - {name: ok, type: ss, server: a.example.com, port: 8388, cipher: aes-128-gcm, password: p}
+  - {name: noport, type: trojan, server: b.example.com, password: p}
+  - {name: weird, type: ss, server: c.example.com, port: 1, cipher: rot13, password: p}
+  - {name: future, type: quantum, server: d.example.com, port: 1}
+  - "not a proxy"
+`)
+	d := diagnose(t, data)
+	if d.Format != "Mihomo/Clash" || d.Entries != 5 || d.Converted != 4 {
+		t.Fatalf("got %+v", d)
+	}
+	if d.Reasons[ReasonMalformedConfig] != 1 {
+		t.Errorf("reasons = %v", d.Reasons)
+	}
+	if d.Rejected[ReasonMissingPort] != 1 || d.Rejected[ReasonUnknownCipher] != 1 || d.Rejected[ReasonUnsupported] != 1 {
+		t.Errorf("rejected = %v", d.Rejected)
+	}
+	if d.Unsupported["quantum"] != 1 || d.RejectedCount() != 3 {
+		t.Errorf("unsupported %v, rejected %d", d.Unsupported, d.RejectedCount())
+	}
+}
+
+func TestDiagnoseBadBase64(t *testing.T) {
+	d := diagnose(t, []byte("dm1lc3M6Ly9hYmNk!!!!ZGVmZ2hpamtsbW5vcA"))
+	if d.Format != "unknown" {
+		t.Errorf("format = %q", d.Format)
+	}
+	if d.Reasons[ReasonUnrecognized]+d.Reasons[ReasonBadBase64] != 1 {
+		t.Errorf("reasons = %v", d.Reasons)
+	}
+}
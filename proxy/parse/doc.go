@@ -3,7 +3,10 @@
 //   - parse.go：解析调度与格式路由（parseSubscriptionData、parseLineBasedFormats）
 //   - convert.go：各格式到 map[string]any 的具体转换实现
 //   - convert_extra.go：上游暂未支持的非标协议扩展（mieru、anytls 等）
+//   - convert_client.go：Quantumult X、Loon、Surge 客户端配置中的节点行
 //   - normalize.go：节点字段语义修正（NormalizeNode 及相关工具函数）
+//   - diagnose.go：解析诊断（识别格式、条目与转换数、失败行样例及原因）
+//   - validate.go：节点校验（按协议检查 UUID、REALITY 公钥与 short-id、加密方式、密码等，修复或给出拒绝原因）
 //   - codec.go：编解码与 URL 工具（Base64、HostPort 分割、协议猜测）
 //   - url_utils.go：URL 字符串处理（CleanURL、NormalizeGitHubRawURL、日志辅助）
 //
@@ -105,6 +105,16 @@ func ParseSubscriptionData(data []byte, subURL string) ([]map[string]any, error)
 	return parseLineBasedFormats(data, subURL)
 }
 
+// clientConfParsers 客户端配置方言解析器，format 同时用作日志与统计的 key
+var clientConfParsers = []struct {
+	format string
+	parse  func([]byte) []map[string]any
+}{
+	{"QuantumultX", ParseQuantumultXProxies},
+	{"Loon", ParseLoonProxies},
+	{"Surge", ParseSurgeProxies},
+}
+
 // parseLineBasedFormats 处理所有行级格式，收集全部结果后去重合并
 func parseLineBasedFormats(data []byte, subURL string) ([]map[string]any, error) {
 	seen := make(map[string]struct{})
@@ -145,17 +155,31 @@ func parseLineBasedFormats(data []byte, subURL string) ([]map[string]any, error)
 	// ④ 逐行 YAML flow 格式
 	add(ParseYamlFlowList(data), "YAML Flow List")
 
-	// ⑤ Surge/Surfboard
-	if bytes.Contains(data, []byte("=")) &&
+	// ⑤ Quantumult X / Loon / Surge 客户端配置
+	//    命中后不再交给 ⑥⑧ 的通用 KV 解析，避免同一行被粗略解析出字段不全的重复节点
+	clientHit := false
+	if bytes.Contains(data, []byte("=")) {
+		for _, p := range clientConfParsers {
+			if nodes := p.parse(data); len(nodes) > 0 {
+				clientHit = true
+				add(nodes, p.format)
+			}
+		}
+	}
+
+	// ⑥ Surfboard
+	if !clientHit && bytes.Contains(data, []byte("=")) &&
 		(bytes.Contains(data, []byte("[VMess]")) || bytes.Contains(data, []byte(", 20"))) {
 		add(ParseSurfboardProxies(data), "Surfboard/Surge")
 	}
 
-	// ⑥ xray JSON lines
+	// ⑦ xray JSON lines
 	add(ParseV2RayJSONLines(data), "V2Ray JSON Lines")
 
-	// ⑦ Bracket KV 格式
-	add(ParseBracketKVProxies(data), "Bracket KV")
+	// ⑧ Bracket KV 格式
+	if !clientHit {
+		add(ParseBracketKVProxies(data), "Bracket KV")
+	}
 
 	if len(merged) > 0 {
 		slog.Debug("行级解析完成", "订阅", subURL, "总数量", len(merged))
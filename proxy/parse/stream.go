@@ -51,6 +51,7 @@ func ParseSubscriptionDataStream(
 		case map[string]any:
 			if proxies, ok := val["proxies"].([]any); ok {
 				slog.Debug("解析成功", "订阅", subURL, "格式", "Mihomo/Clash")
+				stats["Entries"] = len(proxies) // 供诊断统计非对象条目
 				for i, p := range proxies {
 					proxies[i] = nil
 					if node, ok := p.(map[string]any); ok {
@@ -155,7 +156,19 @@ func ParseSubscriptionDataStream(
 			return stats, nil
 		}
 	}
-	if bytes.Contains(data, []byte("=")) &&
+	clientHit := false
+	if bytes.Contains(data, []byte("=")) {
+		for _, p := range clientConfParsers {
+			if nodes := p.parse(data); len(nodes) > 0 {
+				anyHit, clientHit = true, true
+				if !drainLine(nodes, p.format) {
+					stats["LineDedup"] = lineDeduped
+					return stats, nil
+				}
+			}
+		}
+	}
+	if !clientHit && bytes.Contains(data, []byte("=")) &&
 		(bytes.Contains(data, []byte("[VMess]")) || bytes.Contains(data, []byte(", 20"))) {
 		if nodes := ParseSurfboardProxies(data); len(nodes) > 0 {
 			anyHit = true
@@ -172,11 +185,13 @@ func ParseSubscriptionDataStream(
 			return stats, nil
 		}
 	}
-	if nodes := ParseBracketKVProxies(data); len(nodes) > 0 {
-		anyHit = true
-		if !drainLine(nodes, "BracketKV") {
-			stats["LineDedup"] = lineDeduped
-			return stats, nil
+	if !clientHit {
+		if nodes := ParseBracketKVProxies(data); len(nodes) > 0 {
+			anyHit = true
+			if !drainLine(nodes, "BracketKV") {
+				stats["LineDedup"] = lineDeduped
+				return stats, nil
+			}
 		}
 	}
 
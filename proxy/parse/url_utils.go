@@ -1,9 +1,13 @@
 package parse
 
 import (
-	"github.com/sinspired/subs-check-pro/v2/utils"
+	"bytes"
 	"net/url"
+	"path"
 	"strings"
+
+	"github.com/goccy/go-yaml"
+	"github.com/sinspired/subs-check-pro/v2/utils"
 )
 
 // NormalizeGitHubRawURL 将 GitHub 的 blob 或 raw 页面链接转换为 raw.githubusercontent.com 直链
@@ -80,3 +84,54 @@ func CleanURL(raw string) string {
 	// 3. 循环移除尾部所有属于 cutset 的字符，直到遇到非 cutset 字符为止
 	return strings.TrimRight(s, cutset)
 }
+
+// ExtractLinkedURLs 从任意文本中提取 http(s) 链接，依次收集 Clash proxy-providers、
+// Markdown 链接与纯文本 URL，已清洗、转换 GitHub 直链并去重
+func ExtractLinkedURLs(data []byte) []string {
+	seen := make(map[string]struct{})
+	var out []string
+	add := func(raw string) {
+		u := NormalizeGitHubRawURL(CleanURL(raw))
+		if _, ok := seen[u]; ok {
+			return
+		}
+		if parsed, err := url.Parse(u); err == nil && parsed.Host != "" &&
+			(parsed.Scheme == "http" || parsed.Scheme == "https") {
+			seen[u] = struct{}{}
+			out = append(out, u)
+		}
+	}
+
+	if bytes.Contains(data, []byte("proxy-providers")) || bytes.Contains(data, []byte("proxy_providers")) {
+		var generic map[string]any
+		if err := yaml.Unmarshal(data, &generic); err == nil {
+			for _, u := range ExtractClashProviderURLs(generic) {
+				add(u)
+			}
+		}
+	}
+	for _, u := range ExtractMarkdownURLs(data) {
+		add(u)
+	}
+	for _, u := range plainURLRegex.FindAll(data, -1) {
+		add(string(u))
+	}
+	return out
+}
+
+// nonSubscriptionExts 明显不是订阅内容的文件扩展名
+var nonSubscriptionExts = map[string]bool{
+	".png": true, ".jpg": true, ".jpeg": true, ".gif": true, ".svg": true, ".webp": true, ".ico": true,
+	".css": true, ".js": true, ".woff": true, ".woff2": true, ".ttf": true,
+	".mp4": true, ".mp3": true, ".pdf": true,
+	".zip": true, ".gz": true, ".7z": true, ".rar": true, ".exe": true, ".apk": true, ".dmg": true, ".msi": true, ".deb": true,
+}
+
+// LooksLikeSubscriptionURL 排除图片、脚本、安装包等明显不是订阅的链接
+func LooksLikeSubscriptionURL(rawURL string) bool {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") {
+		return false
+	}
+	return !nonSubscriptionExts[strings.ToLower(path.Ext(u.Path))]
+}
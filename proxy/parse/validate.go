@@ -0,0 +1,248 @@
+package parse
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// 节点校验：在 NormalizeNode 之后按协议检查必填字段与取值格式。
+// 能修复的就地修复（如 UUID 大小写、REALITY 公钥编码、short-id 数组），
+// 无法修复的返回拒绝原因，避免节点到 adapter.ParseProxy 时才失败并只留下 debug 日志。
+
+// 校验拒绝原因，与诊断中的解析失败原因共用命名风格
+const (
+	ReasonMissingPassword  = "missing password"
+	ReasonInvalidPassword  = "invalid password"
+	ReasonInvalidUUID      = "invalid uuid"
+	ReasonUnsupportedFlow  = "unsupported flow"
+	ReasonInvalidPublicKey = "invalid reality public-key"
+	ReasonInvalidShortID   = "invalid reality short-id"
+	ReasonInvalidKey       = "invalid key"
+)
+
+// mihomoTypes mihomo adapter.ParseProxy 接受的节点类型，未单独校验的类型原样交给 mihomo 判断
+var mihomoTypes = map[string]bool{
+	"ss": true, "ssr": true, "socks5": true, "http": true, "vmess": true, "vless": true, "snell": true,
+	"trojan": true, "hysteria": true, "hysteria2": true, "wireguard": true, "tuic": true, "shadowquic": true,
+	"gost-relay": true, "direct": true, "dns": true, "reject": true, "rematch": true, "ssh": true, "mieru": true,
+	"anytls": true, "sudoku": true, "masque": true, "trusttunnel": true, "openvpn": true, "tailscale": true, "zerotier": true,
+}
+
+// serverlessTypes 不需要 server 与 port 的节点类型（组网类）
+var serverlessTypes = map[string]bool{"tailscale": true, "zerotier": true}
+
+// vmessCiphers mihomo 支持的 VMess 加密方式
+var vmessCiphers = map[string]bool{"auto": true, "none": true, "zero": true, "aes-128-gcm": true, "chacha20-poly1305": true}
+
+// ValidateNode 校验并修复节点，返回拒绝原因（通过时为空）以及是否做过修复
+func ValidateNode(m map[string]any) (reason string, repaired bool) {
+	if reason = NodeDefect(m); reason != "" {
+		return reason, false
+	}
+	fix := func(key string, v any) {
+		m[key] = v
+		repaired = true
+	}
+
+	if s, ok := m["server"].(string); ok {
+		if t := strings.Trim(strings.TrimSpace(s), "[]"); t != s {
+			fix("server", t)
+		}
+	}
+	typ, _ := m["type"].(string)
+	if !mihomoTypes[typ] && !knownSchemes[typ] {
+		return ReasonUnsupported, repaired
+	}
+
+	switch typ {
+	case "ss":
+		cipher := strings.ToLower(strings.TrimSpace(str(m["cipher"])))
+		if cipher != m["cipher"] {
+			fix("cipher", cipher)
+		}
+		if !knownCiphers[cipher] {
+			return ReasonUnknownCipher, repaired
+		}
+		if cipher != "none" && cipher != "plain" && cipher != "dummy" {
+			if reason = requireString(m, "password", fix); reason != "" {
+				return reason, repaired
+			}
+		}
+		// 2022 系列的密码为 base64 编码的密钥，多用户写法以 : 分隔
+		if strings.HasPrefix(cipher, "2022-") {
+			for part := range strings.SplitSeq(str(m["password"]), ":") {
+				if _, err := base64.StdEncoding.DecodeString(part); err != nil {
+					return ReasonInvalidPassword, repaired
+				}
+			}
+		}
+	case "ssr":
+		if !knownCiphers[strings.ToLower(str(m["cipher"]))] {
+			return ReasonUnknownCipher, repaired
+		}
+		if reason = requireString(m, "password", fix); reason != "" {
+			return reason, repaired
+		}
+		if str(m["obfs"]) == "" {
+			fix("obfs", "plain")
+		}
+		if str(m["protocol"]) == "" {
+			fix("protocol", "origin")
+		}
+	case "vmess", "vless":
+		if reason = validateUUID(m, fix); reason != "" {
+			return reason, repaired
+		}
+		if typ == "vmess" {
+			if c := strings.ToLower(str(m["cipher"])); !vmessCiphers[c] {
+				fix("cipher", "auto")
+			}
+			if s, ok := m["alterId"].(string); ok {
+				fix("alterId", ToIntPort(s))
+			}
+		}
+		if typ == "vless" {
+			switch flow := str(m["flow"]); flow {
+			case "", "xtls-rprx-vision":
+			case "xtls-rprx-vision-udp443":
+				fix("flow", "xtls-rprx-vision")
+			default:
+				// xtls-rprx-direct / origin 等旧版 XTLS 流控已被移除
+				return ReasonUnsupportedFlow, repaired
+			}
+		}
+		if reason = validateReality(m, fix); reason != "" {
+			return reason, repaired
+		}
+	case "trojan", "anytls":
+		if reason = requireString(m, "password", fix); reason != "" {
+			return reason, repaired
+		}
+		if typ == "trojan" {
+			if reason = validateReality(m, fix); reason != "" {
+				return reason, repaired
+			}
+		}
+	case "hysteria2", "hy2":
+		if str(m["password"]) == "" && str(m["auth"]) != "" {
+			fix("password", str(m["auth"]))
+		}
+		if reason = requireString(m, "password", fix); reason != "" {
+			return reason, repaired
+		}
+	case "tuic":
+		// v5 使用 uuid + password，v4 使用 token
+		if str(m["token"]) == "" {
+			if reason = validateUUID(m, fix); reason != "" {
+				return reason, repaired
+			}
+			if reason = requireString(m, "password", fix); reason != "" {
+				return reason, repaired
+			}
+		}
+	case "snell":
+		if reason = requireString(m, "psk", fix); reason != "" {
+			return ReasonMissingPassword, repaired
+		}
+	case "wireguard", "wg":
+		key := str(m["private-key"])
+		if b, err := base64.StdEncoding.DecodeString(key); err != nil || len(b) != 32 {
+			return ReasonInvalidKey, repaired
+		}
+	}
+	return "", repaired
+}
+
+// requireString 检查必填的字符串字段，数字形式的密码转为字符串
+func requireString(m map[string]any, key string, fix func(string, any)) string {
+	v, ok := m[key]
+	if !ok || v == nil {
+		return ReasonMissingPassword
+	}
+	s, isString := v.(string)
+	if !isString {
+		s = str(v)
+		fix(key, s)
+	}
+	if s == "" {
+		return ReasonMissingPassword
+	}
+	return ""
+}
+
+// validateUUID 校验 uuid：标准格式统一为小写，32 位无连字符的补齐连字符；
+// mihomo 会将 1~30 字符的非标准字符串映射为 UUIDv5，同样放行
+func validateUUID(m map[string]any, fix func(string, any)) string {
+	raw := str(m["uuid"])
+	id := strings.ToLower(strings.TrimSpace(raw))
+	if len(id) == 32 && isHex(id) {
+		id = id[:8] + "-" + id[8:12] + "-" + id[12:16] + "-" + id[16:20] + "-" + id[20:]
+	}
+	switch {
+	case isUUID(id):
+		if id != raw {
+			fix("uuid", id)
+		}
+		return ""
+	case raw != "" && len(raw) <= 30:
+		return ""
+	}
+	return ReasonInvalidUUID
+}
+
+// validateReality 校验 reality-opts：公钥为 32 字节的 base64url，short-id 为不超过 16 位的偶数长度十六进制
+func validateReality(m map[string]any, fix func(string, any)) string {
+	opts, ok := m["reality-opts"].(map[string]any)
+	if !ok {
+		return ""
+	}
+
+	pub := strings.TrimSpace(str(opts["public-key"]))
+	normalized := strings.TrimRight(strings.NewReplacer("+", "-", "/", "_").Replace(pub), "=")
+	if b, err := base64.RawURLEncoding.DecodeString(normalized); err != nil || len(b) != 32 {
+		return ReasonInvalidPublicKey
+	}
+	if normalized != opts["public-key"] {
+		opts["public-key"] = normalized
+		fix("reality-opts", opts)
+	}
+
+	raw, hasSID := opts["short-id"]
+	if !hasSID {
+		return ""
+	}
+	sid := strings.ToLower(strings.TrimSpace(extractShortID(raw)))
+	if sid != "" && (len(sid) > 16 || len(sid)%2 != 0 || !isHex(sid)) {
+		return ReasonInvalidShortID
+	}
+	if sid != raw {
+		opts["short-id"] = sid
+		fix("reality-opts", opts)
+	}
+	return ""
+}
+
+func isUUID(s string) bool {
+	if len(s) != 36 || s[8] != '-' || s[13] != '-' || s[18] != '-' || s[23] != '-' {
+		return false
+	}
+	return isHex(s[:8] + s[9:13] + s[14:18] + s[19:23] + s[24:])
+}
+
+func isHex(s string) bool {
+	_, err := hex.DecodeString(s)
+	return err == nil && s != ""
+}
+
+// str 读取字段的字符串形式，nil 返回空
+func str(v any) string {
+	if v == nil {
+		return ""
+	}
+	if s, ok := v.(string); ok {
+		return s
+	}
+	return fmt.Sprint(v)
+}
@@ -0,0 +1,67 @@
+package parse
+
+import "testing"
+
+const realityKey = "SRoz-ljZ1ofJ4tV4adJm9prpmfA5o3jA8wgxNWAkN2c"
+
+func TestValidateNodeReject(t *testing.T) {
+	cases := []struct {
+		node map[string]any
+		want string
+	}{
+		{map[string]any{"type": "trojan", "server": "a.com", "port": 0, "password": "p"}, ReasonMissingPort},
+		{map[string]any{"type": "vless", "server": "a.com", "port": 443, "uuid": "this-is-definitely-not-a-valid-uuid-value"}, ReasonInvalidUUID},
+		{map[string]any{"type": "vless", "server": "a.com", "port": 443, "uuid": "b831381d-6324-4d53-ad4f-8cda48b30811", "flow": "xtls-rprx-direct"}, ReasonUnsupportedFlow},
+		{map[string]any{"type": "vless", "server": "a.com", "port": 443, "uuid": "b831381d-6324-4d53-ad4f-8cda48b30811",
+			"reality-opts": map[string]any{"public-key": "short"}}, ReasonInvalidPublicKey},
+		{map[string]any{"type": "vless", "server": "a.com", "port": 443, "uuid": "b831381d-6324-4d53-ad4f-8cda48b30811",
+			"reality-opts": map[string]any{"public-key": realityKey, "short-id": "xyz"}}, ReasonInvalidShortID},
+		{map[string]any{"type": "ss", "server": "a.com", "port": 8388, "cipher": "rot13", "password": "p"}, ReasonUnknownCipher},
+		{map[string]any{"type": "ss", "server": "a.com", "port": 8388, "cipher": "aes-128-gcm"}, ReasonMissingPassword},
+		{map[string]any{"type": "ss", "server": "a.com", "port": 8388, "cipher": "2022-blake3-aes-128-gcm", "password": "not base64!"}, ReasonInvalidPassword},
+		{map[string]any{"type": "hysteria2", "server": "a.com", "port": 443}, ReasonMissingPassword},
+		{map[string]any{"type": "wireguard", "server": "a.com", "port": 51820, "private-key": "abc"}, ReasonInvalidKey},
+		{map[string]any{"type": "quantum", "server": "a.com", "port": 1}, ReasonUnsupported},
+		// mihomo 支持但未单独校验的类型原样放行
+		{map[string]any{"type": "shadowquic", "server": "a.com", "port": 443, "password": "p"}, ""},
+		{map[string]any{"type": "gost-relay", "server": "a.com", "port": 443}, ""},
+		{map[string]any{"type": "zerotier", "network": "8056c2e21c000001"}, ""},
+		{map[string]any{"type": "tailscale", "auth-key": "k"}, ""},
+	}
+	for _, c := range cases {
+		if got, _ := ValidateNode(c.node); got != c.want {
+			t.Errorf("%v: got %q, want %q", c.node, got, c.want)
+		}
+	}
+}
+
+func TestValidateNodeRepair(t *testing.T) {
+	opts := map[string]any{"public-key": "SRoz+ljZ1ofJ4tV4adJm9prpmfA5o3jA8wgxNWAkN2c=", "short-id": []any{"ABCD"}}
+	node := map[string]any{
+		"type": "vless", "server": " [2001:db8::1] ", "port": 443,
+		"uuid": "B831381D63244D53AD4F8CDA48B30811", "flow": "xtls-rprx-vision-udp443", "reality-opts": opts,
+	}
+	reason, repaired := ValidateNode(node)
+	if reason != "" || !repaired {
+		t.Fatalf("reason %q, repaired %v", reason, repaired)
+	}
+	if node["server"] != "2001:db8::1" || node["uuid"] != "b831381d-6324-4d53-ad4f-8cda48b30811" || node["flow"] != "xtls-rprx-vision" {
+		t.Errorf("node = %v", node)
+	}
+	if opts["public-key"] != realityKey || opts["short-id"] != "abcd" {
+		t.Errorf("reality-opts = %v", opts)
+	}
+
+	hy := map[string]any{"type": "hysteria2", "server": "a.com", "port": 443, "auth": "secret"}
+	if reason, _ := ValidateNode(hy); reason != "" || hy["password"] != "secret" {
+		t.Errorf("hysteria2: %q %v", reason, hy)
+	}
+	ss := map[string]any{"type": "ss", "server": "a.com", "port": 8388, "cipher": "AES-128-GCM", "password": 123456}
+	if reason, _ := ValidateNode(ss); reason != "" || ss["cipher"] != "aes-128-gcm" || ss["password"] != "123456" {
+		t.Errorf("ss: %q %v", reason, ss)
+	}
+	// mihomo 会把短字符串映射为 UUID
+	if reason, repaired := ValidateNode(map[string]any{"type": "vmess", "server": "a.com", "port": 443, "uuid": "mypass", "cipher": "auto"}); reason != "" || repaired {
+		t.Errorf("vmess short uuid: %q %v", reason, repaired)
+	}
+}
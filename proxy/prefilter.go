@@ -0,0 +1,539 @@
+package proxies
+
+import (
+	"fmt"
+	"log/slog"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync/atomic"
+
+	"github.com/sinspired/subs-check-pro/v2/proxy/parse"
+)
+
+// 预筛选表达式语法：
+//
+//	字段      type server port network tls sni cipher name sub tag subname subtags，其余标识符按节点字段读取，支持 a.b 访问嵌套字段
+//	字面量    "str" 'str' 123 true false ["a","b"]
+//	比较      == != < <= > >=
+//	集合      in、not in（右侧为列表时判断成员，为字符串时判断子串）
+//	文本      matches（右侧为正则字面量）、contains
+//	逻辑      && || ! ( )
+//
+// 示例：type in ["vless","hysteria2"] && port != 80 && !(name matches "过期|剩余")
+//
+// 缺失字段视为空值；单独的字段按真值判断，如 tls、!udp。
+
+// PreFilter 已编译的预筛选表达式
+type PreFilter struct {
+	src      string
+	root     exprNode
+	rejected atomic.Int64
+}
+
+// CompilePreFilter 编译预筛选表达式
+func CompilePreFilter(src string) (*PreFilter, error) {
+	toks, err := lexExpr(src)
+	if err != nil {
+		return nil, err
+	}
+	p := &exprParser{toks: toks}
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.toks) {
+		return nil, fmt.Errorf("预筛选表达式第 %d 个字符处存在多余内容 %q", p.toks[p.pos].pos+1, p.toks[p.pos].text)
+	}
+	return &PreFilter{src: src, root: root}, nil
+}
+
+// Match 判断节点是否通过筛选，sub/tag 为节点来源订阅
+func (f *PreFilter) Match(node map[string]any, sub, tag string) bool {
+	return truthy(f.root.eval(exprEnv{node: node, sub: sub, tag: tag}))
+}
+
+// String 返回原始表达式
+func (f *PreFilter) String() string { return f.src }
+
+// Rejected 返回被本表达式过滤的节点数
+func (f *PreFilter) Rejected() int64 { return f.rejected.Load() }
+
+// activePreFilters 本轮生效的预筛选表达式，GetProxies 开始时设置
+var activePreFilters []*PreFilter
+
+// ActivePreFilters 返回本轮生效的预筛选表达式，用于在分析报告中输出各表达式的过滤数量
+func ActivePreFilters() []*PreFilter { return activePreFilters }
+
+// setupPreFilters 编译配置中的预筛选表达式，无效表达式跳过并告警
+func setupPreFilters(exprs []string) {
+	activePreFilters = nil
+	for _, src := range exprs {
+		if strings.TrimSpace(src) == "" {
+			continue
+		}
+		f, err := CompilePreFilter(src)
+		if err != nil {
+			slog.Warn("预筛选表达式无效，已忽略", "expr", src, "error", err)
+			continue
+		}
+		activePreFilters = append(activePreFilters, f)
+	}
+	if len(activePreFilters) > 0 {
+		slog.Info("预筛选", "表达式", len(activePreFilters))
+	}
+}
+
+// preFilterReject 依次匹配表达式，返回 true 表示节点被过滤；计入首个未通过的表达式
+func preFilterReject(node map[string]any, sub, tag string) bool {
+	for _, f := range activePreFilters {
+		if !f.Match(node, sub, tag) {
+			f.rejected.Add(1)
+			return true
+		}
+	}
+	return false
+}
+
+// logPreFilterStats 输出各表达式过滤数量
+func logPreFilterStats() {
+	for _, f := range activePreFilters {
+		slog.Info("预筛选过滤", "expr", f.src, "数量", f.rejected.Load())
+	}
+}
+
+type exprEnv struct {
+	node     map[string]any
+	sub, tag string
+}
+
+// field 读取字段值
+func (e exprEnv) field(name string) any {
+	switch name {
+	case "sub":
+		return e.sub
+	case "tag":
+		return e.tag
+	case "subname":
+		return e.node["sub_name"]
+	case "subtags":
+		tags, _ := e.node["sub_tags"].(string)
+		var out []any
+		for t := range strings.SplitSeq(tags, "|") {
+			if t != "" {
+				out = append(out, t)
+			}
+		}
+		return out
+	case "port":
+		return float64(parse.ToIntPort(e.node["port"]))
+	case "sni":
+		if v, ok := e.node["sni"]; ok && v != "" {
+			return v
+		}
+		return e.node["servername"]
+	}
+
+	var cur any = e.node
+	for part := range strings.SplitSeq(name, ".") {
+		m, ok := cur.(map[string]any)
+		if !ok {
+			return nil
+		}
+		cur = m[part]
+	}
+	return cur
+}
+
+type exprNode interface {
+	eval(env exprEnv) any
+}
+
+type (
+	litNode   struct{ v any }
+	fieldNode struct{ name string }
+	listNode  struct{ items []exprNode }
+	notNode   struct{ x exprNode }
+	logicNode struct {
+		and  bool
+		l, r exprNode
+	}
+	cmpNode struct {
+		op   string
+		l, r exprNode
+		re   *regexp.Regexp // matches
+	}
+)
+
+func (n litNode) eval(exprEnv) any       { return n.v }
+func (n fieldNode) eval(env exprEnv) any { return env.field(n.name) }
+func (n notNode) eval(env exprEnv) any   { return !truthy(n.x.eval(env)) }
+
+func (n listNode) eval(env exprEnv) any {
+	out := make([]any, len(n.items))
+	for i, it := range n.items {
+		out[i] = it.eval(env)
+	}
+	return out
+}
+
+func (n logicNode) eval(env exprEnv) any {
+	l := truthy(n.l.eval(env))
+	if n.and {
+		return l && truthy(n.r.eval(env))
+	}
+	return l || truthy(n.r.eval(env))
+}
+
+func (n cmpNode) eval(env exprEnv) any {
+	l := n.l.eval(env)
+	if n.op == "matches" {
+		return n.re.MatchString(toText(l))
+	}
+	r := n.r.eval(env)
+	switch n.op {
+	case "==":
+		return valuesEqual(l, r)
+	case "!=":
+		return !valuesEqual(l, r)
+	case "<", "<=", ">", ">=":
+		a, okA := toNumber(l)
+		b, okB := toNumber(r)
+		if !okA || !okB {
+			return false
+		}
+		switch n.op {
+		case "<":
+			return a < b
+		case "<=":
+			return a <= b
+		case ">":
+			return a > b
+		}
+		return a >= b
+	case "in", "not in":
+		in := false
+		if list, ok := r.([]any); ok {
+			in = slices.ContainsFunc(list, func(v any) bool { return valuesEqual(l, v) })
+		} else {
+			in = strings.Contains(toText(r), toText(l))
+		}
+		return in == (n.op == "in")
+	case "contains":
+		if list, ok := l.([]any); ok {
+			return slices.ContainsFunc(list, func(v any) bool { return valuesEqual(v, r) })
+		}
+		return strings.Contains(toText(l), toText(r))
+	}
+	return false
+}
+
+// truthy 值的真假：nil、false、空串、0、空列表为假
+func truthy(v any) bool {
+	switch x := v.(type) {
+	case nil:
+		return false
+	case bool:
+		return x
+	case string:
+		return x != "" && x != "false"
+	case []any:
+		return len(x) > 0
+	}
+	if f, ok := toNumber(v); ok {
+		return f != 0
+	}
+	return true
+}
+
+// valuesEqual 数字按数值比较，布尔按真值比较，其余按文本比较
+func valuesEqual(a, b any) bool {
+	if _, ok := a.(bool); ok {
+		return truthy(a) == truthy(b)
+	}
+	if _, ok := b.(bool); ok {
+		return truthy(a) == truthy(b)
+	}
+	if x, ok := toNumber(a); ok {
+		if y, ok := toNumber(b); ok {
+			return x == y
+		}
+	}
+	return toText(a) == toText(b)
+}
+
+func toNumber(v any) (float64, bool) {
+	switch x := v.(type) {
+	case float64:
+		return x, true
+	case float32:
+		return float64(x), true
+	case int:
+		return float64(x), true
+	case int64:
+		return float64(x), true
+	case uint64:
+		return float64(x), true
+	case int32:
+		return float64(x), true
+	case uint32:
+		return float64(x), true
+	case uint16:
+		return float64(x), true
+	case string:
+		f, err := strconv.ParseFloat(strings.TrimSpace(x), 64)
+		return f, err == nil
+	}
+	return 0, false
+}
+
+func toText(v any) string {
+	if v == nil {
+		return ""
+	}
+	if s, ok := v.(string); ok {
+		return s
+	}
+	return fmt.Sprint(v)
+}
+
+// 词法分析
+
+type exprTokKind int
+
+const (
+	tokIdent exprTokKind = iota
+	tokString
+	tokNumber
+	tokOp
+)
+
+type exprTok struct {
+	kind exprTokKind
+	text string
+	pos  int
+}
+
+func lexExpr(src string) ([]exprTok, error) {
+	var toks []exprTok
+	for i := 0; i < len(src); {
+		c := src[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c == '"' || c == '\'':
+			var sb strings.Builder
+			j := i + 1
+			for ; j < len(src) && src[j] != c; j++ {
+				// 仅处理引号与反斜杠的转义，其余反斜杠原样保留，便于书写正则
+				if src[j] == '\\' && j+1 < len(src) && (src[j+1] == c || src[j+1] == '\\') {
+					j++
+				}
+				sb.WriteByte(src[j])
+			}
+			if j >= len(src) {
+				return nil, fmt.Errorf("预筛选表达式第 %d 个字符处的字符串未闭合", i+1)
+			}
+			toks = append(toks, exprTok{tokString, sb.String(), i})
+			i = j + 1
+		case c >= '0' && c <= '9':
+			j := i
+			for j < len(src) && (src[j] >= '0' && src[j] <= '9' || src[j] == '.') {
+				j++
+			}
+			toks = append(toks, exprTok{tokNumber, src[i:j], i})
+			i = j
+		case c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z':
+			j := i
+			for j < len(src) && (src[j] == '_' || src[j] == '-' || src[j] == '.' ||
+				src[j] >= 'a' && src[j] <= 'z' || src[j] >= 'A' && src[j] <= 'Z' || src[j] >= '0' && src[j] <= '9') {
+				j++
+			}
+			toks = append(toks, exprTok{tokIdent, src[i:j], i})
+			i = j
+		default:
+			op := ""
+			for _, o := range []string{"&&", "||", "==", "!=", "<=", ">=", "<", ">", "!", "(", ")", "[", "]", ","} {
+				if strings.HasPrefix(src[i:], o) {
+					op = o
+					break
+				}
+			}
+			if op == "" {
+				return nil, fmt.Errorf("预筛选表达式第 %d 个字符处存在无法识别的字符 %q", i+1, c)
+			}
+			toks = append(toks, exprTok{tokOp, op, i})
+			i += len(op)
+		}
+	}
+	return toks, nil
+}
+
+// 语法分析
+
+type exprParser struct {
+	toks []exprTok
+	pos  int
+}
+
+func (p *exprParser) peek() (exprTok, bool) {
+	if p.pos >= len(p.toks) {
+		return exprTok{}, false
+	}
+	return p.toks[p.pos], true
+}
+
+// accept 下一个记号为指定运算符或关键字时消费并返回 true
+func (p *exprParser) accept(text string) bool {
+	if t, ok := p.peek(); ok && (t.kind == tokOp || t.kind == tokIdent) && t.text == text {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *exprParser) errorf(format string, args ...any) error {
+	msg := fmt.Sprintf(format, args...)
+	if t, ok := p.peek(); ok {
+		return fmt.Errorf("预筛选表达式第 %d 个字符处%s", t.pos+1, msg)
+	}
+	return fmt.Errorf("预筛选表达式结尾处%s", msg)
+}
+
+func (p *exprParser) parseOr() (exprNode, error) {
+	l, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept("||") {
+		r, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		l = logicNode{and: false, l: l, r: r}
+	}
+	return l, nil
+}
+
+func (p *exprParser) parseAnd() (exprNode, error) {
+	l, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept("&&") {
+		r, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		l = logicNode{and: true, l: l, r: r}
+	}
+	return l, nil
+}
+
+func (p *exprParser) parseUnary() (exprNode, error) {
+	if p.accept("!") {
+		x, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return notNode{x}, nil
+	}
+	return p.parseCmp()
+}
+
+func (p *exprParser) parseCmp() (exprNode, error) {
+	l, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+
+	op := ""
+	for _, o := range []string{"==", "!=", "<=", ">=", "<", ">", "in", "matches", "contains"} {
+		if p.accept(o) {
+			op = o
+			break
+		}
+	}
+	if op == "" && p.accept("not") {
+		if !p.accept("in") {
+			return nil, p.errorf("需要 in")
+		}
+		op = "not in"
+	}
+	if op == "" {
+		return l, nil
+	}
+
+	r, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+	n := cmpNode{op: op, l: l, r: r}
+	if op == "matches" {
+		lit, ok := r.(litNode)
+		s, isStr := lit.v.(string)
+		if !ok || !isStr {
+			return nil, fmt.Errorf("预筛选表达式 matches 右侧必须是字符串")
+		}
+		if n.re, err = regexp.Compile(s); err != nil {
+			return nil, fmt.Errorf("预筛选表达式正则 %q 无效: %w", s, err)
+		}
+	}
+	return n, nil
+}
+
+func (p *exprParser) parsePrimary() (exprNode, error) {
+	t, ok := p.peek()
+	if !ok {
+		return nil, p.errorf("缺少操作数")
+	}
+	switch t.kind {
+	case tokString:
+		p.pos++
+		return litNode{t.text}, nil
+	case tokNumber:
+		p.pos++
+		f, err := strconv.ParseFloat(t.text, 64)
+		if err != nil {
+			return nil, fmt.Errorf("预筛选表达式第 %d 个字符处的数字 %q 无效", t.pos+1, t.text)
+		}
+		return litNode{f}, nil
+	case tokIdent:
+		switch t.text {
+		case "true", "false":
+			p.pos++
+			return litNode{t.text == "true"}, nil
+		case "in", "not", "matches", "contains":
+			return nil, p.errorf("缺少操作数")
+		}
+		p.pos++
+		return fieldNode{t.text}, nil
+	}
+
+	switch {
+	case p.accept("("):
+		x, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if !p.accept(")") {
+			return nil, p.errorf("需要 )")
+		}
+		return x, nil
+	case p.accept("["):
+		var items []exprNode
+		for !p.accept("]") {
+			if len(items) > 0 && !p.accept(",") {
+				return nil, p.errorf("需要 , 或 ]")
+			}
+			x, err := p.parsePrimary()
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, x)
+		}
+		return listNode{items}, nil
+	}
+	return nil, p.errorf("存在意外的 %q", t.text)
+}
@@ -0,0 +1,65 @@
+package proxies
+
+import "testing"
+
+func TestPreFilterMatch(t *testing.T) {
+	nodes := map[string]map[string]any{
+		"vless": {"name": "HK 01", "type": "vless", "server": "a.example", "port": 443, "tls": true, "servername": "sni.example", "network": "ws", "ws-opts": map[string]any{"path": "/ray"}},
+		"hy2":   {"name": "剩余流量 10G", "type": "hysteria2", "server": "1.2.3.4", "port": "8443"},
+		"ss":    {"name": "JP", "type": "ss", "server": "5.6.7.8", "port": 80, "cipher": "aes-128-gcm"},
+	}
+	cases := []struct {
+		expr string
+		want map[string]bool
+	}{
+		{`type in ["vless","hysteria2"] && port != 80 && !(name matches "过期|剩余")`, map[string]bool{"vless": true, "hy2": false, "ss": false}},
+		{`port >= 443 && port < 8443`, map[string]bool{"vless": true, "hy2": false, "ss": false}},
+		{`tls && sni == "sni.example" && ws-opts.path contains "ray"`, map[string]bool{"vless": true, "hy2": false, "ss": false}},
+		{`cipher not in ['rc4-md5', "aes-128-gcm"] || sub matches "^https://good"`, map[string]bool{"vless": true, "hy2": true, "ss": true}},
+		{`!tls && network == ""`, map[string]bool{"vless": false, "hy2": true, "ss": true}},
+		{`tag == "free" || name == 'JP'`, map[string]bool{"vless": false, "hy2": false, "ss": true}},
+	}
+	for _, c := range cases {
+		f, err := CompilePreFilter(c.expr)
+		if err != nil {
+			t.Fatalf("%s: %v", c.expr, err)
+		}
+		for key, want := range c.want {
+			if got := f.Match(nodes[key], "https://good.example/sub", "paid"); got != want {
+				t.Errorf("%s on %s: got %v, want %v", c.expr, key, got, want)
+			}
+		}
+	}
+}
+
+func TestPreFilterRejectCounts(t *testing.T) {
+	defer func() { activePreFilters = nil }()
+	setupPreFilters([]string{`port != 80`, `invalid ==`, `type == "vless"`})
+	if len(activePreFilters) != 2 {
+		t.Fatalf("expected 2 filters, got %d", len(activePreFilters))
+	}
+
+	for _, n := range []map[string]any{
+		{"type": "ss", "port": 80},
+		{"type": "ss", "port": 443},
+		{"type": "vless", "port": 443},
+	} {
+		preFilterReject(n, "", "")
+	}
+	// 分析报告通过 ActivePreFilters 读取各表达式的过滤数量
+	filters := ActivePreFilters()
+	if a, b := filters[0].Rejected(), filters[1].Rejected(); a != 1 || b != 1 {
+		t.Errorf("rejected counts: %d %d", a, b)
+	}
+	if filters[1].String() != `type == "vless"` {
+		t.Errorf("expr: %q", filters[1].String())
+	}
+}
+
+func TestCompilePreFilterErrors(t *testing.T) {
+	for _, src := range []string{`type ==`, `(port > 1`, `name matches "("`, `name matches type`, `port @ 1`, `"abc`, `type not "x"`, `a b`} {
+		if _, err := CompilePreFilter(src); err == nil {
+			t.Errorf("%q: expected error", src)
+		}
+	}
+}
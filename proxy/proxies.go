@@ -17,6 +17,7 @@ import (
 	"strings"
 	"sync"
 	"sync/atomic"
+	"time"
 
 	"github.com/goccy/go-yaml"
 	"github.com/samber/lo"
@@ -27,7 +28,7 @@ import (
 )
 
 type SubUrls struct {
-	SubUrls []string `yaml:"sub-urls" json:"sub-urls"`
+	SubUrls []config.SubSource `yaml:"sub-urls" json:"sub-urls"`
 }
 
 // SubStat 记录订阅链接的总数和成功数
@@ -149,6 +150,9 @@ func GetProxies(progressCallback func(stepName string, done, total, available in
 	// 获取远程订阅列表
 	subUrls, localNum, remoteNum, historyNum := resolveSubUrls(progressCallback)
 	logSubscriptionStats(len(subUrls), localNum, remoteNum, historyNum)
+	setupPreFilters(config.GlobalConfig.PreFilter)
+	initSubCache()
+	initDiagnostics()
 
 	// 定义优先级常量
 	const (
@@ -190,6 +194,7 @@ func GetProxies(progressCallback func(stepName string, done, total, available in
 	type NodeEntry struct {
 		Data  map[string]any
 		Level int
+		Subs  []string // 提供该节点的全部订阅，不含本地保留的节点
 	}
 
 	// 预分配200K减少rehash；实际unique数通常远小于raw数
@@ -225,7 +230,8 @@ func GetProxies(progressCallback func(stepName string, done, total, available in
 				}
 
 				// 统计订阅源
-				if su, ok := proxy["sub_url"].(string); ok && su != "" {
+				su, _ := proxy["sub_url"].(string)
+				if su != "" {
 					st := SubStats[su]
 					st.Total++
 					SubStats[su] = st
@@ -245,18 +251,26 @@ func GetProxies(progressCallback func(stepName string, done, total, available in
 					continue
 				}
 
-				// 单次 Map 寻址即完成检查和覆盖
-				if existing, exists := uniqueMap[key]; !exists || level > existing.Level {
-					uniqueMap[key] = NodeEntry{
-						Data:  proxy,
-						Level: level,
-					}
+				// 单次 Map 寻址即完成检查和覆盖，同时记录提供该节点的订阅，用于订阅健康统计
+				entry, exists := uniqueMap[key]
+				changed := false
+				if !exists || level > entry.Level {
+					entry.Data, entry.Level = proxy, level
+					changed = true
+				}
+				if level == KeepLevelNone && su != "" && !lo.Contains(entry.Subs, su) {
+					entry.Subs = append(entry.Subs, su)
+					changed = true
+				}
+				if changed {
+					uniqueMap[key] = entry
 				}
 			}
 		}
 	}()
 
 	// 生产者：并发拉取订阅
+	relays := newRelaySet()
 	sem := make(chan struct{}, concurrency)
 	var wg sync.WaitGroup
 	listenPort := strings.TrimPrefix(config.GlobalConfig.ListenPort, ":")
@@ -275,7 +289,7 @@ func GetProxies(progressCallback func(stepName string, done, total, available in
 		go func(u, t string, succ, hist bool) {
 			defer wg.Done()
 			defer func() { <-sem }()
-			hasValid := processSubscription(u, t, succ, hist, proxyChan, batchSize)
+			hasValid := processSubscription(u, t, succ, hist, proxyChan, batchSize, relays)
 			if hasValid {
 				validSubsCount.Add(1)
 			}
@@ -288,10 +302,19 @@ func GetProxies(progressCallback func(stepName string, done, total, available in
 	wg.Wait()
 	close(proxyChan)
 	<-done
+	relays.close()
 
 	// 将 Map 转为 Slice 的同时，注入临时优先排序字段
 	finalProxies := make([]map[string]any, 0, len(uniqueMap))
+	uniqueBySub := make(map[string]int)
 	for _, entry := range uniqueMap {
+		if len(entry.Subs) == 1 {
+			uniqueBySub[entry.Subs[0]]++
+		}
+		// 多个订阅提供或沿用本地订阅地址的节点，记下全部来源，检测后计入每个来源的可用数
+		if len(entry.Subs) > 1 || (len(entry.Subs) == 1 && entry.Data["sub_url"] != entry.Subs[0]) {
+			entry.Data["sub_providers"] = entry.Subs
+		}
 		switch entry.Level {
 		case KeepLevelSuccess:
 			finalSuccCount++
@@ -310,17 +333,44 @@ func GetProxies(progressCallback func(stepName string, done, total, available in
 		return levelI > levelJ
 	})
 
+	// 已按名称模板渲染过的节点还原原始名称，须在清理 _node_key 之前
+	if config.GlobalConfig.NameTemplate != "" {
+		restoreNameOrigins(finalProxies, time.Now())
+	}
+
 	// 排序完成后再统一清理所有的元数据
 	for _, node := range finalProxies {
 		cleanMetadata(node)
 	}
 
 	// 打印去重统计日志
-	slog.Info("节点解析",
+	parseArgs := []any{
 		"合计", rawCount,
 		"结果", len(finalProxies),
 		"去重", rawCount-len(finalProxies),
-	)
+	}
+	if subCacheEntries != nil {
+		parseArgs = append(parseArgs, "缓存命中", subCacheHits.Load())
+	}
+	if n := relays.Hits(); n > 0 {
+		parseArgs = append(parseArgs, "节点中继", n)
+	}
+	if rejected, repaired := validationTotals(); rejected+repaired > 0 {
+		parseArgs = append(parseArgs, "校验剔除", rejected, "校验修复", repaired)
+	}
+	slog.Info("节点解析", parseArgs...)
+	saveSubCache()
+	saveDiagnostics()
+	logPreFilterStats()
+	if config.GlobalConfig.SubQuota.Notify {
+		updateQuotaNotices(time.Now())
+	}
+	saveQuotas()
+	logQuotaAlerts()
+	recordUniqueNodes(uniqueBySub)
+	if r := currentResolver(); r != nil {
+		r.logStats()
+	}
 	saveStats(SubStats)
 
 	// 释放 Map 内存（虽然函数返回后也会释放）
@@ -336,13 +386,58 @@ func resolveSubUrls(progressCallback func(stepName string, done, total, availabl
 	// 初始化内存限制
 	initMemory()
 
-	var localNum, remoteNum, historyNum int
-	localNum = len(config.GlobalConfig.SubUrls)
+	var localNum, remoteNum, historyNum, disabled, unhealthy int
+	subSources = make(map[string]config.SubSource)
+	githubBlobs = make(map[string]string)
 
+	// addSources 登记订阅源选项，跳过未启用的订阅源，github:// 仓库订阅源展开为其中的文件
 	urls := make([]string, 0, len(config.GlobalConfig.SubUrls))
-	urls = append(urls, config.GlobalConfig.SubUrls...)
+	var addSources func(list []config.SubSource) int
+	addSources = func(list []config.SubSource) int {
+		n := 0
+		for _, src := range list {
+			src.URL = strings.TrimSpace(src.URL)
+			if !src.Enabled() {
+				disabled++
+				continue
+			}
+			if isGitHubSource(src.URL) {
+				files, err := expandGitHubSource(src)
+				if err != nil {
+					logFatal(err, src.URL)
+					continue
+				}
+				n += addSources(files)
+				continue
+			}
+			if skipUnhealthy(src.URL) {
+				unhealthy++
+				continue
+			}
+			if existing, ok := subSources[src.URL]; !ok {
+				subSources[src.URL] = src
+			} else if src.Local && !existing.Local {
+				// 同一文件同时出现在 sub-urls 与 sub-dirs 中时，以 sub-dirs 的标记为准
+				existing.Local = true
+				subSources[src.URL] = existing
+			}
+			urls = append(urls, src.URL)
+			n++
+		}
+		return n
+	}
+	// 目录监听发现新文件时，本轮只检测这些文件
+	queued := takeQueuedFiles()
+	beginHealthRound(len(queued) > 0)
+	if len(queued) > 0 {
+		slog.Info("检测本地目录新增的订阅文件", "数量", len(queued))
+		localNum = addSources(queued)
+	} else {
+		localNum = addSources(config.GlobalConfig.SubUrls)
+		localNum += addSources(localDirSources())
+	}
 
-	if len(config.GlobalConfig.SubUrlsRemote) != 0 {
+	if len(config.GlobalConfig.SubUrlsRemote) != 0 && len(queued) == 0 {
 		slog.Info("拉取远程订阅列表")
 		if progressCallback != nil {
 			progressCallback("拉取远程订阅列表", 0, len(config.GlobalConfig.SubUrlsRemote), 0)
@@ -359,8 +454,7 @@ func resolveSubUrls(progressCallback func(stepName string, done, total, availabl
 				}
 			} else {
 				valid++
-				remoteNum += len(remote)
-				urls = append(urls, remote...)
+				remoteNum += addSources(remote)
 			}
 			fetched++
 			if progressCallback != nil {
@@ -370,6 +464,16 @@ func resolveSubUrls(progressCallback func(stepName string, done, total, availabl
 	} else {
 		slog.Info("拉取订阅列表")
 	}
+	// 从聚合页递归发现的订阅
+	if len(queued) == 0 {
+		remoteNum += addSources(discoverSources())
+	}
+	if disabled > 0 {
+		slog.Info("已跳过停用的订阅", "数量", disabled)
+	}
+	if unhealthy > 0 {
+		slog.Info("已跳过自动停用的订阅", "数量", unhealthy)
+	}
 
 	requiredListenPort := strings.TrimSpace(strings.TrimPrefix(config.GlobalConfig.ListenPort, ":"))
 	localLastSucced := "http://127.0.0.1:" + requiredListenPort + "/all.yaml"
@@ -377,14 +481,9 @@ func resolveSubUrls(progressCallback func(stepName string, done, total, availabl
 
 	// 如果用户设置了保留成功节点，则把本地的 all.yaml 和 history.yaml 放到最前面
 	if config.GlobalConfig.KeepSuccessProxies {
-		saver, err := method.NewLocalSaver()
-		saver.OutputPath = filepath.Join(saver.OutputPath, "sub")
-		if err == nil {
-			if !filepath.IsAbs(saver.OutputPath) {
-				saver.OutputPath = filepath.Join(saver.BasePath, saver.OutputPath)
-			}
-			localLastSuccedFile := filepath.Join(saver.OutputPath, "all.yaml")
-			localHistoryFile := filepath.Join(saver.OutputPath, "history.yaml")
+		if dir, err := localSubDir(); err == nil {
+			localLastSuccedFile := filepath.Join(dir, "all.yaml")
+			localHistoryFile := filepath.Join(dir, "history.yaml")
 
 			if _, err := os.Stat(localLastSuccedFile); err == nil {
 				historyNum++
@@ -427,8 +526,21 @@ func resolveSubUrls(progressCallback func(stepName string, done, total, availabl
 	return out, localNum, remoteNum, historyNum
 }
 
-// fetchRemoteSubUrls 从远程地址读取订阅URL清单
-func fetchRemoteSubUrls(listURL string) ([]string, error) {
+// localSubDir 本地订阅文件（all.yaml、history.yaml）所在目录
+func localSubDir() (string, error) {
+	saver, err := method.NewLocalSaver()
+	if err != nil {
+		return "", err
+	}
+	dir := filepath.Join(saver.OutputPath, "sub")
+	if !filepath.IsAbs(dir) {
+		dir = filepath.Join(saver.BasePath, dir)
+	}
+	return dir, nil
+}
+
+// fetchRemoteSubUrls 从远程地址读取订阅URL清单，对象形式的清单可携带订阅源选项
+func fetchRemoteSubUrls(listURL string) ([]config.SubSource, error) {
 	if listURL == "" {
 		return nil, errors.New("远程列表为空")
 	}
@@ -446,21 +558,21 @@ func fetchRemoteSubUrls(listURL string) ([]string, error) {
 	// 2) 尝试解析为数组形式 ([...])
 	var arr []string
 	if err := yaml.Unmarshal(data, &arr); err == nil && len(arr) > 0 {
-		return arr, nil
+		return plainSources(arr), nil
 	}
 
 	// 2.5) 解析为通用 map，尝试从 Clash/Mihomo 配置中提取 proxy-providers.*.url
 	var generic map[string]any
 	if err := yaml.Unmarshal(data, &generic); err == nil && len(generic) > 0 {
 		if urls := parse.ExtractClashProviderURLs(generic); len(urls) > 0 {
-			return urls, nil
+			return plainSources(urls), nil
 		}
 	}
 
 	// 3) 尝试从 Markdown 链接语法提取: [描述](https://...)
 	if urls := parse.ExtractMarkdownURLs(data); len(urls) > 0 {
 		slog.Debug("从 Markdown 链接提取订阅URL", "count", len(urls))
-		return urls, nil
+		return plainSources(urls), nil
 	}
 
 	// 4) 回退为按行解析 (纯文本) + 快速 URL 校验
@@ -487,7 +599,16 @@ func fetchRemoteSubUrls(listURL string) ([]string, error) {
 	if err := scanner.Err(); err != nil {
 		return nil, err
 	}
-	return res, nil
+	return plainSources(res), nil
+}
+
+// plainSources 将 URL 列表转换为无附加选项的订阅源
+func plainSources(urls []string) []config.SubSource {
+	out := make([]config.SubSource, 0, len(urls))
+	for _, u := range urls {
+		out = append(out, config.SubSource{URL: u})
+	}
+	return out
 }
 
 // defaultParseBatchSize 默认每批次节点数。
@@ -508,14 +629,54 @@ func processSubscription(
 	wasSucced, wasHistory bool,
 	out chan<- []map[string]any,
 	batchSize int, // 由 GetProxies 传入，统一管理
+	relays *relaySet, // 本轮的中继节点，可为 nil
 ) bool {
-	data, err := FetchSubsData(urlStr)
+	src := sourceOf(urlStr)
+	v := subCacheValidators(urlStr)
+	var (
+		data []byte
+		err  error
+	)
+	if subCacheBlobUnchanged(urlStr) {
+		// GitHub 仓库中的文件 blob SHA 未变化，无需下载
+		err = errNotModified
+	} else {
+		data, err = fetchSubsDataWith(urlStr, src, v, relays)
+	}
+	diag := parse.NewDiagnostics(urlStr)
+
+	// 订阅未变化：304 或内容哈希与上次一致时复用缓存节点，跳过解析
+	var (
+		cached    []map[string]any
+		fromCache bool
+		hash      string
+	)
+	if errors.Is(err, errNotModified) {
+		if cached, fromCache = loadCachedNodes(urlStr, v); !fromCache {
+			// 缓存文件丢失或损坏，去掉校验值重新下载
+			dropCachedNodes(urlStr)
+			v = subCacheValidators(urlStr)
+			data, err = fetchSubsDataWith(urlStr, src, v, relays)
+		}
+	}
 	if err != nil {
 		if !errors.Is(err, ErrIgnore) {
 			logFatal(err, urlStr)
+			diag.Error = err.Error()
+			recordDiagnostics(diag)
 		}
 		return false
 	}
+	if !fromCache && v != nil {
+		hash = contentHash(data)
+		if subCacheUnchanged(urlStr, hash) {
+			cached, fromCache = loadCachedNodes(urlStr, v)
+		}
+	}
+	var rec *nodeRecorder
+	if v != nil && !fromCache {
+		rec = &nodeRecorder{}
+	}
 
 	filterTypes := config.GlobalConfig.NodeType
 
@@ -523,6 +684,7 @@ func processSubscription(
 		rawHits      int // 层 1：解析阶段产出的候选节点数（可能含同订阅内跨解析器重复，见 parse/stream.go）
 		validCount   int // 层 2：通过类型/端口校验、实际发往全局去重队列的节点数（去重前）
 		typeFiltered int
+		preFiltered  int
 		hasValid     bool
 	)
 
@@ -542,9 +704,44 @@ func processSubscription(
 	// 注意：这只是「同一订阅内」的去重；跨订阅去重由消费者侧全局 map 负责。
 	seenInSub := make(map[string]struct{}, 256)
 
+	// enqueue 订阅内去重后写入批次
+	enqueue := func(node map[string]any) {
+		// 订阅内去重（减少对全局 map 和 channel 的压力）
+		key := utils.NodeKey(node)
+		if _, dup := seenInSub[key]; dup {
+			return
+		}
+		seenInSub[key] = struct{}{}
+
+		// 将计算好的 key 存入节点传递给消费者，免去下游消费时的二次高负载计算
+		node["_node_key"] = key
+
+		node["sub_url"] = urlStr
+		node["sub_tag"] = tag
+		node["sub_was_succeed"] = wasSucced
+		node["sub_from_history"] = wasHistory
+
+		batch = append(batch, node)
+		validCount++
+		if len(batch) >= batchSize {
+			flush()
+		}
+	}
+
+	// pending：待解析节点，resolver 为 nil 时不使用
+	resolver := currentResolver()
+	var pending []map[string]any
+	drainPending := func() {
+		for _, n := range resolver.expand(pending) {
+			enqueue(n)
+		}
+		pending = pending[:0]
+	}
+
 	// handle 既用作 ParseSubscriptionDataStream 的 yield 回调，也用于处理兜底正则提取出的节点
 	handle := func(node map[string]any) bool {
 		rawHits++
+		rec.add(node)
 
 		// 类型过滤
 		if len(filterTypes) > 0 {
@@ -557,48 +754,60 @@ func processSubscription(
 		// 统一清洗节点字段，注入默认值
 		parse.NormalizeNode(node)
 
-		// 有效性校验
-		serverStr := strings.TrimSpace(fmt.Sprintf("%v", node["server"]))
-		port := parse.ToIntPort(node["port"])
-		if serverStr == "" || serverStr == "<nil>" || port <= 0 || port > 65535 || node["type"] == nil {
-			slog.Debug("过滤掉无效的畸形节点", "订阅", urlStr, "数据", node)
+		// 有效性校验：按协议检查必填字段，能修复的就地修复
+		if reason := diag.Validate(node); reason != "" {
+			slog.Debug("过滤掉无效的畸形节点", "订阅", urlStr, "原因", reason, "数据", node)
 			return true
 		}
 
 		hasValid = true
+		setSourceMeta(node, src)
 
-		// 订阅内去重（减少对全局 map 和 channel 的压力）
-		key := utils.NodeKey(node)
-		if _, dup := seenInSub[key]; dup {
+		// 预筛选表达式
+		if preFilterReject(node, urlStr, tag) {
+			preFiltered++
 			return true
 		}
-		seenInSub[key] = struct{}{}
-
-		// 将计算好的 key 存入节点传递给消费者，免去下游消费时的二次高负载计算
-		node["_node_key"] = key
-
-		node["sub_url"] = urlStr
-		node["sub_tag"] = tag
-		node["sub_was_succeed"] = wasSucced
-		node["sub_from_history"] = wasHistory
 
-		batch = append(batch, node)
-		validCount++
-		if len(batch) >= batchSize {
-			flush()
+		// 原生域名解析：攒批并发解析后再去重，使裂变出的节点参与去重
+		if resolver != nil {
+			pending = append(pending, node)
+			if len(pending) >= resolveBatchSize {
+				drainPending()
+			}
+			return true
 		}
+		enqueue(node)
 		return true
 	}
 
-	parseStats, streamErr := parse.ParseSubscriptionDataStream(data, urlStr, handle)
-	if streamErr != nil {
-		// 兜底：正则提取，通常节点量极少，无需流式
-		for _, node := range parse.FallbackExtractV2Ray(data, urlStr) {
+	var parseStats map[string]int
+	if fromCache {
+		for _, node := range cached {
 			handle(node)
 		}
+		cached = nil
+		diag.Cached, diag.Format = true, "cache"
+		diag.Entries, diag.Converted = rawHits, rawHits
+	} else {
+		var streamErr error
+		parseStats, streamErr = parse.ParseSubscriptionDataStream(data, urlStr, handle)
+		if streamErr != nil {
+			// 兜底：正则提取，通常节点量极少，无需流式
+			fallback := parse.FallbackExtractV2Ray(data, urlStr)
+			for _, node := range fallback {
+				handle(node)
+			}
+			parseStats["Fallback"] = len(fallback)
+		}
+		storeCachedNodes(urlStr, v, hash, rec)
+		diag.Finish(data, parseStats, rawHits)
 	}
 	data = nil //nolint:ineffassign
-	flush()    // 发送剩余节点
+	if len(pending) > 0 {
+		drainPending()
+	}
+	flush() // 发送剩余节点
 
 	// 将解析器内部已去重的数量补回 rawHits，使其代表真实候选数
 	parserDeduped := parseStats["LineDedup"] + parseStats["BatchDedup"]
@@ -607,11 +816,17 @@ func processSubscription(
 	// totalRawHits 仅用于日志，不再用于 GC 触发
 	totalRawHits.Add(int64(rawHits))
 
+	diag.Accepted, diag.TypeFiltered, diag.PreFiltered = validCount, typeFiltered, preFiltered
+	recordDiagnostics(diag)
+
 	slog.Debug("订阅解析完成",
 		"URL", urlStr,
 		"候选", rawHits,
 		"类型过滤", typeFiltered,
+		"预筛选", preFiltered,
+		"校验剔除", diag.RejectedCount(),
 		"入队", validCount,
+		"缓存", fromCache,
 	)
 
 	return hasValid
@@ -670,14 +885,14 @@ func saveStats(subStats map[string]SubStat) {
 	validSB.WriteString("# 可直接替换 config.yaml 中的 subs-urls 字段\n")
 	validSB.WriteString("sub-urls:\n")
 	for _, p := range pairs {
-		fmt.Fprintf(&validSB, "  - %q # nodes: %d\n", p.URL, p.Total)
+		writeSourceEntry(&validSB, p.URL, fmt.Sprintf("nodes: %d", p.Total))
 	}
 
 	if len(subStats) < uniqueSubsCount {
 		validSB.WriteString("\n# 已剔除以下失效订阅链接：\n")
-		for _, u := range config.GlobalConfig.SubUrls {
-			if _, ok := subStats[u]; !ok {
-				fmt.Fprintf(&validSB, "# - %q\n", u)
+		for _, src := range config.GlobalConfig.SubUrls {
+			if _, ok := subStats[src.URL]; !ok && src.Enabled() {
+				fmt.Fprintf(&validSB, "# - %q\n", src.URL)
 			}
 		}
 		_ = method.SaveToStats([]byte(validSB.String()), "sub-urls.yaml", "订阅净化")
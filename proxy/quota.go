@@ -0,0 +1,288 @@
+package proxies
+
+import (
+	"cmp"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/goccy/go-yaml"
+	"github.com/sinspired/subs-check-pro/v2/config"
+	"github.com/sinspired/subs-check-pro/v2/save/method"
+	"github.com/sinspired/subs-check-pro/v2/utils"
+)
+
+const subQuotaFile = "sub-quota.yaml"
+
+// SubQuota 上游订阅通过 subscription-userinfo 响应头返回的流量与到期信息
+type SubQuota struct {
+	URL       string    `yaml:"url" json:"url"`
+	Name      string    `yaml:"name,omitempty" json:"name,omitempty"`
+	Upload    uint64    `yaml:"upload" json:"upload"`
+	Download  uint64    `yaml:"download" json:"download"`
+	Total     uint64    `yaml:"total" json:"total"`   // 0 表示不限量
+	Expire    int64     `yaml:"expire" json:"expire"` // Unix 秒，0 表示不过期
+	UpdatedAt time.Time `yaml:"updated-at" json:"updatedAt"`
+	// Alerted 上次已通知的告警状态，状态变化时才再次通知
+	Alerted []string `yaml:"alerted,omitempty" json:"-"`
+}
+
+// Used 已用流量
+func (q SubQuota) Used() uint64 { return q.Upload + q.Download }
+
+// Remaining 剩余流量，不限量时返回 0 与 false
+func (q SubQuota) Remaining() (uint64, bool) {
+	if q.Total == 0 {
+		return 0, false
+	}
+	if q.Used() >= q.Total {
+		return 0, true
+	}
+	return q.Total - q.Used(), true
+}
+
+var (
+	quotaMu     sync.Mutex
+	subQuotas   map[string]SubQuota // key: 订阅 URL
+	quotaLoaded bool                // 是否已从统计文件加载
+	// quotaNotices 本轮状态发生变化、需要通知的告警
+	quotaNotices []string
+)
+
+// 告警状态
+const (
+	quotaExpiring = "expiring"
+	quotaExpired  = "expired"
+	quotaLow      = "low"
+)
+
+// quotaAlert 单条告警及其状态
+type quotaAlert struct {
+	state string
+	text  string
+}
+
+// ParseSubscriptionUserinfo 解析 subscription-userinfo，
+// 格式：upload=1; download=2; total=3; expire=1700000000
+func ParseSubscriptionUserinfo(header string) (SubQuota, bool) {
+	var q SubQuota
+	found := false
+	for part := range strings.SplitSeq(header, ";") {
+		k, v, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			continue
+		}
+		v = strings.TrimSpace(v)
+		// 部分机场返回浮点数
+		n, err := strconv.ParseFloat(v, 64)
+		if err != nil || n < 0 {
+			continue
+		}
+		switch strings.ToLower(strings.TrimSpace(k)) {
+		case "upload":
+			q.Upload, found = uint64(n), true
+		case "download":
+			q.Download, found = uint64(n), true
+		case "total":
+			q.Total, found = uint64(n), true
+		case "expire":
+			q.Expire, found = int64(n), true
+		}
+	}
+	return q, found
+}
+
+// recordQuota 记录订阅源的流量信息
+func recordQuota(src *config.SubSource, header string) {
+	if src == nil || header == "" {
+		return
+	}
+	q, ok := ParseSubscriptionUserinfo(header)
+	if !ok {
+		return
+	}
+	q.URL, q.Name, q.UpdatedAt = src.URL, src.Name, time.Now()
+
+	quotaMu.Lock()
+	defer quotaMu.Unlock()
+	ensureQuotaLoaded()
+	q.Alerted = subQuotas[q.URL].Alerted
+	subQuotas[q.URL] = q
+}
+
+// ensureQuotaLoaded 首次访问时读取上次保存的记录，调用方需持有 quotaMu
+func ensureQuotaLoaded() {
+	if quotaLoaded {
+		return
+	}
+	quotaLoaded = true
+	subQuotas = make(map[string]SubQuota)
+
+	saver, err := method.NewStatsSaver()
+	if err != nil {
+		return
+	}
+	data, err := os.ReadFile(filepath.Join(saver.StatsPath, subQuotaFile))
+	if err != nil {
+		return
+	}
+	var list []SubQuota
+	if err := yaml.Unmarshal(data, &list); err != nil {
+		return
+	}
+	for _, q := range list {
+		subQuotas[q.URL] = q
+	}
+}
+
+// Quotas 返回各订阅源的流量信息，按到期时间升序，不过期的排在最后
+func Quotas() []SubQuota {
+	quotaMu.Lock()
+	defer quotaMu.Unlock()
+	ensureQuotaLoaded()
+
+	out := make([]SubQuota, 0, len(subQuotas))
+	for _, q := range subQuotas {
+		out = append(out, q)
+	}
+	slices.SortFunc(out, func(a, b SubQuota) int {
+		ea, eb := a.Expire, b.Expire
+		if ea == 0 {
+			ea = 1<<63 - 1
+		}
+		if eb == 0 {
+			eb = 1<<63 - 1
+		}
+		if ea != eb {
+			return cmp.Compare(ea, eb)
+		}
+		return strings.Compare(a.URL, b.URL)
+	})
+	return out
+}
+
+// QuotaOf 返回单个订阅源的流量信息
+func QuotaOf(urlStr string) (SubQuota, bool) {
+	quotaMu.Lock()
+	defer quotaMu.Unlock()
+	ensureQuotaLoaded()
+	q, ok := subQuotas[strings.TrimSpace(urlStr)]
+	return q, ok
+}
+
+// saveQuotas 保存本轮记录，清理不再出现在订阅列表中的订阅源
+func saveQuotas() {
+	quotaMu.Lock()
+	ensureQuotaLoaded()
+	for u := range subQuotas {
+		if _, ok := subSources[u]; !ok {
+			delete(subQuotas, u)
+		}
+	}
+	quotaMu.Unlock()
+
+	list := Quotas()
+	if len(list) == 0 {
+		return
+	}
+	data, err := yaml.Marshal(list)
+	if err != nil {
+		return
+	}
+	_ = method.SaveToStats(data, subQuotaFile, "订阅流量信息")
+}
+
+// QuotaAlerts 返回即将到期或流量不足的订阅源提示
+func QuotaAlerts(now time.Time) []string {
+	var alerts []string
+	for _, q := range Quotas() {
+		for _, a := range q.alerts(now) {
+			alerts = append(alerts, a.text)
+		}
+	}
+	return alerts
+}
+
+// alerts 按配置阈值检查单个订阅源
+func (q SubQuota) alerts(now time.Time) []quotaAlert {
+	cfg := config.GlobalConfig.SubQuota
+	label := q.Name
+	if label == "" {
+		label = q.URL
+	}
+	var alerts []quotaAlert
+	if q.Expire > 0 && cfg.ExpireDays > 0 {
+		left := time.Unix(q.Expire, 0).Sub(now)
+		switch {
+		case left <= 0:
+			alerts = append(alerts, quotaAlert{quotaExpired, label + " 已过期"})
+		case left <= time.Duration(cfg.ExpireDays)*24*time.Hour:
+			alerts = append(alerts, quotaAlert{quotaExpiring, fmt.Sprintf("%s 将于 %s 到期", label, time.Unix(q.Expire, 0).Format(time.DateOnly))})
+		}
+	}
+	if remain, limited := q.Remaining(); limited && cfg.MinRemainGB > 0 {
+		if float64(remain) < cfg.MinRemainGB*(1<<30) {
+			alerts = append(alerts, quotaAlert{quotaLow, fmt.Sprintf("%s 剩余流量 %s / %s", label, utils.FormatTraffic(remain), utils.FormatTraffic(q.Total))})
+		}
+	}
+	return alerts
+}
+
+// updateQuotaNotices 对比上次已通知的状态，只保留新进入的告警（如 正常→流量不足、将到期→已过期），
+// 恢复正常的状态同时清除，之后再次触发时会重新通知
+func updateQuotaNotices(now time.Time) {
+	quotaMu.Lock()
+	defer quotaMu.Unlock()
+	ensureQuotaLoaded()
+
+	quotaNotices = nil
+	for u, q := range subQuotas {
+		var states []string
+		for _, a := range q.alerts(now) {
+			states = append(states, a.state)
+			if !slices.Contains(q.Alerted, a.state) {
+				quotaNotices = append(quotaNotices, a.text)
+			}
+		}
+		q.Alerted = states
+		subQuotas[u] = q
+	}
+	slices.Sort(quotaNotices)
+}
+
+// QuotaNotices 返回本轮需要通知的订阅流量告警
+func QuotaNotices() []string {
+	quotaMu.Lock()
+	defer quotaMu.Unlock()
+	return slices.Clone(quotaNotices)
+}
+
+// logQuotaAlerts 输出订阅流量告警
+func logQuotaAlerts() {
+	for _, a := range QuotaAlerts(time.Now()) {
+		slog.Warn("订阅流量提醒", "info", a)
+	}
+}
+
+// AggregateQuota 汇总所有限量订阅源的流量，expire 取最早的未过期时间。
+// 没有任何限量订阅源时 ok 为 false。
+func AggregateQuota(now time.Time) (upload, download, total uint64, expire int64, ok bool) {
+	for _, q := range Quotas() {
+		if q.Total > 0 {
+			upload += q.Upload
+			download += q.Download
+			total += q.Total
+			ok = true
+		}
+		if q.Expire > now.Unix() && (expire == 0 || q.Expire < expire) {
+			expire = q.Expire
+		}
+	}
+	return upload, download, total, expire, ok
+}
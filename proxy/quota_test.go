@@ -0,0 +1,100 @@
+package proxies
+
+import (
+	"testing"
+	"time"
+
+	"github.com/sinspired/subs-check-pro/v2/config"
+)
+
+func TestParseSubscriptionUserinfo(t *testing.T) {
+	q, ok := ParseSubscriptionUserinfo("upload=1024; download=2048.0; total=10737418240; expire=1893456000")
+	if !ok || q.Upload != 1024 || q.Download != 2048 || q.Total != 10737418240 || q.Expire != 1893456000 {
+		t.Errorf("got %+v", q)
+	}
+	if _, ok := ParseSubscriptionUserinfo("foo=bar"); ok {
+		t.Error("expected no quota for unrelated header")
+	}
+}
+
+func TestQuotaAlertsAndAggregate(t *testing.T) {
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	quotaMu.Lock()
+	oldQuotas, oldLoaded := subQuotas, quotaLoaded
+	subQuotas = map[string]SubQuota{
+		"a": {URL: "a", Name: "机场A", Upload: 1 << 30, Download: 8 << 30, Total: 10 << 30, Expire: now.Add(48 * time.Hour).Unix()},
+		"b": {URL: "b", Total: 100 << 30, Expire: now.Add(30 * 24 * time.Hour).Unix()},
+		"c": {URL: "c", Expire: now.Add(-time.Hour).Unix()},
+	}
+	quotaLoaded = true
+	quotaMu.Unlock()
+
+	oldCfg := config.GlobalConfig
+	config.GlobalConfig = &config.Config{SubQuota: config.SubQuotaConfig{ExpireDays: 3, MinRemainGB: 2}}
+	defer func() {
+		config.GlobalConfig = oldCfg
+		subQuotas, quotaLoaded = oldQuotas, oldLoaded
+	}()
+
+	alerts := QuotaAlerts(now)
+	if len(alerts) != 3 {
+		t.Fatalf("expected 3 alerts, got %v", alerts)
+	}
+
+	up, down, total, expire, ok := AggregateQuota(now)
+	if !ok || up != 1<<30 || down != 8<<30 || total != 110<<30 {
+		t.Errorf("aggregate: %d %d %d", up, down, total)
+	}
+	if expire != now.Add(48*time.Hour).Unix() {
+		t.Errorf("expire should be earliest future expiry, got %d", expire)
+	}
+}
+
+func TestQuotaNoticesOnStateChange(t *testing.T) {
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	quotaMu.Lock()
+	oldQuotas, oldLoaded, oldNotices := subQuotas, quotaLoaded, quotaNotices
+	subQuotas = map[string]SubQuota{
+		"a": {URL: "a", Total: 10 << 30, Download: 9 << 30, Expire: now.Add(48 * time.Hour).Unix()},
+		"b": {URL: "b", Total: 10 << 30},
+	}
+	quotaLoaded = true
+	quotaMu.Unlock()
+
+	oldCfg := config.GlobalConfig
+	config.GlobalConfig = &config.Config{SubQuota: config.SubQuotaConfig{ExpireDays: 3, MinRemainGB: 2}}
+	defer func() {
+		config.GlobalConfig = oldCfg
+		subQuotas, quotaLoaded, quotaNotices = oldQuotas, oldLoaded, oldNotices
+	}()
+
+	updateQuotaNotices(now)
+	if n := QuotaNotices(); len(n) != 2 {
+		t.Fatalf("first run should notify expiring and low, got %v", n)
+	}
+	// 状态未变化：不再重复通知
+	updateQuotaNotices(now)
+	if n := QuotaNotices(); len(n) != 0 {
+		t.Errorf("unchanged state should not notify again, got %v", n)
+	}
+	// 将到期 → 已过期
+	updateQuotaNotices(now.Add(72 * time.Hour))
+	if n := QuotaNotices(); len(n) != 1 || n[0] != "a 已过期" {
+		t.Errorf("expired should be notified once, got %v", n)
+	}
+	// 续费恢复后再次流量不足时重新通知
+	recordQuota(&config.SubSource{URL: "a"}, "download=0; total=10737418240")
+	updateQuotaNotices(now)
+	if got := subQuotas["a"].Alerted; len(got) != 0 {
+		t.Errorf("recovered source should clear alerted state, got %v", got)
+	}
+	recordQuota(&config.SubSource{URL: "a"}, "download=9663676416; total=10737418240")
+	updateQuotaNotices(now)
+	if n := QuotaNotices(); len(n) != 1 {
+		t.Errorf("low again after recovery should notify, got %v", n)
+	}
+	// 从未告警的订阅不受影响
+	if len(subQuotas["b"].Alerted) != 0 {
+		t.Errorf("b: %v", subQuotas["b"].Alerted)
+	}
+}
@@ -0,0 +1,190 @@
+package proxies
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/goccy/go-yaml"
+	"github.com/metacubex/mihomo/adapter"
+	"github.com/metacubex/mihomo/constant"
+	"github.com/sinspired/subs-check-pro/v2/config"
+)
+
+// 订阅中继：直连、系统代理与 GitHub 代理均无法获取订阅时，
+// 使用上次检测成功的节点（all.yaml）作为代理依次再试，
+// 适用于主机本身没有其他代理、依靠自身结果自举的场景。
+// 订阅地址及其中的 token 会经过这些第三方节点，http 订阅内容明文可见，因此默认关闭。
+
+// errNoRelay 没有可用的中继节点
+var errNoRelay = errors.New("无可用的中继节点")
+
+type relayNode struct {
+	name   string
+	proxy  constant.Proxy
+	client *http.Client
+}
+
+// relaySet 单轮 GetProxies 使用的中继节点，节点在首次需要中继时才加载，本轮结束时关闭。
+// 订阅转换、订阅发现等其他调用方不使用中继
+type relaySet struct {
+	mu     sync.Mutex
+	nodes  []*relayNode
+	loaded bool
+	hits   atomic.Int32
+}
+
+// newRelaySet 创建本轮的中继节点集合，未开启 sub-urls-relay 时返回 nil
+func newRelaySet() *relaySet {
+	if config.GlobalConfig.SubUrlsRelay <= 0 {
+		return nil
+	}
+	return &relaySet{}
+}
+
+// Hits 通过中继成功获取的订阅数
+func (rs *relaySet) Hits() int32 {
+	if rs == nil {
+		return 0
+	}
+	return rs.hits.Load()
+}
+
+// close 关闭中继节点及其连接池
+func (rs *relaySet) close() {
+	if rs == nil {
+		return
+	}
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+	for _, r := range rs.nodes {
+		r.client.CloseIdleConnections()
+		_ = r.proxy.Close()
+	}
+	rs.nodes, rs.loaded = nil, true
+}
+
+// load 读取 all.yaml 中排在最前的若干节点
+func (rs *relaySet) load() []*relayNode {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+	if rs.loaded {
+		return rs.nodes
+	}
+	rs.loaded = true
+
+	limit := config.GlobalConfig.SubUrlsRelay
+	if limit <= 0 {
+		return nil
+	}
+	dir, err := localSubDir()
+	if err != nil {
+		return nil
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "all.yaml"))
+	if err != nil {
+		return nil
+	}
+	var doc struct {
+		Proxies []map[string]any `yaml:"proxies"`
+	}
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		slog.Debug("读取中继节点失败", "error", err)
+		return nil
+	}
+
+	for _, m := range doc.Proxies {
+		if len(rs.nodes) >= limit {
+			break
+		}
+		if r := newRelayNode(m); r != nil {
+			rs.nodes = append(rs.nodes, r)
+		}
+	}
+	slog.Debug("已加载订阅中继节点", "数量", len(rs.nodes))
+	return rs.nodes
+}
+
+// newRelayNode 基于 mihomo 适配器创建经由节点的 HTTP Client
+func newRelayNode(mapping map[string]any) *relayNode {
+	p, err := adapter.ParseProxy(mapping)
+	if err != nil {
+		slog.Debug("中继节点解析失败", "error", err)
+		return nil
+	}
+
+	transport := &http.Transport{
+		DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+			host, portStr, err := net.SplitHostPort(addr)
+			if err != nil {
+				return nil, err
+			}
+			port, err := strconv.ParseUint(portStr, 10, 16)
+			if err != nil {
+				return nil, err
+			}
+			return p.DialContext(ctx, &constant.Metadata{
+				Host:    host,
+				DstPort: uint16(port),
+			})
+		},
+		ForceAttemptHTTP2: true,
+		Proxy:             nil,
+		IdleConnTimeout:   30 * time.Second,
+	}
+	// xhttp/splithttp 禁用外层 H2，与 check.CreateClient 保持一致
+	if network, ok := mapping["network"].(string); ok && (network == "xhttp" || network == "splithttp") {
+		transport.ForceAttemptHTTP2 = false
+	}
+
+	name, _ := mapping["name"].(string)
+	return &relayNode{
+		name:   name,
+		proxy:  p,
+		client: &http.Client{Transport: transport, Timeout: 60 * time.Second},
+	}
+}
+
+// relayWorthy 判断失败原因是否值得通过中继重试，404/410 等明确结果无需重试
+func relayWorthy(err error) bool {
+	if err == nil {
+		return false
+	}
+	var se *httpStatusError
+	if errors.As(err, &se) {
+		return se.Code != http.StatusNotFound && se.Code != http.StatusGone
+	}
+	return true
+}
+
+// fetch 依次通过中继节点获取订阅
+func (rs *relaySet) fetch(target string, timeoutSec int, ua string, src *config.SubSource, v *cacheValidators) ([]byte, error) {
+	relays := rs.load()
+	if len(relays) == 0 {
+		return nil, errNoRelay
+	}
+
+	var lastErr error
+	for _, r := range relays {
+		slog.Debug("尝试通过中继节点下载", "Target", target, "节点", r.name)
+		body, err, fatal := fetchWith(r.client, target, true, timeoutSec, ua, src, v)
+		if err == nil || errors.Is(err, errNotModified) {
+			rs.hits.Add(1)
+			slog.Info("已通过检测成功的节点获取订阅", "URL", target, "节点", r.name)
+			return body, err
+		}
+		lastErr = err
+		if fatal {
+			break
+		}
+	}
+	return nil, lastErr
+}
@@ -0,0 +1,112 @@
+package proxies
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"testing"
+
+	"github.com/sinspired/subs-check-pro/v2/config"
+)
+
+// connectProxy 最小的 HTTP CONNECT 代理，用作中继节点
+func connectProxy(t *testing.T) *httptest.Server {
+	t.Helper()
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodConnect {
+			http.Error(w, "connect only", http.StatusMethodNotAllowed)
+			return
+		}
+		upstream, err := net.Dial("tcp", r.Host)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadGateway)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		conn, _, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			upstream.Close()
+			return
+		}
+		go func() {
+			defer upstream.Close()
+			_, _ = io.Copy(upstream, conn)
+		}()
+		_, _ = io.Copy(conn, upstream)
+		conn.Close()
+	}))
+}
+
+func TestFetchViaRelays(t *testing.T) {
+	origin := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("proxies: []"))
+	}))
+	defer origin.Close()
+	px := connectProxy(t)
+	defer px.Close()
+
+	u, _ := url.Parse(px.URL)
+	port, _ := strconv.Atoi(u.Port())
+	bad := newRelayNode(map[string]any{"name": "bad", "type": "http", "server": "127.0.0.1", "port": 1})
+	good := newRelayNode(map[string]any{"name": "good", "type": "http", "server": u.Hostname(), "port": port})
+	if bad == nil || good == nil {
+		t.Fatal("failed to build relay nodes")
+	}
+
+	rs := &relaySet{nodes: []*relayNode{bad, good}, loaded: true}
+	defer rs.close()
+
+	body, err := rs.fetch(origin.URL, 5, "test", nil, nil)
+	if err != nil || string(body) != "proxies: []" {
+		t.Fatalf("got %q, %v", body, err)
+	}
+	if rs.Hits() != 1 {
+		t.Fatalf("relay hits = %d", rs.Hits())
+	}
+}
+
+func TestRelaySetScope(t *testing.T) {
+	old := config.GlobalConfig.SubUrlsRelay
+	defer func() { config.GlobalConfig.SubUrlsRelay = old }()
+
+	config.GlobalConfig.SubUrlsRelay = 0
+	if rs := newRelaySet(); rs != nil || rs.Hits() != 0 {
+		t.Error("relay should be off when sub-urls-relay is 0")
+	}
+	config.GlobalConfig.SubUrlsRelay = 3
+	a, b := newRelaySet(), newRelaySet()
+	if a == nil || a == b {
+		t.Fatal("each run should get its own relay set")
+	}
+	a.close()
+	if nodes := a.load(); len(nodes) != 0 {
+		t.Errorf("closed set should not reload relays: %d", len(nodes))
+	}
+}
+
+func TestRelayWorthy(t *testing.T) {
+	cases := map[error]bool{
+		&httpStatusError{Code: 404}:                            false,
+		&httpStatusError{Code: 410}:                            false,
+		&httpStatusError{Code: 403}:                            true,
+		fmt.Errorf("wrapped: %w", &httpStatusError{Code: 404}): false,
+		errorString("404"):                                     true, // 非状态码错误不按文本解析
+		errorString("dial tcp: timeout"):                       true,
+	}
+	for err, want := range cases {
+		if got := relayWorthy(err); got != want {
+			t.Errorf("%v: got %v, want %v", err, got, want)
+		}
+	}
+	if relayWorthy(nil) {
+		t.Error("nil error should not relay")
+	}
+}
+
+type errorString string
+
+func (e errorString) Error() string { return string(e) }
@@ -15,16 +15,8 @@ var (
 func Rename(name, countryCodeTag string) string {
 	flag := CountryCodeToFlag(name)
 
-	key, label := name, name
-	if config.GlobalConfig.EnhancedTag {
-		if countryCodeTag != "" {
-			key, label = countryCodeTag, countryCodeTag
-		} else if name != "" {
-			// 添加 "ˣ" 角标, 例如: "HKˣ", 以做区分
-			label = name + "ˣ"
-			key = label
-		}
-	}
+	label := CountryLabel(name, countryCodeTag)
+	key := label
 
 	counterLock.Lock()
 	counter[key]++
@@ -34,12 +26,28 @@ func Rename(name, countryCodeTag string) string {
 	return flag + label + "_" + strconv.Itoa(n)
 }
 
+// CountryLabel 返回重命名使用的国家标签，开启 enhanced-tag 时优先使用增强标签
+func CountryLabel(name, countryCodeTag string) string {
+	if !config.GlobalConfig.EnhancedTag {
+		return name
+	}
+	if countryCodeTag != "" {
+		return countryCodeTag
+	}
+	if name != "" {
+		// 添加 "ˣ" 角标, 例如: "HKˣ", 以做区分
+		return name + "ˣ"
+	}
+	return name
+}
+
 // ResetRenameCounter 将所有计数器重置为 0
 func ResetRenameCounter() {
 	counterLock.Lock()
-	defer counterLock.Unlock()
-
 	counter = make(map[string]int)
+	counterLock.Unlock()
+
+	resetTemplateCounters()
 }
 
 func CountryCodeToFlag(code string) string {
@@ -0,0 +1,402 @@
+package proxies
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"maps"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/goccy/go-json"
+	"github.com/sinspired/subs-check-pro/v2/config"
+)
+
+// DoH JSON 接口
+var dohProviders = map[string]string{
+	"ali":        "https://dns.alidns.com/resolve",
+	"google":     "https://dns.google/resolve",
+	"cloudflare": "https://cloudflare-dns.com/dns-query",
+}
+
+// dnsCacheEntry 解析结果缓存
+type dnsCacheEntry struct {
+	ips     []string
+	expires time.Time
+}
+
+// domainResolver 订阅阶段的域名解析器，替代 sub-store 的 Resolve Domain 与节点裂变。
+// provider 可选 ali/google/cloudflare、自定义 DoH JSON 地址（http/https），
+// 或 udp://host:port、tcp://host:port 形式的普通 DNS 服务器（便于本地测试）。
+type domainResolver struct {
+	cfg      config.ResolveDomainConfig
+	split    bool
+	endpoint string        // DoH 地址
+	stub     *net.Resolver // 普通 DNS
+	client   *http.Client
+	timeout  time.Duration
+	sem      chan struct{}
+
+	resolved atomic.Int64 // 成功解析的域名数（不含缓存命中）
+	failed   atomic.Int64
+	hits     atomic.Int64 // 缓存命中
+	expanded atomic.Int64 // 裂变新增节点数
+}
+
+// resolveBatchSize 每攒够多少个节点并发解析一次
+const resolveBatchSize = 256
+
+var (
+	dnsCache       sync.Map // key -> dnsCacheEntry
+	resolverMu     sync.Mutex
+	activeResolver *domainResolver
+)
+
+// nativeResolveEnabled 是否在 GetProxies 内直接解析域名
+func nativeResolveEnabled() bool {
+	sp := config.GlobalConfig.SubProcess
+	return strings.EqualFold(sp.ResolveDomain.Engine, "native") && (sp.ResolveDomain.Enable || sp.NodeSplit)
+}
+
+// currentResolver 返回按当前配置构建的解析器，未启用时返回 nil
+func currentResolver() *domainResolver {
+	if !nativeResolveEnabled() {
+		return nil
+	}
+	resolverMu.Lock()
+	defer resolverMu.Unlock()
+	sp := config.GlobalConfig.SubProcess
+	r := activeResolver
+	if r == nil || r.cfg != sp.ResolveDomain || r.split != sp.NodeSplit {
+		var err error
+		r, err = newDomainResolver(sp.ResolveDomain, sp.NodeSplit)
+		if err != nil {
+			slog.Warn("域名解析配置无效，跳过解析", "error", err)
+			return nil
+		}
+		activeResolver = r
+	}
+	return r
+}
+
+// newDomainResolver 根据配置创建解析器
+func newDomainResolver(cfg config.ResolveDomainConfig, split bool) (*domainResolver, error) {
+	r := &domainResolver{cfg: cfg, split: split}
+	r.timeout = time.Duration(cfg.Timeout) * time.Millisecond
+	if r.timeout <= 0 {
+		r.timeout = 8 * time.Second
+	}
+	r.sem = make(chan struct{}, max(cfg.Concurrency, 1))
+
+	provider := strings.TrimSpace(cfg.Provider)
+	if provider == "" {
+		provider = "ali"
+	}
+	if ep, ok := dohProviders[strings.ToLower(provider)]; ok {
+		r.endpoint = ep
+		r.client = &http.Client{Timeout: r.timeout}
+		return r, nil
+	}
+
+	u, err := url.Parse(provider)
+	if err != nil || u.Host == "" {
+		return nil, fmt.Errorf("未知的 DNS 服务商: %s", provider)
+	}
+	switch u.Scheme {
+	case "http", "https":
+		r.endpoint = provider
+		r.client = &http.Client{Timeout: r.timeout}
+	case "udp", "tcp":
+		network, addr := u.Scheme, u.Host
+		r.stub = &net.Resolver{
+			PreferGo: true,
+			Dial: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				var d net.Dialer
+				return d.DialContext(ctx, network, addr)
+			},
+		}
+	default:
+		return nil, fmt.Errorf("不支持的 DNS 协议: %s", u.Scheme)
+	}
+	return r, nil
+}
+
+// qtypes 按配置返回查询类型
+func (r *domainResolver) qtypes() []string {
+	switch strings.ToLower(strings.TrimSpace(r.cfg.Type)) {
+	case "ipv6", "aaaa":
+		return []string{"AAAA"}
+	case "dual", "both", "ipv4+ipv6":
+		return []string{"A", "AAAA"}
+	default:
+		return []string{"A"}
+	}
+}
+
+// cacheEnabled 缓存开关，兼容 sub-store 的 enabled/disabled 写法
+func (r *domainResolver) cacheEnabled() bool {
+	c := strings.ToLower(strings.TrimSpace(r.cfg.Cache))
+	return c != "disabled" && c != "disable" && c != "false"
+}
+
+// lookup 解析域名，返回去重后的 IP 列表
+func (r *domainResolver) lookup(domain string) []string {
+	key := r.cfg.Provider + "|" + r.cfg.Type + "|" + r.cfg.Edns + "|" + domain
+	if r.cacheEnabled() {
+		if v, ok := dnsCache.Load(key); ok {
+			if e := v.(dnsCacheEntry); time.Now().Before(e.expires) {
+				r.hits.Add(1)
+				return e.ips
+			}
+			dnsCache.Delete(key)
+		}
+	}
+
+	r.sem <- struct{}{}
+	defer func() { <-r.sem }()
+
+	var ips []string
+	minTTL := 0
+	seen := make(map[string]bool)
+	for _, qtype := range r.qtypes() {
+		got, ttl, err := r.query(domain, qtype)
+		if err != nil {
+			slog.Debug("域名解析失败", "domain", domain, "type", qtype, "error", err)
+			continue
+		}
+		for _, ip := range got {
+			if !seen[ip] {
+				seen[ip] = true
+				ips = append(ips, ip)
+			}
+		}
+		if ttl > 0 && (minTTL == 0 || ttl < minTTL) {
+			minTTL = ttl
+		}
+	}
+	if len(ips) == 0 {
+		r.failed.Add(1)
+		return nil
+	}
+	r.resolved.Add(1)
+
+	if r.cacheEnabled() {
+		ttl := r.cfg.CacheTTL
+		if ttl <= 0 {
+			ttl = max(minTTL, 60)
+		}
+		dnsCache.Store(key, dnsCacheEntry{ips: ips, expires: time.Now().Add(time.Duration(ttl) * time.Second)})
+	}
+	return ips
+}
+
+// query 执行单次查询，返回 IP 与最小 TTL（普通 DNS 无 TTL，返回 0）
+func (r *domainResolver) query(domain, qtype string) ([]string, int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), r.timeout)
+	defer cancel()
+
+	if r.stub != nil {
+		network := "ip4"
+		if qtype == "AAAA" {
+			network = "ip6"
+		}
+		addrs, err := r.stub.LookupNetIP(ctx, network, domain)
+		if err != nil {
+			return nil, 0, err
+		}
+		ips := make([]string, 0, len(addrs))
+		for _, a := range addrs {
+			ips = append(ips, a.Unmap().String())
+		}
+		return ips, 0, nil
+	}
+
+	q := url.Values{}
+	q.Set("name", domain)
+	q.Set("type", qtype)
+	if r.cfg.Edns != "" {
+		q.Set("edns_client_subnet", r.cfg.Edns)
+	}
+	sep := "?"
+	if strings.Contains(r.endpoint, "?") {
+		sep = "&"
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, r.endpoint+sep+q.Encode(), nil)
+	if err != nil {
+		return nil, 0, err
+	}
+	req.Header.Set("Accept", "application/dns-json")
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, 0, fmt.Errorf("DoH 状态码: %d", resp.StatusCode)
+	}
+
+	var body struct {
+		Status int `json:"Status"`
+		Answer []struct {
+			Type int    `json:"type"`
+			TTL  int    `json:"TTL"`
+			Data string `json:"data"`
+		} `json:"Answer"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, 0, err
+	}
+	if body.Status != 0 {
+		return nil, 0, fmt.Errorf("DNS 响应码: %d", body.Status)
+	}
+
+	want := 1
+	if qtype == "AAAA" {
+		want = 28
+	}
+	var ips []string
+	minTTL := 0
+	for _, a := range body.Answer {
+		// 跳过 CNAME 等记录
+		if a.Type != want {
+			continue
+		}
+		if addr, err := netip.ParseAddr(a.Data); err == nil {
+			ips = append(ips, addr.Unmap().String())
+			if minTTL == 0 || a.TTL < minTTL {
+				minTTL = a.TTL
+			}
+		}
+	}
+	return ips, minTTL, nil
+}
+
+// expand 并发解析一批节点的域名。
+// 未开启裂变或域名只有一个 IP 时将 server 替换为首个 IP；开启裂变且有多个 IP 时每个 IP 生成一个节点，替代原域名节点。
+// 解析失败的节点原样保留。
+func (r *domainResolver) expand(nodes []map[string]any) []map[string]any {
+	var list []string
+	domains := make(map[string][]string)
+	for _, n := range nodes {
+		if d := nodeDomain(n); d != "" {
+			if _, ok := domains[d]; !ok {
+				domains[d] = nil
+				list = append(list, d)
+			}
+		}
+	}
+	if len(list) == 0 {
+		return nodes
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, d := range list {
+		wg.Go(func() {
+			ips := r.lookup(d)
+			mu.Lock()
+			domains[d] = ips
+			mu.Unlock()
+		})
+	}
+	wg.Wait()
+
+	out := make([]map[string]any, 0, len(nodes))
+	for _, n := range nodes {
+		domain := nodeDomain(n)
+		ips := domains[domain]
+		if len(ips) == 0 {
+			out = append(out, n)
+			continue
+		}
+		if !r.split || len(ips) == 1 {
+			out = append(out, withResolvedServer(n, domain, ips[0]))
+			continue
+		}
+		// 旧版本保留的原域名节点带有 |已裂变 后缀，去掉后按普通节点裂变
+		name, _ := n["name"].(string)
+		name = strings.TrimSuffix(name, "|已裂变")
+		for i, ip := range ips {
+			c := withResolvedServer(maps.Clone(n), domain, ip)
+			c["name"] = name + "|+" + strconv.Itoa(i+1)
+			out = append(out, c)
+		}
+		r.expanded.Add(int64(len(ips) - 1))
+	}
+	return out
+}
+
+// logStats 输出本轮解析统计并清零
+func (r *domainResolver) logStats() {
+	args := []any{
+		"成功", r.resolved.Swap(0),
+		"失败", r.failed.Swap(0),
+		"缓存命中", r.hits.Swap(0),
+	}
+	if r.split {
+		args = append(args, "裂变新增", r.expanded.Swap(0))
+	}
+	slog.Info("域名解析", args...)
+}
+
+// nodeDomain 返回节点的域名型 server，IP 或空值返回 ""
+func nodeDomain(n map[string]any) string {
+	server, _ := n["server"].(string)
+	server = strings.TrimSpace(server)
+	if server == "" {
+		return ""
+	}
+	if _, err := netip.ParseAddr(strings.Trim(server, "[]")); err == nil {
+		return ""
+	}
+	return strings.ToLower(server)
+}
+
+// withResolvedServer 将 server 替换为 IP，并补全依赖原域名的 SNI 与 WebSocket Host
+func withResolvedServer(n map[string]any, domain, ip string) map[string]any {
+	n["server"] = ip
+
+	pType, _ := n["type"].(string)
+	tls, _ := n["tls"].(bool)
+	switch pType {
+	case "vmess", "vless":
+		if _, ok := n["servername"]; !ok && tls {
+			n["servername"] = domain
+		}
+	case "trojan", "hysteria", "hysteria2", "tuic", "anytls":
+		if _, ok := n["sni"]; !ok {
+			n["sni"] = domain
+		}
+	default:
+		if _, ok := n["sni"]; !ok && tls {
+			n["sni"] = domain
+		}
+	}
+
+	if network, _ := n["network"].(string); network == "ws" {
+		opts, _ := n["ws-opts"].(map[string]any)
+		headers, _ := opts["headers"].(map[string]any)
+		if _, ok := headers["Host"]; !ok {
+			// 复制嵌套 map，避免裂变节点之间共享修改
+			opts, headers = maps.Clone(opts), maps.Clone(headers)
+			if opts == nil {
+				opts = make(map[string]any)
+			}
+			if headers == nil {
+				headers = make(map[string]any)
+			}
+			headers["Host"] = domain
+			opts["headers"] = headers
+			n["ws-opts"] = opts
+		}
+	}
+	return n
+}
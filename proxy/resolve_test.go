@@ -0,0 +1,118 @@
+package proxies
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/sinspired/subs-check-pro/v2/config"
+)
+
+// newStubDoH 本地 DoH JSON 服务，a.example 返回两个 IPv4，b.example 返回一个 IPv4，v6.example 返回一个 IPv6
+func newStubDoH(t *testing.T, queries *atomic.Int64) *httptest.Server {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		queries.Add(1)
+		name, qtype := r.URL.Query().Get("name"), r.URL.Query().Get("type")
+		w.Header().Set("Content-Type", "application/dns-json")
+		switch {
+		case name == "a.example" && qtype == "A":
+			fmt.Fprint(w, `{"Status":0,"Answer":[{"type":5,"TTL":60,"data":"cdn.example."},{"type":1,"TTL":60,"data":"1.1.1.1"},{"type":1,"TTL":30,"data":"1.0.0.1"}]}`)
+		case name == "b.example" && qtype == "A":
+			fmt.Fprint(w, `{"Status":0,"Answer":[{"type":1,"TTL":60,"data":"9.9.9.9"}]}`)
+		case name == "v6.example" && qtype == "AAAA":
+			fmt.Fprint(w, `{"Status":0,"Answer":[{"type":28,"TTL":60,"data":"2606:4700::1111"}]}`)
+		default:
+			fmt.Fprint(w, `{"Status":3}`)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestResolverSplit(t *testing.T) {
+	var queries atomic.Int64
+	srv := newStubDoH(t, &queries)
+	r, err := newDomainResolver(config.ResolveDomainConfig{Provider: srv.URL, Type: "IPv4", Cache: "enabled", Concurrency: 4, Timeout: 2000}, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	nodes := []map[string]any{
+		{"name": "n1", "type": "vless", "server": "a.example", "tls": true, "network": "ws", "ws-opts": map[string]any{"path": "/"}},
+		{"name": "n2", "type": "ss", "server": "8.8.8.8"},
+		{"name": "n3", "type": "trojan", "server": "missing.example"},
+		{"name": "n5", "type": "ss", "server": "b.example"},
+	}
+	out := r.expand(nodes)
+	// 多 IP 域名裂变为每个 IP 一个节点且不保留原域名节点，单 IP 域名仍为一个节点
+	if len(out) != 5 {
+		t.Fatalf("expected 5 nodes, got %d: %v", len(out), out)
+	}
+
+	if out[0]["server"] != "1.1.1.1" || out[0]["name"] != "n1|+1" || out[1]["server"] != "1.0.0.1" || out[1]["name"] != "n1|+2" {
+		t.Errorf("split nodes: %v %v", out[0], out[1])
+	}
+	if out[0]["servername"] != "a.example" {
+		t.Errorf("servername not filled: %v", out[0])
+	}
+	host := out[1]["ws-opts"].(map[string]any)["headers"].(map[string]any)["Host"]
+	if host != "a.example" {
+		t.Errorf("ws host not filled: %v", out[1])
+	}
+	// 原始节点的嵌套 map 不应被修改
+	if _, ok := nodes[0]["ws-opts"].(map[string]any)["headers"]; ok {
+		t.Errorf("original ws-opts mutated: %v", nodes[0])
+	}
+	if out[2]["server"] != "8.8.8.8" || out[3]["server"] != "missing.example" {
+		t.Errorf("unresolved nodes changed: %v %v", out[2], out[3])
+	}
+	if out[4]["server"] != "9.9.9.9" || out[4]["name"] != "n5" {
+		t.Errorf("single ip node: %v", out[4])
+	}
+	if r.expanded.Load() != 1 {
+		t.Errorf("expanded = %d, want 1", r.expanded.Load())
+	}
+
+	// 第二次解析命中缓存，旧版本保留的 |已裂变 域名节点按原名裂变
+	before := queries.Load()
+	out = r.expand([]map[string]any{{"name": "n4", "type": "ss", "server": "a.example"}, {"name": "n1|已裂变", "type": "ss", "server": "a.example"}})
+	if queries.Load() != before {
+		t.Errorf("cache miss: %d queries", queries.Load()-before)
+	}
+	if len(out) != 4 || out[2]["name"] != "n1|+1" || out[3]["name"] != "n1|+2" {
+		t.Errorf("re-split: %v", out)
+	}
+	if r.hits.Load() == 0 || r.resolved.Load() != 2 || r.failed.Load() != 1 {
+		t.Errorf("stats: hits=%d resolved=%d failed=%d", r.hits.Load(), r.resolved.Load(), r.failed.Load())
+	}
+}
+
+func TestResolverTypeWithoutSplit(t *testing.T) {
+	var queries atomic.Int64
+	srv := newStubDoH(t, &queries)
+	r, err := newDomainResolver(config.ResolveDomainConfig{Provider: srv.URL, Type: "IPv6", Cache: "disabled", Concurrency: 1, Timeout: 2000}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	out := r.expand([]map[string]any{
+		{"name": "v6", "type": "hysteria2", "server": "v6.example"},
+		{"name": "v4", "type": "ss", "server": "a.example"},
+	})
+	if len(out) != 2 {
+		t.Fatalf("expected 2 nodes, got %v", out)
+	}
+	if out[0]["server"] != "2606:4700::1111" || out[0]["sni"] != "v6.example" || out[0]["name"] != "v6" {
+		t.Errorf("ipv6 node: %v", out[0])
+	}
+	// a.example 没有 AAAA 记录，保持原样
+	if out[1]["server"] != "a.example" {
+		t.Errorf("ipv4-only node: %v", out[1])
+	}
+
+	if _, err := newDomainResolver(config.ResolveDomainConfig{Provider: "unknown"}, false); err == nil {
+		t.Error("expected error for unknown provider")
+	}
+}
@@ -1,9 +1,15 @@
 package proxies
 
 import (
+	"context"
 	"math/rand/v2"
 	"net"
+	"net/netip"
 	"strconv"
+	"sync"
+	"time"
+
+	"github.com/oschwald/maxminddb-golang/v2"
 )
 
 type ShuffleConfig struct {
@@ -12,6 +18,26 @@ type ShuffleConfig struct {
 	MinSpacing int        // 同一 IPv4 /24 的最小间距；<=0 关闭
 	ScanLimit  int        // 冲突向前扫描的最大距离
 	Rand       *rand.Rand // 随机数，为空则使用 time.Now().UnixNano()
+
+	ResolveDomain     bool              // 解析域名型 server，按解析出的 IPv4 计算网段与 ASN
+	ResolveTimeout    time.Duration     // 单个域名解析超时，默认 2s
+	ResolveConcurrent int               // 域名解析并发数，默认 64
+	ASNDB             *maxminddb.Reader // ASN 库，为空则不做 ASN 约束
+	ASNMinSpacing     int               // 同一 ASN 的最小间距；<=0 关闭
+}
+
+// ShuffleStats 乱序后实际达到的间距统计
+type ShuffleStats struct {
+	Domains    int // 域名型 server 数量（去重）
+	Resolved   int // 成功解析的域名数量
+	WithASN    int // 查到 ASN 的节点数量
+	ASNCount   int // 不同 ASN 数量
+	TopASNSize int // 节点最多的 ASN 包含的节点数
+
+	Min24Gap         int // 同一 /24 相邻出现的最小间距，-1 表示没有重复
+	Spacing24Misses  int // 间距未达到 MinSpacing 的次数
+	MinASNGap        int // 同一 ASN 相邻出现的最小间距，-1 表示没有重复
+	SpacingASNMisses int // 间距未达到 ASNMinSpacing 的次数
 }
 
 type serverMeta struct {
@@ -20,13 +46,16 @@ type serverMeta struct {
 	octets   [4]byte
 	prefix24 uint32
 	prefixOK bool
+	asn      uint
+	asnOK    bool
 }
 
-// SmartShuffleByServer 对 items 就地打乱，避免相邻相似，并尽量满足最小间距
-func SmartShuffleByServer(items []map[string]any, cfg ShuffleConfig) {
+// SmartShuffleByServer 对 items 就地打乱，避免相邻相似，并尽量满足 /24 与 ASN 的最小间距
+func SmartShuffleByServer(items []map[string]any, cfg ShuffleConfig) ShuffleStats {
+	stats := ShuffleStats{Min24Gap: -1, MinASNGap: -1}
 	n := len(items)
 	if n < 2 {
-		return
+		return stats
 	}
 
 	// 默认参数
@@ -47,6 +76,14 @@ func SmartShuffleByServer(items []map[string]any, cfg ShuffleConfig) {
 			metas[i] = parseServerMeta(s)
 		}
 	}
+	if cfg.ResolveDomain {
+		stats.Domains, stats.Resolved = resolveMetas(metas, cfg)
+	}
+	if cfg.ASNDB != nil {
+		stats.WithASN = lookupMetaASN(metas, cfg.ASNDB)
+	} else {
+		cfg.ASNMinSpacing = 0
+	}
 
 	// 初次完全打乱 (同时打乱 items 和 metas)
 	rand.Shuffle(n, func(i, j int) {
@@ -54,27 +91,35 @@ func SmartShuffleByServer(items []map[string]any, cfg ShuffleConfig) {
 	})
 
 	// 检查最小间距的闭包函数
-	checkSpacing := func(lp map[uint32]int, idx int, m serverMeta) bool {
-		if cfg.MinSpacing <= 0 || !m.prefixOK {
-			return true
-		}
+	checkSpacing := func(lp map[uint32]int, la map[uint]int, idx int, m serverMeta) bool {
 		// idx 是放置候选节点的位置，last 是上一次出现该 IP 段的位置
 		// 要求: 当前位置 - 上次位置 > 最小间距
-		if last, ok := lp[m.prefix24]; !ok || idx-last > cfg.MinSpacing {
-			return true
+		if cfg.MinSpacing > 0 && m.prefixOK {
+			if last, ok := lp[m.prefix24]; ok && idx-last <= cfg.MinSpacing {
+				return false
+			}
+		}
+		if cfg.ASNMinSpacing > 0 && m.asnOK {
+			if last, ok := la[m.asn]; ok && idx-last <= cfg.ASNMinSpacing {
+				return false
+			}
 		}
-		return false
+		return true
 	}
 
 	for pass := 0; pass < cfg.Passes; pass++ {
 		changed := false
 		// 每次 pass 重置 lastPos map，容量建议设为 n 或 64
 		lastPos := make(map[uint32]int, 64)
+		lastASN := make(map[uint]int, 64)
 
 		// 记录第 0 个元素的位置
 		if metas[0].prefixOK {
 			lastPos[metas[0].prefix24] = 0
 		}
+		if metas[0].asnOK {
+			lastASN[metas[0].asn] = 0
+		}
 
 		for i := 0; i < n-1; i++ {
 			// 记录当前节点 i 的位置信息（为了给后续节点判断间距用）
@@ -82,7 +127,8 @@ func SmartShuffleByServer(items []map[string]any, cfg ShuffleConfig) {
 
 			// 检查 items[i] 和 items[i+1] 是否冲突
 			conflict := similarity(m1, m2) >= cfg.Threshold ||
-				(cfg.MinSpacing > 0 && same24(m1, m2))
+				(cfg.MinSpacing > 0 && same24(m1, m2)) ||
+				(cfg.ASNMinSpacing > 0 && !checkSpacing(nil, lastASN, i+1, m2))
 
 			if conflict {
 				bestJ, bestScore := -1, 2.0 // 2.0 大于任何可能的相似度(最大1.0)
@@ -95,7 +141,7 @@ func SmartShuffleByServer(items []map[string]any, cfg ShuffleConfig) {
 
 					// 候选者 mj 放到 i+1 的位置，必须满足与 m1 的间距要求
 					// 这里的 lastPos 记录的是 i 及其之前的状态
-					if !checkSpacing(lastPos, i+1, mj) {
+					if !checkSpacing(lastPos, lastASN, i+1, mj) {
 						continue
 					}
 
@@ -120,7 +166,7 @@ func SmartShuffleByServer(items []map[string]any, cfg ShuffleConfig) {
 				// 注意：这里 m2 == metas[i+1] 说明上面的 break 没触发
 				if !changed && bestJ != -1 {
 					// 再次确认间距（其实上面循环里确认过了，但为了保险）
-					if checkSpacing(lastPos, i+1, metas[bestJ]) {
+					if checkSpacing(lastPos, lastASN, i+1, metas[bestJ]) {
 						swap(items, metas, i+1, bestJ)
 						changed = true
 
@@ -133,12 +179,150 @@ func SmartShuffleByServer(items []map[string]any, cfg ShuffleConfig) {
 			if m2.prefixOK {
 				lastPos[m2.prefix24] = i + 1
 			}
+			if m2.asnOK {
+				lastASN[m2.asn] = i + 1
+			}
 		}
 
 		if !changed {
 			break
 		}
 	}
+
+	collectSpacingStats(metas, cfg, &stats)
+	return stats
+}
+
+// resolveMetas 并发解析域名型 server，相同域名只解析一次，返回域名数与成功数
+func resolveMetas(metas []serverMeta, cfg ShuffleConfig) (domains, resolved int) {
+	timeout := cfg.ResolveTimeout
+	if timeout <= 0 {
+		timeout = 2 * time.Second
+	}
+	concurrent := cfg.ResolveConcurrent
+	if concurrent <= 0 {
+		concurrent = 64
+	}
+
+	hosts := make(map[string]netip.Addr)
+	var list []string
+	for i := range metas {
+		if m := &metas[i]; m.raw != "" && !m.isIPv4 && net.ParseIP(m.raw) == nil {
+			if _, ok := hosts[m.raw]; !ok {
+				hosts[m.raw] = netip.Addr{}
+				list = append(list, m.raw)
+			}
+		}
+	}
+	if len(list) == 0 {
+		return 0, 0
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	jobs := make(chan string)
+	for range min(concurrent, len(list)) {
+		wg.Go(func() {
+			for host := range jobs {
+				ctx, cancel := context.WithTimeout(context.Background(), timeout)
+				addrs, err := net.DefaultResolver.LookupNetIP(ctx, "ip4", host)
+				cancel()
+				if err != nil || len(addrs) == 0 {
+					continue
+				}
+				mu.Lock()
+				hosts[host] = addrs[0].Unmap()
+				mu.Unlock()
+			}
+		})
+	}
+	for _, host := range list {
+		jobs <- host
+	}
+	close(jobs)
+	wg.Wait()
+
+	for i := range metas {
+		m := &metas[i]
+		addr, ok := hosts[m.raw]
+		if !ok || !addr.Is4() {
+			continue
+		}
+		ip4 := addr.As4()
+		m.isIPv4 = true
+		m.octets = ip4
+		m.prefix24 = uint32(ip4[0])<<24 | uint32(ip4[1])<<16 | uint32(ip4[2])<<8
+		m.prefixOK = true
+	}
+	for _, addr := range hosts {
+		if addr.IsValid() {
+			resolved++
+		}
+	}
+	return len(list), resolved
+}
+
+// lookupMetaASN 为已知 IPv4 的节点查询 ASN，返回查到的数量
+func lookupMetaASN(metas []serverMeta, db *maxminddb.Reader) int {
+	cache := make(map[[4]byte]uint)
+	found := 0
+	for i := range metas {
+		m := &metas[i]
+		if !m.isIPv4 {
+			continue
+		}
+		asn, ok := cache[m.octets]
+		if !ok {
+			var rec asnRecord
+			if err := db.Lookup(netip.AddrFrom4(m.octets)).Decode(&rec); err == nil {
+				asn = rec.Number
+			}
+			cache[m.octets] = asn
+		}
+		if asn > 0 {
+			m.asn, m.asnOK = asn, true
+			found++
+		}
+	}
+	return found
+}
+
+// collectSpacingStats 统计乱序结果中同一 /24 与同一 ASN 的实际间距
+func collectSpacingStats(metas []serverMeta, cfg ShuffleConfig, stats *ShuffleStats) {
+	lastPos := make(map[uint32]int)
+	lastASN := make(map[uint]int)
+	asnSize := make(map[uint]int)
+	for i, m := range metas {
+		if m.prefixOK {
+			if last, ok := lastPos[m.prefix24]; ok {
+				gap := i - last
+				if stats.Min24Gap < 0 || gap < stats.Min24Gap {
+					stats.Min24Gap = gap
+				}
+				if cfg.MinSpacing > 0 && gap <= cfg.MinSpacing {
+					stats.Spacing24Misses++
+				}
+			}
+			lastPos[m.prefix24] = i
+		}
+		if m.asnOK {
+			if last, ok := lastASN[m.asn]; ok {
+				gap := i - last
+				if stats.MinASNGap < 0 || gap < stats.MinASNGap {
+					stats.MinASNGap = gap
+				}
+				if cfg.ASNMinSpacing > 0 && gap <= cfg.ASNMinSpacing {
+					stats.SpacingASNMisses++
+				}
+			}
+			lastASN[m.asn] = i
+			asnSize[m.asn]++
+		}
+	}
+	stats.ASNCount = len(asnSize)
+	for _, size := range asnSize {
+		stats.TopASNSize = max(stats.TopASNSize, size)
+	}
 }
 
 func parseServerMeta(s string) serverMeta {
@@ -0,0 +1,59 @@
+package proxies
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestSmartShuffleSpacingStats(t *testing.T) {
+	var items []map[string]any
+	for i := range 40 {
+		items = append(items, map[string]any{"server": fmt.Sprintf("10.%d.0.%d", i%4, i)})
+	}
+
+	stats := SmartShuffleByServer(items, ShuffleConfig{Threshold: 0.75, Passes: 3, MinSpacing: 1, ScanLimit: 40})
+	seen := make(map[string]bool)
+	for _, it := range items {
+		seen[it["server"].(string)] = true
+	}
+	if len(seen) != 40 {
+		t.Fatalf("items lost: %d", len(seen))
+	}
+	// 4 个 /24 各 10 个节点，乱序后应基本避免相邻
+	if stats.Min24Gap < 1 || stats.Spacing24Misses > 4 {
+		t.Errorf("spacing too poor: %+v", stats)
+	}
+	if stats.MinASNGap != -1 || stats.ASNCount != 0 {
+		t.Errorf("asn stats without db: %+v", stats)
+	}
+}
+
+func TestCollectSpacingStats(t *testing.T) {
+	metas := []serverMeta{
+		{prefixOK: true, prefix24: 1, asnOK: true, asn: 13335},
+		{prefixOK: true, prefix24: 2, asnOK: true, asn: 13335},
+		{prefixOK: true, prefix24: 1, asnOK: true, asn: 906},
+		{prefixOK: true, prefix24: 3, asnOK: true, asn: 13335},
+	}
+	var stats ShuffleStats
+	stats.Min24Gap, stats.MinASNGap = -1, -1
+	collectSpacingStats(metas, ShuffleConfig{MinSpacing: 2, ASNMinSpacing: 2}, &stats)
+
+	if stats.Min24Gap != 2 || stats.Spacing24Misses != 1 {
+		t.Errorf("/24 stats: %+v", stats)
+	}
+	if stats.MinASNGap != 1 || stats.SpacingASNMisses != 2 || stats.ASNCount != 2 || stats.TopASNSize != 3 {
+		t.Errorf("asn stats: %+v", stats)
+	}
+}
+
+func TestResolveMetas(t *testing.T) {
+	metas := []serverMeta{parseServerMeta("localhost"), parseServerMeta("localhost"), parseServerMeta("1.2.3.4")}
+	domains, _ := resolveMetas(metas, ShuffleConfig{})
+	if domains != 1 {
+		t.Errorf("domains: got %d, want 1", domains)
+	}
+	if !metas[2].prefixOK {
+		t.Errorf("ip server should keep prefix")
+	}
+}
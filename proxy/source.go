@@ -0,0 +1,76 @@
+package proxies
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/goccy/go-yaml"
+	"github.com/sinspired/subs-check-pro/v2/config"
+)
+
+// subSources 本轮订阅 URL 对应的订阅源选项，由 resolveSubUrls 构建，之后只读
+var subSources map[string]config.SubSource
+
+// sourceOf 返回订阅 URL 的独立选项，未配置时返回 nil
+func sourceOf(urlStr string) *config.SubSource {
+	if src, ok := subSources[strings.TrimSpace(urlStr)]; ok {
+		return &src
+	}
+	return nil
+}
+
+// SourceLabel 返回订阅源的显示名称与标签，供分析报告使用
+func SourceLabel(urlStr string) (name string, tags []string) {
+	if src := sourceOf(urlStr); src != nil {
+		return src.Name, src.Tags
+	}
+	return "", nil
+}
+
+// applySourceHeaders 写入订阅源的自定义请求头与认证信息
+func applySourceHeaders(req *http.Request, src *config.SubSource) {
+	if src == nil {
+		return
+	}
+	for k, v := range src.Headers {
+		req.Header.Set(k, v)
+	}
+	if src.Cookie != "" {
+		req.Header.Set("Cookie", src.Cookie)
+	}
+	switch {
+	case src.Bearer != "":
+		req.Header.Set("Authorization", "Bearer "+src.Bearer)
+	case src.BasicAuth != "":
+		user, pass, _ := strings.Cut(src.BasicAuth, ":")
+		req.SetBasicAuth(user, pass)
+	}
+}
+
+// setSourceMeta 将订阅源名称与标签写入节点元数据
+func setSourceMeta(node map[string]any, src *config.SubSource) {
+	if src == nil {
+		return
+	}
+	if src.Name != "" {
+		node["sub_name"] = src.Name
+	}
+	if len(src.Tags) > 0 {
+		node["sub_tags"] = strings.Join(src.Tags, "|")
+	}
+}
+
+// writeSourceEntry 输出 sub-urls 列表项，带独立选项的订阅源保留对象写法
+func writeSourceEntry(sb *strings.Builder, urlStr, comment string) {
+	if src := sourceOf(urlStr); src != nil {
+		if out, err := yaml.Marshal([]config.SubSource{*src}); err == nil && strings.Contains(string(out), "url:") {
+			fmt.Fprintf(sb, "  # %s\n", comment)
+			for line := range strings.SplitSeq(strings.TrimRight(string(out), "\n"), "\n") {
+				sb.WriteString("  " + line + "\n")
+			}
+			return
+		}
+	}
+	fmt.Fprintf(sb, "  - %q # %s\n", urlStr, comment)
+}
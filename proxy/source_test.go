@@ -0,0 +1,68 @@
+package proxies
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/sinspired/subs-check-pro/v2/config"
+)
+
+func TestFetchWithSourceOptions(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, pass, ok := r.BasicAuth()
+		if r.UserAgent() != "custom-ua" || r.Header.Get("X-Token") != "abc" || r.Header.Get("Cookie") != "s=1" || !ok || user != "u" || pass != "p" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		_, _ = w.Write([]byte("ok"))
+	}))
+	defer srv.Close()
+
+	old := config.GlobalConfig
+	config.GlobalConfig = &config.Config{}
+	defer func() { config.GlobalConfig = old }()
+
+	src := &config.SubSource{
+		URL:       srv.URL,
+		UserAgent: "custom-ua",
+		Headers:   map[string]string{"X-Token": "abc"},
+		Cookie:    "s=1",
+		BasicAuth: "u:p",
+		Proxy:     "direct",
+		Timeout:   5,
+		Retry:     1,
+	}
+	body, err, _ := fetchOnce(srv.URL, false, 5, src.UserAgent, src, nil)
+	if err != nil || string(body) != "ok" {
+		t.Fatalf("fetch with options: %q %v", body, err)
+	}
+	if _, err, _ := fetchOnce(srv.URL, false, 5, "other", nil, nil); err == nil || err.Error() != "403" {
+		t.Errorf("fetch without options should be rejected, got %v", err)
+	}
+}
+
+func TestSourceMetaAndEntry(t *testing.T) {
+	subSources = map[string]config.SubSource{
+		"https://a.example/sub": {URL: "https://a.example/sub", Name: "机场A", Tags: []string{"paid", "hk"}},
+	}
+	defer func() { subSources = nil }()
+
+	node := map[string]any{}
+	setSourceMeta(node, sourceOf(" https://a.example/sub "))
+	if node["sub_name"] != "机场A" || node["sub_tags"] != "paid|hk" {
+		t.Errorf("meta: %v", node)
+	}
+
+	var sb strings.Builder
+	writeSourceEntry(&sb, "https://a.example/sub", "nodes: 3")
+	writeSourceEntry(&sb, "https://b.example/sub", "nodes: 1")
+	out := sb.String()
+	if !strings.Contains(out, "  - url: https://a.example/sub\n") || !strings.Contains(out, "    name: 机场A\n") {
+		t.Errorf("object entry:\n%s", out)
+	}
+	if !strings.Contains(out, `  - "https://b.example/sub" # nodes: 1`) {
+		t.Errorf("plain entry:\n%s", out)
+	}
+}
@@ -0,0 +1,256 @@
+package proxies
+
+import (
+	"cmp"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/goccy/go-json"
+	"github.com/sinspired/subs-check-pro/v2/config"
+	"github.com/sinspired/subs-check-pro/v2/save/method"
+	"github.com/sinspired/subs-check-pro/v2/utils"
+)
+
+// 订阅缓存：按订阅 URL 记录 ETag、Last-Modified 与内容哈希，
+// 订阅未变化（304、内容哈希或 GitHub blob SHA 一致）时直接复用上次解析出的节点，跳过解析。
+//
+// 目录结构（位于输出目录下）：
+//
+//	cache/subs/index.json      各订阅的校验信息
+//	cache/subs/<sha1(url)>.json 上次解析出的节点
+
+const (
+	subCacheIndex = "index.json"
+	// subCacheRetention 超过该时长未使用的缓存将被清理
+	subCacheRetention = 7 * 24 * time.Hour
+)
+
+// errNotModified 服务端返回 304，内容未变化
+var errNotModified = errors.New("not modified")
+
+// subCacheEntry 单个订阅的缓存校验信息
+type subCacheEntry struct {
+	ETag         string    `json:"etag,omitempty"`
+	LastModified string    `json:"last-modified,omitempty"`
+	Hash         string    `json:"hash"`
+	Blob         string    `json:"blob,omitempty"` // GitHub 仓库订阅源文件的 blob SHA
+	Nodes        int       `json:"nodes"`
+	UsedAt       time.Time `json:"used-at"`
+}
+
+// cacheValidators 条件请求的校验值，fetchOnce 读取旧值并写回新值
+type cacheValidators struct {
+	ETag         string
+	LastModified string
+}
+
+var (
+	subCacheMu      sync.Mutex
+	subCacheEntries map[string]*subCacheEntry // 为 nil 表示未启用
+	subCacheDir     string
+	subCacheHits    atomic.Int32
+)
+
+// subCacheEnabled 是否启用订阅缓存
+func subCacheEnabled() bool {
+	return config.GlobalConfig.SubsCache
+}
+
+// cacheable 本地订阅（上次结果、历史记录）每轮都会变化，不做缓存
+func cacheable(urlStr string) bool {
+	return subCacheEntries != nil && !utils.IsLocalURL(urlStr)
+}
+
+// initSubCache 读取缓存索引，每轮 GetProxies 开始时调用
+func initSubCache() {
+	subCacheMu.Lock()
+	defer subCacheMu.Unlock()
+	subCacheEntries, subCacheDir = nil, ""
+	subCacheHits.Store(0)
+	if !subCacheEnabled() {
+		return
+	}
+
+	saver, err := method.NewStatsSaver()
+	if err != nil {
+		return
+	}
+	subCacheDir = filepath.Join(filepath.Dir(saver.StatsPath), "cache", "subs")
+	if err := os.MkdirAll(subCacheDir, 0o755); err != nil {
+		slog.Warn("创建订阅缓存目录失败", "error", err)
+		return
+	}
+
+	subCacheEntries = make(map[string]*subCacheEntry)
+	if data, err := os.ReadFile(filepath.Join(subCacheDir, subCacheIndex)); err == nil {
+		if err := json.Unmarshal(data, &subCacheEntries); err != nil || subCacheEntries == nil {
+			subCacheEntries = make(map[string]*subCacheEntry)
+		}
+	}
+}
+
+// subCacheFile 节点缓存文件路径
+func subCacheFile(urlStr string) string {
+	sum := sha1.Sum([]byte(urlStr))
+	return filepath.Join(subCacheDir, hex.EncodeToString(sum[:])+".json")
+}
+
+// subCacheValidators 返回订阅的条件请求校验值，无缓存时返回 nil
+func subCacheValidators(urlStr string) *cacheValidators {
+	subCacheMu.Lock()
+	defer subCacheMu.Unlock()
+	if !cacheable(urlStr) {
+		return nil
+	}
+	v := &cacheValidators{}
+	if e, ok := subCacheEntries[urlStr]; ok {
+		v.ETag, v.LastModified = e.ETag, e.LastModified
+	}
+	return v
+}
+
+// contentHash 订阅内容哈希
+func contentHash(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:16])
+}
+
+// subCacheUnchanged 内容哈希与缓存一致
+func subCacheUnchanged(urlStr, hash string) bool {
+	subCacheMu.Lock()
+	defer subCacheMu.Unlock()
+	if !cacheable(urlStr) {
+		return false
+	}
+	e, ok := subCacheEntries[urlStr]
+	return ok && e.Hash == hash
+}
+
+// subCacheBlobUnchanged GitHub 仓库订阅源文件的 blob SHA 与缓存一致
+func subCacheBlobUnchanged(urlStr string) bool {
+	sha := githubBlobSHA(urlStr)
+	if sha == "" {
+		return false
+	}
+	subCacheMu.Lock()
+	defer subCacheMu.Unlock()
+	if !cacheable(urlStr) {
+		return false
+	}
+	e, ok := subCacheEntries[urlStr]
+	return ok && e.Blob == sha
+}
+
+// loadCachedNodes 读取缓存的节点，成功时计入缓存命中并更新校验值
+func loadCachedNodes(urlStr string, v *cacheValidators) ([]map[string]any, bool) {
+	data, err := os.ReadFile(subCacheFile(urlStr))
+	if err != nil {
+		return nil, false
+	}
+	var nodes []map[string]any
+	if err := json.Unmarshal(data, &nodes); err != nil {
+		return nil, false
+	}
+
+	subCacheMu.Lock()
+	if e, ok := subCacheEntries[urlStr]; ok {
+		e.UsedAt = time.Now()
+		e.Blob = cmp.Or(githubBlobSHA(urlStr), e.Blob)
+		if v != nil && (v.ETag != "" || v.LastModified != "") {
+			e.ETag, e.LastModified = v.ETag, v.LastModified
+		}
+	}
+	subCacheMu.Unlock()
+	subCacheHits.Add(1)
+	return nodes, true
+}
+
+// nodeRecorder 在解析回调入口记录节点快照，后续的规范化与解析会原地修改节点
+type nodeRecorder struct {
+	nodes []json.RawMessage
+}
+
+func (r *nodeRecorder) add(node map[string]any) {
+	if r == nil {
+		return
+	}
+	if b, err := json.Marshal(node); err == nil {
+		r.nodes = append(r.nodes, b)
+	}
+}
+
+// storeCachedNodes 保存本次解析出的节点与校验信息
+func storeCachedNodes(urlStr string, v *cacheValidators, hash string, rec *nodeRecorder) {
+	if v == nil || rec == nil || !cacheable(urlStr) {
+		return
+	}
+	nodes := rec.nodes
+	if nodes == nil {
+		nodes = []json.RawMessage{}
+	}
+	data, err := json.Marshal(nodes)
+	if err != nil {
+		return
+	}
+	if err := os.WriteFile(subCacheFile(urlStr), data, 0o644); err != nil {
+		slog.Debug("写入订阅缓存失败", "URL", urlStr, "error", err)
+		return
+	}
+
+	subCacheMu.Lock()
+	defer subCacheMu.Unlock()
+	if subCacheEntries == nil {
+		return
+	}
+	subCacheEntries[urlStr] = &subCacheEntry{
+		ETag:         v.ETag,
+		LastModified: v.LastModified,
+		Hash:         hash,
+		Blob:         githubBlobSHA(urlStr),
+		Nodes:        len(nodes),
+		UsedAt:       time.Now(),
+	}
+}
+
+// dropCachedNodes 缓存文件损坏时删除校验信息，下次重新下载
+func dropCachedNodes(urlStr string) {
+	subCacheMu.Lock()
+	defer subCacheMu.Unlock()
+	if subCacheEntries != nil {
+		delete(subCacheEntries, urlStr)
+	}
+	_ = os.Remove(subCacheFile(urlStr))
+}
+
+// saveSubCache 清理过期缓存并写入索引
+func saveSubCache() {
+	subCacheMu.Lock()
+	defer subCacheMu.Unlock()
+	if subCacheEntries == nil {
+		return
+	}
+
+	cutoff := time.Now().Add(-subCacheRetention)
+	for u, e := range subCacheEntries {
+		if e.UsedAt.Before(cutoff) {
+			delete(subCacheEntries, u)
+			_ = os.Remove(subCacheFile(u))
+		}
+	}
+
+	data, err := json.Marshal(subCacheEntries)
+	if err != nil {
+		return
+	}
+	if err := os.WriteFile(filepath.Join(subCacheDir, subCacheIndex), data, 0o644); err != nil {
+		slog.Warn("保存订阅缓存索引失败", "error", err)
+	}
+}
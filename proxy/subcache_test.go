@@ -0,0 +1,79 @@
+package proxies
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestSubCacheConditionalFetch(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		w.Header().Set("Last-Modified", "Mon, 01 Jan 2024 00:00:00 GMT")
+		_, _ = w.Write([]byte("proxies: []"))
+	}))
+	defer srv.Close()
+
+	v := &cacheValidators{}
+	body, err, _ := fetchOnce(srv.URL, false, 5, "test", nil, v)
+	if err != nil || string(body) != "proxies: []" {
+		t.Fatalf("first fetch: %q %v", body, err)
+	}
+	if v.ETag != `"v1"` || v.LastModified == "" {
+		t.Fatalf("validators not recorded: %+v", v)
+	}
+	if _, err, fatal := fetchOnce(srv.URL, false, 5, "test", nil, v); err != errNotModified || !fatal {
+		t.Fatalf("expected errNotModified, got %v", err)
+	}
+	// 不带校验值时不应返回 304
+	if _, err, _ := fetchOnce(srv.URL, false, 5, "test", nil, nil); err != nil {
+		t.Fatalf("plain fetch: %v", err)
+	}
+}
+
+func TestSubCacheStoreLoad(t *testing.T) {
+	subCacheDir = t.TempDir()
+	subCacheEntries = map[string]*subCacheEntry{}
+	subCacheHits.Store(0)
+	defer func() { subCacheEntries, subCacheDir = nil, "" }()
+
+	const u = "https://example.com/sub"
+	v := subCacheValidators(u)
+	if v == nil {
+		t.Fatal("expected validators for remote url")
+	}
+	if subCacheValidators("http://127.0.0.1:8199/all.yaml") != nil {
+		t.Fatal("local url should not be cached")
+	}
+
+	v.ETag = `"abc"`
+	rec := &nodeRecorder{}
+	rec.add(map[string]any{"name": "a", "type": "ss", "server": "1.1.1.1", "port": 443})
+	rec.add(map[string]any{"name": "b", "type": "vless", "server": "b.example", "port": 8443})
+	hash := contentHash([]byte("data"))
+	storeCachedNodes(u, v, hash, rec)
+
+	if !subCacheUnchanged(u, hash) || subCacheUnchanged(u, contentHash([]byte("other"))) {
+		t.Fatal("hash comparison mismatch")
+	}
+	if got := subCacheValidators(u); got.ETag != `"abc"` {
+		t.Fatalf("etag not persisted: %+v", got)
+	}
+
+	nodes, ok := loadCachedNodes(u, nil)
+	if !ok || len(nodes) != 2 || nodes[1]["server"] != "b.example" {
+		t.Fatalf("load: %v %v", ok, nodes)
+	}
+	if subCacheHits.Load() != 1 {
+		t.Fatalf("hits = %d", subCacheHits.Load())
+	}
+
+	dropCachedNodes(u)
+	if _, ok := loadCachedNodes(u, nil); ok {
+		t.Fatal("expected cache dropped")
+	}
+}
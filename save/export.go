@@ -0,0 +1,847 @@
+package save
+
+import (
+	"cmp"
+	"encoding/base64"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/goccy/go-json"
+	"github.com/goccy/go-yaml"
+	"github.com/sinspired/subs-check-pro/v2/config"
+)
+
+// 客户端配置导出：将检测后的节点转换为 iOS 等客户端可直接导入的格式。
+// 客户端无法表达的协议或传输层会被跳过；能够工作但丢失部分选项的节点记为降级，两者都会输出日志。
+
+// clientExporter 单个客户端的导出器
+type clientExporter struct {
+	key  string // 配置 exporters 中的名称
+	file string // 输出文件名
+	// line 将单个节点转换为一行，返回的 downgrade 描述被丢弃的选项，err 表示无法导出
+	line func(p map[string]any, name string) (out, downgrade string, err error)
+	// wrap 将所有节点行组装为最终文件内容
+	wrap func(lines, names []string) []byte
+}
+
+var clientExporters = []clientExporter{
+	{key: "surge", file: "surge.conf", line: surgeLine, wrap: wrapSurge},
+	{key: "loon", file: "loon.conf", line: loonLine, wrap: wrapLoon},
+	{key: "quanx", file: "quanx.list", line: quanxLine, wrap: wrapLines},
+	{key: "shadowrocket", file: "shadowrocket.txt", line: shadowrocketLine, wrap: wrapBase64},
+}
+
+// enabledExporters 返回配置中启用的导出器
+func enabledExporters() []clientExporter {
+	var out []clientExporter
+	for _, key := range config.GlobalConfig.Exporters {
+		key = strings.ToLower(strings.TrimSpace(key))
+		found := false
+		for _, e := range clientExporters {
+			if e.key == key {
+				out = append(out, e)
+				found = true
+				break
+			}
+		}
+		if !found && key != "" {
+			slog.Warn("未知的导出格式", "exporter", key)
+		}
+	}
+	return out
+}
+
+// exporterByFile 按输出文件名查找导出器
+func exporterByFile(file string) (clientExporter, bool) {
+	for _, e := range clientExporters {
+		if e.file == file {
+			return e, true
+		}
+	}
+	return clientExporter{}, false
+}
+
+// ExportFile 返回导出格式对应的输出文件名
+func ExportFile(key string) (string, bool) {
+	for _, e := range clientExporters {
+		if e.key == key {
+			return e.file, true
+		}
+	}
+	return "", false
+}
+
+// Export 按导出格式即时转换节点，未知格式或没有可导出的节点时返回 nil
+func Export(key string, proxies []map[string]any) []byte {
+	for _, e := range clientExporters {
+		if e.key == key {
+			return e.export(proxies)
+		}
+	}
+	return nil
+}
+
+// Render 将节点渲染为订阅转换的目标格式。
+// clash 为套用 mihomo 覆写模板的完整配置，list 为 true 时仅输出 proxies；
+// v2ray 为 base64 链接列表；其余为客户端导出格式
+func Render(target string, proxies []map[string]any, list bool) ([]byte, error) {
+	switch target {
+	case "clash":
+		if list {
+			return yaml.Marshal(map[string]any{"proxies": proxies})
+		}
+		return buildMihomoYAML(proxies)
+	case "v2ray":
+		target = "shadowrocket" // base64 链接列表与 v2rayN 兼容
+	}
+	if _, ok := ExportFile(target); !ok {
+		return nil, fmt.Errorf("不支持的转换格式: %s", target)
+	}
+	out := Export(target, proxies)
+	if len(out) == 0 {
+		return nil, fmt.Errorf("没有可导出为 %s 格式的节点", target)
+	}
+	return out, nil
+}
+
+// errUnsupported 客户端不支持的协议或选项
+type errUnsupported string
+
+func (e errUnsupported) Error() string { return string(e) }
+
+// export 逐个转换节点并汇总跳过与降级情况
+func (e clientExporter) export(proxies []map[string]any) []byte {
+	var (
+		lines, names []string
+		skipped      = make(map[string]int)
+		downgraded   int
+	)
+	used := make(map[string]int, len(proxies))
+	for _, p := range proxies {
+		name := uniqueExportName(used, exportName(p))
+		out, downgrade, err := e.line(p, name)
+		if err != nil {
+			skipped[err.Error()]++
+			slog.Debug("节点无法导出", "格式", e.key, "节点", name, "原因", err)
+			continue
+		}
+		if downgrade != "" {
+			downgraded++
+			slog.Debug("节点降级导出", "格式", e.key, "节点", name, "丢弃", downgrade)
+		}
+		lines = append(lines, out)
+		names = append(names, name)
+	}
+
+	if len(skipped) > 0 || downgraded > 0 {
+		args := []any{"格式", e.key, "导出", len(lines), "降级", downgraded}
+		for reason, n := range skipped {
+			args = append(args, reason, n)
+		}
+		slog.Info("客户端导出", args...)
+	}
+	if len(lines) == 0 {
+		return nil
+	}
+	return e.wrap(lines, names)
+}
+
+// exportName 客户端配置中的节点名不能包含逗号与等号
+func exportName(p map[string]any) string {
+	name := strings.TrimSpace(str(p, "name"))
+	name = strings.NewReplacer(",", " ", "=", " ", "\n", " ", "\r", "").Replace(name)
+	if name == "" {
+		name = str(p, "server") + ":" + str(p, "port")
+	}
+	return name
+}
+
+// uniqueExportName 重名时追加 -2、-3 等后缀；后缀名已被占用时继续递增，输出过的名称都记入 used
+func uniqueExportName(used map[string]int, name string) string {
+	candidate := name
+	for n := used[name]; ; n++ {
+		if n > 0 {
+			candidate = name + "-" + strconv.Itoa(n+1)
+		}
+		if used[candidate] == 0 {
+			used[name] = n + 1
+			if candidate != name {
+				used[candidate] = 1
+			}
+			return candidate
+		}
+	}
+}
+
+// ---------------- 字段读取 ----------------
+
+// credentialKeys 写入客户端配置的凭据字段
+var credentialKeys = []string{"username", "password", "uuid", "psk", "token"}
+
+// checkCredentials Surge/Loon/Quantumult X 以逗号分隔参数，引号内也不支持转义，
+// 凭据含引号、逗号或换行时无法原样写入，直接跳过该节点
+func checkCredentials(p map[string]any) error {
+	for _, k := range credentialKeys {
+		if strings.ContainsAny(str(p, k), "\",\r\n") {
+			return errUnsupported("凭据含引号或逗号")
+		}
+	}
+	return nil
+}
+
+// quote 用双引号包裹参数，调用前需经 checkCredentials 检查
+func quote(v string) string {
+	return `"` + v + `"`
+}
+
+func str(p map[string]any, key string) string {
+	v, ok := p[key]
+	if !ok || v == nil {
+		return ""
+	}
+	return strings.TrimSpace(fmt.Sprint(v))
+}
+
+func boolOf(p map[string]any, key string) bool {
+	switch v := p[key].(type) {
+	case bool:
+		return v
+	case string:
+		b, _ := strconv.ParseBool(v)
+		return b
+	}
+	return false
+}
+
+func optsOf(p map[string]any, key string) map[string]any {
+	m, _ := p[key].(map[string]any)
+	return m
+}
+
+// sniOf 依次读取 servername / sni
+func sniOf(p map[string]any) string {
+	if s := str(p, "servername"); s != "" {
+		return s
+	}
+	return str(p, "sni")
+}
+
+// wsOf 读取 ws 传输层的 path、Host 及是否存在 Host 以外的请求头
+func wsOf(p map[string]any) (path, host string, extra bool) {
+	opts := optsOf(p, "ws-opts")
+	path = str(opts, "path")
+	if path == "" {
+		path = "/"
+	}
+	for k, v := range optsOf(opts, "headers") {
+		if strings.EqualFold(k, "host") {
+			host = strings.TrimSpace(fmt.Sprint(v))
+		} else {
+			extra = true
+		}
+	}
+	return path, host, extra
+}
+
+// transportOf 返回传输层类型，tcp 与空值统一为 tcp
+func transportOf(p map[string]any) string {
+	n := strings.ToLower(str(p, "network"))
+	if n == "" {
+		return "tcp"
+	}
+	return n
+}
+
+// bandwidthMbps 解析 "100 Mbps" / "100" 形式的带宽
+func bandwidthMbps(v string) string {
+	v = strings.TrimSpace(strings.TrimSuffix(strings.ToLower(v), "mbps"))
+	if _, err := strconv.Atoi(v); err == nil {
+		return v
+	}
+	return ""
+}
+
+// wrapLines 纯节点列表
+func wrapLines(lines, _ []string) []byte {
+	return []byte(strings.Join(lines, "\n") + "\n")
+}
+
+// wrapBase64 base64 编码的节点链接列表
+func wrapBase64(lines, _ []string) []byte {
+	return []byte(base64.StdEncoding.EncodeToString([]byte(strings.Join(lines, "\n"))))
+}
+
+const exportTestURL = "http://www.gstatic.com/generate_204"
+
+// ---------------- Surge ----------------
+
+func wrapSurge(lines, names []string) []byte {
+	var sb strings.Builder
+	sb.WriteString("[General]\nloglevel = notify\n")
+	sb.WriteString("skip-proxy = 127.0.0.1, 192.168.0.0/16, 10.0.0.0/8, 172.16.0.0/12, 100.64.0.0/10, localhost, *.local\n")
+	sb.WriteString("dns-server = system, 223.5.5.5, 119.29.29.29\n\n")
+	sb.WriteString("[Proxy]\n")
+	for _, l := range lines {
+		sb.WriteString(l + "\n")
+	}
+	joined := strings.Join(names, ", ")
+	sb.WriteString("\n[Proxy Group]\n")
+	sb.WriteString("Proxy = select, Auto, " + joined + "\n")
+	sb.WriteString("Auto = url-test, " + joined + ", url=" + exportTestURL + ", interval=600, tolerance=50\n")
+	sb.WriteString("\n[Rule]\nGEOIP,CN,DIRECT\nFINAL,Proxy,dns-failed\n")
+	return []byte(sb.String())
+}
+
+func surgeLine(p map[string]any, name string) (string, string, error) {
+	if err := checkCredentials(p); err != nil {
+		return "", "", err
+	}
+	typ := str(p, "type")
+	server, port := str(p, "server"), str(p, "port")
+	var (
+		args      []string
+		downgrade string
+	)
+
+	switch typ {
+	case "ss":
+		args = append(args, "ss", server, port, "encrypt-method="+str(p, "cipher"), "password="+str(p, "password"))
+		switch str(p, "plugin") {
+		case "":
+		case "obfs", "simple-obfs":
+			opts := optsOf(p, "plugin-opts")
+			args = append(args, "obfs="+str(opts, "mode"), "obfs-host="+str(opts, "host"))
+		default:
+			return "", "", errUnsupported("不支持插件 " + str(p, "plugin"))
+		}
+	case "vmess", "trojan":
+		if typ == "vmess" {
+			args = append(args, "vmess", server, port, "username="+str(p, "uuid"))
+			if str(p, "alterId") == "" || str(p, "alterId") == "0" {
+				args = append(args, "vmess-aead=true")
+			}
+			if boolOf(p, "tls") {
+				args = append(args, "tls=true")
+			}
+		} else {
+			args = append(args, "trojan", server, port, "password="+str(p, "password"))
+		}
+		switch transportOf(p) {
+		case "tcp":
+		case "ws":
+			path, host, extra := wsOf(p)
+			args = append(args, "ws=true", "ws-path="+path)
+			if host != "" {
+				args = append(args, "ws-headers=Host:"+host)
+			}
+			if extra {
+				downgrade = "ws 额外请求头"
+			}
+		default:
+			return "", "", errUnsupported("不支持传输层 " + transportOf(p))
+		}
+	case "hysteria2":
+		if str(p, "obfs") != "" {
+			return "", "", errUnsupported("不支持 hysteria2 混淆")
+		}
+		args = append(args, "hysteria2", server, port, "password="+str(p, "password"))
+		if bw := bandwidthMbps(str(p, "down")); bw != "" {
+			args = append(args, "download-bandwidth="+bw)
+		}
+	case "tuic":
+		if token := str(p, "token"); token != "" {
+			args = append(args, "tuic", server, port, "token="+token)
+		} else {
+			args = append(args, "tuic-v5", server, port, "password="+str(p, "password"), "uuid="+str(p, "uuid"))
+		}
+		if alpn, ok := p["alpn"].([]any); ok && len(alpn) > 0 {
+			args = append(args, "alpn="+fmt.Sprint(alpn[0]))
+		}
+	case "snell":
+		args = append(args, "snell", server, port, "psk="+str(p, "psk"))
+		if v := str(p, "version"); v != "" {
+			args = append(args, "version="+v)
+		}
+		if opts := optsOf(p, "obfs-opts"); str(opts, "mode") != "" {
+			args = append(args, "obfs="+str(opts, "mode"), "obfs-host="+str(opts, "host"))
+		}
+	case "http", "socks5":
+		kind := typ
+		if boolOf(p, "tls") {
+			kind = map[string]string{"http": "https", "socks5": "socks5-tls"}[typ]
+		}
+		args = append(args, kind, server, port)
+		if user := str(p, "username"); user != "" {
+			args = append(args, user, quote(str(p, "password")))
+		}
+	default:
+		return "", "", errUnsupported("不支持协议 " + typ)
+	}
+
+	// TLS 参数：ss/snell 没有 TLS，http/socks5 仅在开启 TLS 时写入
+	if typ != "ss" && typ != "snell" && (typ != "http" && typ != "socks5" || boolOf(p, "tls")) {
+		if sni := sniOf(p); sni != "" {
+			args = append(args, "sni="+sni)
+		}
+		if boolOf(p, "skip-cert-verify") {
+			args = append(args, "skip-cert-verify=true")
+		}
+	}
+	if boolOf(p, "udp") && (typ == "ss" || typ == "vmess" || typ == "trojan" || typ == "snell" || typ == "socks5") {
+		args = append(args, "udp-relay=true")
+	}
+	return name + " = " + strings.Join(args, ", "), downgrade, nil
+}
+
+// ---------------- Loon ----------------
+
+func wrapLoon(lines, names []string) []byte {
+	var sb strings.Builder
+	sb.WriteString("[General]\n")
+	sb.WriteString("skip-proxy = 127.0.0.1,192.168.0.0/16,10.0.0.0/8,172.16.0.0/12,100.64.0.0/10,localhost,*.local\n")
+	sb.WriteString("dns-server = system,223.5.5.5,119.29.29.29\n\n")
+	sb.WriteString("[Proxy]\n")
+	for _, l := range lines {
+		sb.WriteString(l + "\n")
+	}
+	joined := strings.Join(names, ",")
+	sb.WriteString("\n[Proxy Group]\n")
+	sb.WriteString("Proxy = select,Auto," + joined + "\n")
+	sb.WriteString("Auto = url-test," + joined + ",url=" + exportTestURL + ",interval=600\n")
+	sb.WriteString("\n[Rule]\nGEOIP,CN,DIRECT\nFINAL,Proxy\n")
+	return []byte(sb.String())
+}
+
+func loonLine(p map[string]any, name string) (string, string, error) {
+	if err := checkCredentials(p); err != nil {
+		return "", "", err
+	}
+	typ := str(p, "type")
+	server, port := str(p, "server"), str(p, "port")
+	var (
+		args      []string
+		downgrade string
+	)
+
+	// transport 写入 vmess/vless/trojan 的传输层参数
+	transport := func() error {
+		switch transportOf(p) {
+		case "tcp":
+			args = append(args, "transport=tcp")
+		case "ws":
+			path, host, extra := wsOf(p)
+			args = append(args, "transport=ws", "path="+path)
+			if host != "" {
+				args = append(args, "host="+host)
+			}
+			if extra {
+				downgrade = "ws 额外请求头"
+			}
+		case "http":
+			opts := optsOf(p, "http-opts")
+			args = append(args, "transport=http")
+			if paths, ok := opts["path"].([]any); ok && len(paths) > 0 {
+				args = append(args, "path="+fmt.Sprint(paths[0]))
+			}
+		default:
+			return errUnsupported("不支持传输层 " + transportOf(p))
+		}
+		return nil
+	}
+	tls := func() {
+		if boolOf(p, "tls") {
+			args = append(args, "over-tls=true")
+		}
+		if sni := sniOf(p); sni != "" {
+			args = append(args, "sni="+sni)
+		}
+		if boolOf(p, "skip-cert-verify") {
+			args = append(args, "skip-cert-verify=true")
+		}
+	}
+
+	switch typ {
+	case "ss":
+		args = append(args, "Shadowsocks", server, port, str(p, "cipher"), quote(str(p, "password")))
+		switch str(p, "plugin") {
+		case "":
+		case "obfs", "simple-obfs":
+			opts := optsOf(p, "plugin-opts")
+			args = append(args, "obfs-name="+str(opts, "mode"), "obfs-host="+str(opts, "host"))
+		default:
+			return "", "", errUnsupported("不支持插件 " + str(p, "plugin"))
+		}
+	case "ssr":
+		args = append(args, "ShadowsocksR", server, port, str(p, "cipher"), quote(str(p, "password")),
+			"protocol="+str(p, "protocol"), "protocol-param="+str(p, "protocol-param"),
+			"obfs="+str(p, "obfs"), "obfs-param="+str(p, "obfs-param"))
+	case "vmess":
+		args = append(args, "vmess", server, port, cmp.Or(str(p, "cipher"), "auto"), quote(str(p, "uuid")))
+		if err := transport(); err != nil {
+			return "", "", err
+		}
+		args = append(args, "alterId="+cmp.Or(str(p, "alterId"), "0"))
+		tls()
+	case "vless":
+		args = append(args, "vless", server, port, quote(str(p, "uuid")))
+		if err := transport(); err != nil {
+			return "", "", err
+		}
+		tls()
+		if flow := str(p, "flow"); flow != "" {
+			args = append(args, "flow="+flow)
+		}
+		if r := optsOf(p, "reality-opts"); r != nil {
+			args = append(args, "public-key="+str(r, "public-key"), "short-id="+str(r, "short-id"))
+		}
+	case "trojan":
+		args = append(args, "trojan", server, port, quote(str(p, "password")))
+		if err := transport(); err != nil {
+			return "", "", err
+		}
+		tls()
+	case "hysteria2":
+		if str(p, "obfs") != "" {
+			return "", "", errUnsupported("不支持 hysteria2 混淆")
+		}
+		args = append(args, "Hysteria2", server, port, quote(str(p, "password")))
+		if sni := sniOf(p); sni != "" {
+			args = append(args, "sni="+sni)
+		}
+		if boolOf(p, "skip-cert-verify") {
+			args = append(args, "skip-cert-verify=true")
+		}
+		if bw := bandwidthMbps(str(p, "down")); bw != "" {
+			args = append(args, "download-bandwidth="+bw)
+		}
+	case "http", "socks5":
+		kind := typ
+		if typ == "http" && boolOf(p, "tls") {
+			kind = "https"
+		}
+		args = append(args, kind, server, port)
+		if user := str(p, "username"); user != "" {
+			args = append(args, user, quote(str(p, "password")))
+		}
+		if typ == "socks5" && boolOf(p, "tls") {
+			args = append(args, "over-tls=true")
+		}
+	default:
+		return "", "", errUnsupported("不支持协议 " + typ)
+	}
+
+	if boolOf(p, "udp") {
+		args = append(args, "udp=true")
+	}
+	return name + " = " + strings.Join(args, ","), downgrade, nil
+}
+
+// ---------------- Quantumult X ----------------
+
+func quanxLine(p map[string]any, name string) (string, string, error) {
+	if err := checkCredentials(p); err != nil {
+		return "", "", err
+	}
+	typ := str(p, "type")
+	hostPort := str(p, "server") + ":" + str(p, "port")
+	if strings.Contains(str(p, "server"), ":") {
+		hostPort = "[" + str(p, "server") + "]:" + str(p, "port")
+	}
+	var (
+		args      []string
+		downgrade string
+	)
+
+	// obfs 写入 vmess/vless/trojan 的传输层与 TLS 参数
+	obfs := func() error {
+		tls := boolOf(p, "tls")
+		switch transportOf(p) {
+		case "tcp":
+			if tls {
+				args = append(args, "obfs=over-tls")
+			}
+		case "ws":
+			path, host, extra := wsOf(p)
+			mode := "ws"
+			if tls {
+				mode = "wss"
+			}
+			args = append(args, "obfs="+mode, "obfs-uri="+path)
+			if host != "" {
+				args = append(args, "obfs-host="+host)
+			}
+			if extra {
+				downgrade = "ws 额外请求头"
+			}
+		case "http":
+			if tls {
+				return errUnsupported("不支持 http 传输层 + TLS")
+			}
+			args = append(args, "obfs=http")
+			if paths, ok := optsOf(p, "http-opts")["path"].([]any); ok && len(paths) > 0 {
+				args = append(args, "obfs-uri="+fmt.Sprint(paths[0]))
+			}
+		default:
+			return errUnsupported("不支持传输层 " + transportOf(p))
+		}
+		if tls {
+			if sni := sniOf(p); sni != "" {
+				args = append(args, "tls-host="+sni)
+			}
+			args = append(args, "tls-verification="+strconv.FormatBool(!boolOf(p, "skip-cert-verify")))
+		}
+		return nil
+	}
+
+	switch typ {
+	case "ss":
+		args = append(args, "shadowsocks="+hostPort, "method="+str(p, "cipher"), "password="+str(p, "password"))
+		opts := optsOf(p, "plugin-opts")
+		switch str(p, "plugin") {
+		case "":
+		case "obfs", "simple-obfs":
+			args = append(args, "obfs="+str(opts, "mode"), "obfs-host="+str(opts, "host"))
+		case "v2ray-plugin":
+			if str(opts, "mode") != "websocket" {
+				return "", "", errUnsupported("不支持 v2ray-plugin 模式 " + str(opts, "mode"))
+			}
+			mode := "ws"
+			if boolOf(opts, "tls") {
+				mode = "wss"
+			}
+			args = append(args, "obfs="+mode, "obfs-host="+str(opts, "host"), "obfs-uri="+cmp.Or(str(opts, "path"), "/"))
+		default:
+			return "", "", errUnsupported("不支持插件 " + str(p, "plugin"))
+		}
+	case "ssr":
+		args = append(args, "shadowsocks="+hostPort, "method="+str(p, "cipher"), "password="+str(p, "password"),
+			"ssr-protocol="+str(p, "protocol"), "ssr-protocol-param="+str(p, "protocol-param"),
+			"obfs="+str(p, "obfs"), "obfs-host="+str(p, "obfs-param"))
+	case "vmess":
+		method := str(p, "cipher")
+		switch method {
+		case "", "auto", "chacha20-poly1305":
+			method = "chacha20-ietf-poly1305"
+		}
+		args = append(args, "vmess="+hostPort, "method="+method, "password="+str(p, "uuid"))
+		if err := obfs(); err != nil {
+			return "", "", err
+		}
+		args = append(args, "aead="+strconv.FormatBool(str(p, "alterId") == "" || str(p, "alterId") == "0"))
+	case "vless":
+		args = append(args, "vless="+hostPort, "method=none", "password="+str(p, "uuid"))
+		if err := obfs(); err != nil {
+			return "", "", err
+		}
+		if flow := str(p, "flow"); flow != "" {
+			args = append(args, "vless-flow="+flow)
+		}
+		if r := optsOf(p, "reality-opts"); r != nil {
+			args = append(args, "reality-base64-pubkey="+str(r, "public-key"), "reality-hex-shortid="+str(r, "short-id"))
+		}
+	case "trojan":
+		args = append(args, "trojan="+hostPort, "password="+str(p, "password"))
+		if transportOf(p) == "tcp" {
+			args = append(args, "over-tls=true")
+			if sni := sniOf(p); sni != "" {
+				args = append(args, "tls-host="+sni)
+			}
+			args = append(args, "tls-verification="+strconv.FormatBool(!boolOf(p, "skip-cert-verify")))
+		} else if err := obfs(); err != nil {
+			return "", "", err
+		}
+	case "http", "socks5":
+		args = append(args, typ+"="+hostPort)
+		if user := str(p, "username"); user != "" {
+			args = append(args, "username="+user, "password="+str(p, "password"))
+		}
+		if boolOf(p, "tls") {
+			args = append(args, "over-tls=true")
+			if sni := sniOf(p); sni != "" {
+				args = append(args, "tls-host="+sni)
+			}
+		}
+	default:
+		return "", "", errUnsupported("不支持协议 " + typ)
+	}
+
+	if boolOf(p, "udp") {
+		args = append(args, "udp-relay=true")
+	}
+	args = append(args, "tag="+name)
+	return strings.Join(args, ", "), downgrade, nil
+}
+
+// ---------------- Shadowrocket ----------------
+
+func shadowrocketLine(p map[string]any, name string) (string, string, error) {
+	typ := str(p, "type")
+	server, port := str(p, "server"), str(p, "port")
+	hostPort := server + ":" + port
+	if strings.Contains(server, ":") {
+		hostPort = "[" + server + "]:" + port
+	}
+	fragment := "#" + url.PathEscape(name)
+	q := url.Values{}
+
+	// 通用 TLS 与传输层参数（vless/trojan 链接）
+	common := func() error {
+		if sni := sniOf(p); sni != "" {
+			q.Set("sni", sni)
+		}
+		if boolOf(p, "skip-cert-verify") {
+			q.Set("allowInsecure", "1")
+		}
+		switch n := transportOf(p); n {
+		case "tcp":
+		case "ws":
+			path, host, _ := wsOf(p)
+			q.Set("type", "ws")
+			q.Set("path", path)
+			if host != "" {
+				q.Set("host", host)
+			}
+		case "grpc":
+			q.Set("type", "grpc")
+			q.Set("serviceName", str(optsOf(p, "grpc-opts"), "grpc-service-name"))
+		default:
+			return errUnsupported("不支持传输层 " + n)
+		}
+		return nil
+	}
+
+	switch typ {
+	case "ss":
+		userinfo := base64.RawURLEncoding.EncodeToString([]byte(str(p, "cipher") + ":" + str(p, "password")))
+		link := "ss://" + userinfo + "@" + hostPort
+		opts := optsOf(p, "plugin-opts")
+		switch str(p, "plugin") {
+		case "":
+		case "obfs", "simple-obfs":
+			link += "?plugin=" + url.QueryEscape("obfs-local;obfs="+str(opts, "mode")+";obfs-host="+str(opts, "host"))
+		case "v2ray-plugin":
+			plugin := "v2ray-plugin;mode=" + str(opts, "mode") + ";host=" + str(opts, "host") + ";path=" + cmp.Or(str(opts, "path"), "/")
+			if boolOf(opts, "tls") {
+				plugin += ";tls"
+			}
+			link += "?plugin=" + url.QueryEscape(plugin)
+		default:
+			return "", "", errUnsupported("不支持插件 " + str(p, "plugin"))
+		}
+		return link + fragment, "", nil
+
+	case "ssr":
+		b64 := func(s string) string { return base64.RawURLEncoding.EncodeToString([]byte(s)) }
+		body := strings.Join([]string{server, port, str(p, "protocol"), str(p, "cipher"), str(p, "obfs"), b64(str(p, "password"))}, ":") +
+			"/?remarks=" + b64(name) + "&obfsparam=" + b64(str(p, "obfs-param")) + "&protoparam=" + b64(str(p, "protocol-param"))
+		return "ssr://" + b64(body), "", nil
+
+	case "vmess":
+		v := map[string]any{
+			"v": "2", "ps": name, "add": server, "port": port, "id": str(p, "uuid"),
+			"aid": cmp.Or(str(p, "alterId"), "0"), "scy": cmp.Or(str(p, "cipher"), "auto"),
+			"net": transportOf(p), "type": "none",
+		}
+		switch transportOf(p) {
+		case "tcp":
+		case "ws":
+			path, host, _ := wsOf(p)
+			v["path"], v["host"] = path, host
+		case "grpc":
+			v["path"] = str(optsOf(p, "grpc-opts"), "grpc-service-name")
+		default:
+			return "", "", errUnsupported("不支持传输层 " + transportOf(p))
+		}
+		if boolOf(p, "tls") {
+			v["tls"] = "tls"
+			v["sni"] = sniOf(p)
+		}
+		data, err := json.Marshal(v)
+		if err != nil {
+			return "", "", err
+		}
+		return "vmess://" + base64.StdEncoding.EncodeToString(data), "", nil
+
+	case "vless":
+		q.Set("encryption", "none")
+		if err := common(); err != nil {
+			return "", "", err
+		}
+		switch r := optsOf(p, "reality-opts"); {
+		case r != nil:
+			q.Set("security", "reality")
+			q.Set("pbk", str(r, "public-key"))
+			q.Set("sid", str(r, "short-id"))
+		case boolOf(p, "tls"):
+			q.Set("security", "tls")
+		}
+		if flow := str(p, "flow"); flow != "" {
+			q.Set("flow", flow)
+		}
+		if fp := str(p, "client-fingerprint"); fp != "" {
+			q.Set("fp", fp)
+		}
+		return "vless://" + url.PathEscape(str(p, "uuid")) + "@" + hostPort + "?" + q.Encode() + fragment, "", nil
+
+	case "trojan":
+		if err := common(); err != nil {
+			return "", "", err
+		}
+		link := "trojan://" + url.PathEscape(str(p, "password")) + "@" + hostPort
+		if len(q) > 0 {
+			link += "?" + q.Encode()
+		}
+		return link + fragment, "", nil
+
+	case "hysteria2":
+		if sni := sniOf(p); sni != "" {
+			q.Set("sni", sni)
+		}
+		if boolOf(p, "skip-cert-verify") {
+			q.Set("insecure", "1")
+		}
+		if o := str(p, "obfs"); o != "" {
+			q.Set("obfs", o)
+			q.Set("obfs-password", str(p, "obfs-password"))
+		}
+		link := "hysteria2://" + url.PathEscape(str(p, "password")) + "@" + hostPort
+		if len(q) > 0 {
+			link += "?" + q.Encode()
+		}
+		return link + fragment, "", nil
+
+	case "tuic":
+		if str(p, "token") != "" {
+			return "", "", errUnsupported("不支持 tuic v4")
+		}
+		if sni := sniOf(p); sni != "" {
+			q.Set("sni", sni)
+		}
+		if alpn, ok := p["alpn"].([]any); ok && len(alpn) > 0 {
+			q.Set("alpn", fmt.Sprint(alpn[0]))
+		}
+		if cc := str(p, "congestion-controller"); cc != "" {
+			q.Set("congestion_control", cc)
+		}
+		link := "tuic://" + url.PathEscape(str(p, "uuid")) + ":" + url.PathEscape(str(p, "password")) + "@" + hostPort
+		if len(q) > 0 {
+			link += "?" + q.Encode()
+		}
+		return link + fragment, "", nil
+
+	case "http", "socks5":
+		scheme := typ
+		if typ == "http" && boolOf(p, "tls") {
+			scheme = "https"
+		}
+		auth := ""
+		if user := str(p, "username"); user != "" {
+			auth = url.UserPassword(user, str(p, "password")).String() + "@"
+		}
+		return scheme + "://" + auth + hostPort + fragment, "", nil
+	}
+	return "", "", errUnsupported("不支持协议 " + typ)
+}
@@ -0,0 +1,172 @@
+package save
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+
+	"github.com/sinspired/subs-check-pro/v2/proxy/parse"
+)
+
+var exportFixture = []map[string]any{
+	{"name": "SS, 01", "type": "ss", "server": "ss.example.com", "port": 8388, "cipher": "aes-128-gcm", "password": "pwd", "udp": true},
+	{"name": "VMess", "type": "vmess", "server": "vmess.example.com", "port": 443, "uuid": "b831381d-6324-4d53-ad4f-8cda48b30811", "alterId": 0, "cipher": "auto",
+		"tls": true, "servername": "cdn.example.com", "network": "ws", "ws-opts": map[string]any{"path": "/ws", "headers": map[string]any{"Host": "cdn.example.com", "X-Key": "1"}}},
+	{"name": "VLESS", "type": "vless", "server": "vless.example.com", "port": 443, "uuid": "b831381d-6324-4d53-ad4f-8cda48b30811", "tls": true,
+		"servername": "www.microsoft.com", "flow": "xtls-rprx-vision", "reality-opts": map[string]any{"public-key": "k4Hq", "short-id": "0123"}},
+	{"name": "Trojan", "type": "trojan", "server": "trojan.example.com", "port": 443, "password": "pwd", "sni": "trojan.example.com"},
+	{"name": "Hy2", "type": "hysteria2", "server": "hy2.example.com", "port": 8443, "password": "pwd", "sni": "hy2.example.com"},
+	{"name": "gRPC", "type": "vmess", "server": "grpc.example.com", "port": 443, "uuid": "b831381d-6324-4d53-ad4f-8cda48b30811", "network": "grpc", "tls": true},
+	{"name": "WG", "type": "wireguard", "server": "wg.example.com", "port": 51820},
+}
+
+// exportAndParse 导出后用对应客户端的解析器读回
+func exportAndParse(t *testing.T, key string, reparse func([]byte) []map[string]any) map[string]map[string]any {
+	t.Helper()
+	for _, e := range clientExporters {
+		if e.key != key {
+			continue
+		}
+		out := e.export(exportFixture)
+		if len(out) == 0 {
+			t.Fatalf("%s: empty output", key)
+		}
+		nodes := make(map[string]map[string]any)
+		for _, n := range reparse(out) {
+			nodes[n["name"].(string)] = n
+		}
+		return nodes
+	}
+	t.Fatalf("exporter %s not found", key)
+	return nil
+}
+
+func TestExportSurgeRoundTrip(t *testing.T) {
+	nodes := exportAndParse(t, "surge", parse.ParseSurgeProxies)
+	// vless、grpc、wireguard 不被 Surge 支持
+	if len(nodes) != 4 {
+		t.Fatalf("got %d nodes: %v", len(nodes), nodes)
+	}
+	if n := nodes["SS  01"]; n == nil || n["cipher"] != "aes-128-gcm" || n["udp"] != true {
+		t.Errorf("ss: %v", n)
+	}
+	if n := nodes["VMess"]; n == nil || n["servername"] != "cdn.example.com" || n["network"] != "ws" {
+		t.Errorf("vmess: %v", n)
+	}
+	if _, ok := nodes["VLESS"]; ok {
+		t.Error("vless should be skipped for surge")
+	}
+}
+
+func TestExportLoonRoundTrip(t *testing.T) {
+	nodes := exportAndParse(t, "loon", parse.ParseLoonProxies)
+	if len(nodes) != 5 {
+		t.Fatalf("got %d nodes: %v", len(nodes), nodes)
+	}
+	n := nodes["VLESS"]
+	if n == nil || n["flow"] != "xtls-rprx-vision" || n["servername"] != "www.microsoft.com" {
+		t.Errorf("vless: %v", n)
+	}
+	if ro, _ := n["reality-opts"].(map[string]any); ro["public-key"] != "k4Hq" {
+		t.Errorf("vless reality: %v", n["reality-opts"])
+	}
+	if n := nodes["Trojan"]; n == nil || n["password"] != "pwd" || n["sni"] != "trojan.example.com" {
+		t.Errorf("trojan: %v", n)
+	}
+}
+
+func TestExportQuanXRoundTrip(t *testing.T) {
+	nodes := exportAndParse(t, "quanx", parse.ParseQuantumultXProxies)
+	// hysteria2、grpc、wireguard 不被 Quantumult X 支持
+	if len(nodes) != 4 {
+		t.Fatalf("got %d nodes: %v", len(nodes), nodes)
+	}
+	if n := nodes["VMess"]; n == nil || n["tls"] != true || n["uuid"] != "b831381d-6324-4d53-ad4f-8cda48b30811" {
+		t.Errorf("vmess: %v", n)
+	}
+}
+
+func TestExportShadowrocket(t *testing.T) {
+	var out []byte
+	for _, e := range clientExporters {
+		if e.key == "shadowrocket" {
+			out = e.export(exportFixture)
+		}
+	}
+	decoded, err := base64.StdEncoding.DecodeString(string(out))
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(string(decoded), "\n")
+	// 仅 wireguard 被跳过
+	if len(lines) != 6 {
+		t.Fatalf("got %d links:\n%s", len(lines), decoded)
+	}
+	for _, prefix := range []string{"ss://", "vmess://", "vless://", "trojan://", "hysteria2://"} {
+		if !strings.Contains(string(decoded), prefix) {
+			t.Errorf("missing %s link", prefix)
+		}
+	}
+	if !strings.Contains(string(decoded), "security=reality") {
+		t.Error("vless link should keep reality")
+	}
+}
+
+func TestExportCredentialQuoting(t *testing.T) {
+	nodes := []map[string]any{
+		{"name": "slash", "type": "trojan", "server": "a.example.com", "port": 443, "password": `p\a$s=密码`},
+		{"name": "quote", "type": "trojan", "server": "b.example.com", "port": 443, "password": `p"ss`},
+		{"name": "comma", "type": "trojan", "server": "c.example.com", "port": 443, "password": "p,ss"},
+		{"name": "user", "type": "socks5", "server": "d.example.com", "port": 1080, "username": "u,1", "password": "p"},
+	}
+	reparse := map[string]func([]byte) []map[string]any{
+		"surge": parse.ParseSurgeProxies,
+		"loon":  parse.ParseLoonProxies,
+		"quanx": parse.ParseQuantumultXProxies,
+	}
+	for _, e := range clientExporters {
+		fn, ok := reparse[e.key]
+		if !ok {
+			continue
+		}
+		out := e.export(nodes)
+		// 不能出现 Go 风格的转义
+		if strings.Contains(string(out), `p\\a`) || strings.Contains(string(out), `\u`) {
+			t.Errorf("%s: escaped credential:\n%s", e.key, out)
+		}
+		got := fn(out)
+		if len(got) != 1 {
+			t.Fatalf("%s: nodes with quotes or commas should be skipped, got %v", e.key, got)
+		}
+		if got[0]["password"] != `p\a$s=密码` {
+			t.Errorf("%s: password = %q", e.key, got[0]["password"])
+		}
+	}
+}
+
+func TestUniqueExportName(t *testing.T) {
+	cases := [][]string{
+		{"a", "a", "a-2"},
+		{"a-2", "a", "a"},
+		{"a", "a", "a", "a-3", "a-2"},
+	}
+	for _, names := range cases {
+		used := make(map[string]int)
+		seen := make(map[string]bool)
+		for _, name := range names {
+			got := uniqueExportName(used, name)
+			if seen[got] {
+				t.Errorf("%v: duplicate name %q", names, got)
+			}
+			seen[got] = true
+		}
+	}
+	used := make(map[string]int)
+	var got []string
+	for _, name := range []string{"a", "a", "a-2"} {
+		got = append(got, uniqueExportName(used, name))
+	}
+	if strings.Join(got, ",") != "a,a-2,a-2-2" {
+		t.Errorf("got %v", got)
+	}
+}
@@ -47,16 +47,22 @@ var localClient = &http.Client{
 
 // NewConfigSaver 创建新的配置保存器，支持显式指定保存方法
 func NewConfigSaver(results []check.Result, saveMethodName string) *ConfigSaver {
+	categories := []ProxyCategory{
+		{Name: "all.yaml", Proxies: nil, Filter: func(r check.Result) bool { return true }},
+		{Name: "mihomo.yaml", Proxies: nil, Filter: func(r check.Result) bool { return true }},
+		{Name: "base64.txt", Proxies: nil, Filter: func(r check.Result) bool { return true }},
+		{Name: "history.yaml", Proxies: nil, Filter: func(r check.Result) bool { return true }},
+	}
+	// 客户端配置导出
+	for _, e := range enabledExporters() {
+		categories = append(categories, ProxyCategory{Name: e.file, Proxies: nil, Filter: func(r check.Result) bool { return true }})
+	}
+
 	return &ConfigSaver{
 		methodName: saveMethodName,
 		results:    results,
 		saveMethod: getSaverFunc(saveMethodName),
-		categories: []ProxyCategory{
-			{Name: "all.yaml", Proxies: nil, Filter: func(r check.Result) bool { return true }},
-			{Name: "mihomo.yaml", Proxies: nil, Filter: func(r check.Result) bool { return true }},
-			{Name: "base64.txt", Proxies: nil, Filter: func(r check.Result) bool { return true }},
-			{Name: "history.yaml", Proxies: nil, Filter: func(r check.Result) bool { return true }},
-		},
+		categories: categories,
 	}
 }
 
@@ -130,6 +136,9 @@ func (cs *ConfigSaver) generateContent(category ProxyCategory) ([]byte, error) {
 	case "base64.txt":
 		return cs.generateBase64()
 	default:
+		if e, ok := exporterByFile(category.Name); ok {
+			return e.export(category.Proxies), nil
+		}
 		return nil, fmt.Errorf("未知的文件类型: %s", category.Name)
 	}
 }
@@ -203,20 +212,29 @@ func (cs *ConfigSaver) generateBase64() ([]byte, error) {
 		return nil, nil // 不满足条件直接跳过，不报错
 	}
 
+	body, err := DownloadSubStore("V2Ray")
+	if err != nil {
+		return nil, fmt.Errorf("获取 base64 失败: %w", err)
+	}
+	return body, nil
+}
+
+// DownloadSubStore 从 sub-store 下载指定客户端格式的订阅，调用方需确认 sub-store 正在运行
+func DownloadSubStore(target string) ([]byte, error) {
 	// http://127.0.0.1:8299/download/sub?target=V2Ray
-	targetURL := utils.BaseURL + "/download/" + utils.SubName + "?target=V2Ray"
+	targetURL := utils.BaseURL + "/download/" + utils.SubName + "?target=" + url.QueryEscape(target)
 	resp, err := localClient.Get(targetURL)
 	if err != nil {
-		return nil, fmt.Errorf("请求 base64 失败: %w", err)
+		return nil, fmt.Errorf("请求失败: %w", err)
 	}
 	defer resp.Body.Close()
 
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return nil, fmt.Errorf("读取 base64 失败: %w", err)
+		return nil, fmt.Errorf("读取失败: %w", err)
 	}
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("获取 base64 失败，状态码: %d", resp.StatusCode)
+		return nil, fmt.Errorf("状态码: %d", resp.StatusCode)
 	}
 	return body, nil
 }
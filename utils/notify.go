@@ -36,6 +36,8 @@ const (
 	NotifyGeoDBUpdate                   // GeoDB 更新
 	NotifySelfUpdate                    // 程序自更新
 	NotifyNewRelease                    // 新版本通知
+	NotifySubQuota                      // 订阅流量提醒
+	NotifySubHealth                     // 订阅自动停用与恢复
 )
 
 const (
@@ -115,6 +117,12 @@ func decorateURL(raw string, kind NotifyKind, downloadURL string) string {
 		case NotifySelfUpdate:
 			q.Set("group", "selfupdate")
 			q.Set("category", "程序更新")
+		case NotifySubQuota:
+			q.Set("group", "subquota")
+			q.Set("category", "订阅流量提醒")
+		case NotifySubHealth:
+			q.Set("group", "sourcehealth")
+			q.Set("category", "订阅健康提醒")
 		}
 	case "ntfy":
 		q.Set("avatar_url", WarpURL(IconURL, IsGhProxyAvailable))
@@ -132,6 +140,10 @@ func decorateURL(raw string, kind NotifyKind, downloadURL string) string {
 			q.Set("tags", "subs-check-pro,geodb-update")
 		case NotifySelfUpdate:
 			q.Set("tags", "subs-check-pro,self-update")
+		case NotifySubQuota:
+			q.Set("tags", "subs-check-pro,sub-quota")
+		case NotifySubHealth:
+			q.Set("tags", "subs-check-pro,source-health")
 		}
 	case "discord":
 		if IconURL != "" {
@@ -336,19 +348,23 @@ func GetCurrentTime() string {
 	return time.Now().Format("2006-01-02 15:04:05")
 }
 
-// SendNotifyCheckResult 发送节点检查结果通知
-func SendNotifyCheckResult(length int, checkTrafficTotal string) {
+// SendNotifyCheckResult 发送节点检查结果通知，notes 为附加提示（如流量预算）
+func SendNotifyCheckResult(length int, checkTrafficTotal string, notes ...string) {
 	title := config.GlobalConfig.NotifyTitle
 	var body string
 	if checkTrafficTotal != "" {
 		body = "✅ 可用节点：" + strconv.Itoa(length) +
-			"  \n📊 消耗流量：" + checkTrafficTotal +
-			"  \n🕒 " + GetCurrentTime()
+			"  \n📊 消耗流量：" + checkTrafficTotal
 	} else {
 		body = "✅ 可用节点：" + strconv.Itoa(length) +
-			"  \n⚠️ 网络异常或手动取消" +
-			"  \n🕒 " + GetCurrentTime()
+			"  \n⚠️ 网络异常或手动取消"
+	}
+	for _, note := range notes {
+		if note != "" {
+			body += "  \n⚠️ " + note
+		}
 	}
+	body += "  \n🕒 " + GetCurrentTime()
 
 	// GUI 系统通知（Wails3 NotificationService）
 	if OSNotifyHook != nil {
@@ -367,6 +383,30 @@ func SendNotifyGeoDBUpdate(version string) {
 	broadcastNotify(NotifyGeoDBUpdate, title, body, "")
 }
 
+// SendNotifySubQuota 发送订阅即将到期或流量不足的提醒
+func SendNotifySubQuota(alerts []string) {
+	if len(alerts) == 0 {
+		return
+	}
+	title := "⏳ 订阅流量提醒"
+	body := "⚠️ " + strings.Join(alerts, "  \n⚠️ ") +
+		"  \n🕒 " + GetCurrentTime()
+
+	broadcastNotify(NotifySubQuota, title, body, "")
+}
+
+// SendNotifySubHealth 发送订阅自动停用与恢复的提醒
+func SendNotifySubHealth(alerts []string) {
+	if len(alerts) == 0 {
+		return
+	}
+	title := "🩺 订阅健康提醒"
+	body := "⚠️ " + strings.Join(alerts, "  \n⚠️ ") +
+		"  \n🕒 " + GetCurrentTime()
+
+	broadcastNotify(NotifySubHealth, title, body, "")
+}
+
 // SendNotifySelfUpdate 发送程序自更新通知
 func SendNotifySelfUpdate(current, latest string) {
 	title := "🔔 subs-check-pro 自动更新"
@@ -230,8 +230,11 @@ func patchDisabled(raw json.RawMessage, disabled bool) (any, error) {
 //  2. Resolve Domain —— DNS 解析（NodeSplit=true 时自动开启）
 //  3. Node Split     —— 裂变，依赖 ① 结果
 //  4. Regex Sort     —— 正则排序
+//
+// ResolveDomain.Engine 为 native 时，解析与裂变已在获取订阅时完成，此处跳过 2、3。
 func buildScpOps(cfg config.SubProcessConfig) []any {
-	needResolve := cfg.ResolveDomain.Enable || cfg.NodeSplit
+	native := strings.EqualFold(cfg.ResolveDomain.Engine, "native")
+	needResolve := (cfg.ResolveDomain.Enable || cfg.NodeSplit) && !native
 
 	var ops []any
 
@@ -270,7 +273,7 @@ func buildScpOps(cfg config.SubProcessConfig) []any {
 	}
 
 	// 3. Node Split
-	if cfg.NodeSplit {
+	if cfg.NodeSplit && !native {
 		ops = append(ops, ScriptOperator{
 			Type:       "Script Operator",
 			CustomName: "节点裂变",
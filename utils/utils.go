@@ -1,10 +1,12 @@
 package utils
 
 import (
+	"context"
 	"crypto/rand"
 	"math/big"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/sinspired/subs-check-pro/v2/config"
 )
@@ -114,3 +116,15 @@ func JoinURL(parts ...string) string {
 
 	return out.String()
 }
+
+// SleepCtx 可被 ctx 取消的等待，被取消时返回 ctx.Err()
+func SleepCtx(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}